@@ -0,0 +1,92 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"hash"
+	"strconv"
+)
+
+// Algorithm identifies a CMAC algorithm. Its numeric value is stable and
+// may be stored.
+type Algorithm uint8
+
+// Supported algorithms.
+const (
+	AES128 Algorithm = 1 + iota // AES-128-CMAC
+	AES192                      // AES-192-CMAC
+	AES256                      // AES-256-CMAC
+)
+
+var algorithms = [...]struct {
+	name    string
+	keySize int
+	cipher  NewCipherFunc
+}{
+	AES128: {"AES-128-CMAC", 16, aes.NewCipher},
+	AES192: {"AES-192-CMAC", 24, aes.NewCipher},
+	AES256: {"AES-256-CMAC", 32, aes.NewCipher},
+}
+
+// ErrUnknownAlgorithm is returned for an unknown algorithm identifier or name.
+var ErrUnknownAlgorithm = newError(ErrInvalidArgument, "cmac: unknown algorithm")
+
+// ParseAlgorithm returns the algorithm with the given name.
+func ParseAlgorithm(name string) (Algorithm, error) {
+	for a := range algorithms {
+		if a != 0 && algorithms[a].name == name {
+			return Algorithm(a), nil
+		}
+	}
+	return 0, ErrUnknownAlgorithm
+}
+
+// Valid returns true if a is a known algorithm.
+func (a Algorithm) Valid() bool {
+	return a != 0 && int(a) < len(algorithms)
+}
+
+// String returns the name of the algorithm, e.g. "AES-128-CMAC".
+func (a Algorithm) String() string {
+	if !a.Valid() {
+		return "Algorithm(" + strconv.Itoa(int(a)) + ")"
+	}
+	return algorithms[a].name
+}
+
+// KeySize returns the key byte length of the algorithm, or 0 when a is not valid.
+func (a Algorithm) KeySize() int {
+	if !a.Valid() {
+		return 0
+	}
+	return algorithms[a].keySize
+}
+
+// New returns a new CMAC hash of the algorithm with the given key. It returns
+// an error if the key size doesn't match the algorithm.
+func (a Algorithm) New(key []byte) (hash.Hash, error) {
+	if !a.Valid() {
+		return nil, ErrUnknownAlgorithm
+	}
+	if len(key) != algorithms[a].keySize {
+		return nil, newError(ErrInvalidKey, "cmac: invalid key size for "+a.String())
+	}
+	return New(algorithms[a].cipher, key)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (a Algorithm) MarshalText() ([]byte, error) {
+	if !a.Valid() {
+		return nil, ErrUnknownAlgorithm
+	}
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (a *Algorithm) UnmarshalText(text []byte) error {
+	v, err := ParseAlgorithm(string(text))
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
@@ -0,0 +1,42 @@
+package cmac
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAlgorithm(t *testing.T) {
+	for _, a := range []Algorithm{AES128, AES192, AES256} {
+		b, err := a.MarshalText()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", a, err)
+		}
+		var v Algorithm
+		if err := v.UnmarshalText(b); err != nil || v != a {
+			t.Errorf("%s: got %s, %v", a, v, err)
+		}
+		h, err := a.New(make([]byte, a.KeySize()))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", a, err)
+		}
+		if h.Size() != 16 {
+			t.Errorf("%s: got size %d, expected 16", a, h.Size())
+		}
+		if _, err := a.New(make([]byte, a.KeySize()+1)); err == nil {
+			t.Errorf("%s: unexpected nil error for invalid key size", a)
+		}
+	}
+	if _, err := ParseAlgorithm("MD5"); err != ErrUnknownAlgorithm {
+		t.Errorf("got error %v, expected %v", err, ErrUnknownAlgorithm)
+	}
+	var a Algorithm
+	if a.Valid() || a.KeySize() != 0 || a.String() != "Algorithm(0)" {
+		t.Errorf("unexpected valid zero algorithm")
+	}
+	if _, err := a.New(nil); err != ErrUnknownAlgorithm {
+		t.Errorf("got error %v, expected %v", err, ErrUnknownAlgorithm)
+	}
+	if _, err := json.Marshal(a); err == nil {
+		t.Errorf("unexpected nil error for invalid algorithm")
+	}
+}
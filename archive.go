@@ -0,0 +1,164 @@
+//go:build !cmac_small
+// +build !cmac_small
+
+package cmac
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+)
+
+/* The tag of an archive entry is the CMAC of the entry name and size
+followed by its content, where integers are big endian:
+
+   uint32(len(name)) || name || uint64(size) || content
+
+so that the tags of two entries can't be swapped with their content.
+*/
+
+// Archive entry tag locations. The tar PAX record value is the standard
+// base64 encoding of the tag. The zip extra field data is the tag.
+const (
+	TarPAXRecord = "CMAC.tag"
+	ZipExtraID   = 0x434d
+)
+
+// ErrNoTag is returned when an archive entry has no tag.
+var ErrNoTag = errors.New("cmac: archive entry has no tag")
+
+// WriteTarEntry writes to tw the header hdr, with its size set and the
+// CMAC of the content read from r computed with h, followed by the content.
+// r is read twice because the tag precedes the content.
+func WriteTarEntry(tw *tar.Writer, hdr *tar.Header, r io.ReadSeeker, h hash.Hash) error {
+	tag, n, err := sumSeeker(r, h, hdr.Name)
+	if err != nil {
+		return err
+	}
+	if hdr.PAXRecords == nil {
+		hdr.PAXRecords = make(map[string]string)
+	}
+	hdr.PAXRecords[TarPAXRecord] = base64.StdEncoding.EncodeToString(tag)
+	hdr.Size = n
+	if err := tw.WriteHeader(hdr); err != nil {
+		return wrapError(ErrIO, err)
+	}
+	_, err = io.Copy(tw, r)
+	return wrapError(ErrIO, err)
+}
+
+// VerifyTarEntry returns a verifying reader of the content of the current
+// entry of tr with header hdr. See NewVerifyingReader.
+func VerifyTarEntry(tr *tar.Reader, hdr *tar.Header, h hash.Hash) (io.Reader, error) {
+	v, ok := hdr.PAXRecords[TarPAXRecord]
+	if !ok {
+		return nil, ErrNoTag
+	}
+	tag, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return nil, ErrNoTag
+	}
+	return newEntryReader(tr, h, tag, hdr.Name, uint64(hdr.Size)), nil
+}
+
+// CreateZipEntry adds to zw a file with header fh, with the CMAC of the
+// content read from r computed with h stored in an extra field, and writes
+// the content. r is read twice because the tag precedes the content.
+func CreateZipEntry(zw *zip.Writer, fh *zip.FileHeader, r io.ReadSeeker, h hash.Hash) error {
+	tag, _, err := sumSeeker(r, h, fh.Name)
+	if err != nil {
+		return err
+	}
+	var hdr [4]byte
+	binary.LittleEndian.PutUint16(hdr[:2], ZipExtraID)
+	binary.LittleEndian.PutUint16(hdr[2:], uint16(len(tag)))
+	fh.Extra = append(append(fh.Extra, hdr[:]...), tag...)
+	w, err := zw.CreateHeader(fh)
+	if err != nil {
+		return wrapError(ErrIO, err)
+	}
+	_, err = io.Copy(w, r)
+	return wrapError(ErrIO, err)
+}
+
+// VerifyZipEntry opens f and returns a verifying reader of its content. See
+// NewVerifyingReader.
+func VerifyZipEntry(f *zip.File, h hash.Hash) (io.ReadCloser, error) {
+	tag := zipExtraTag(f.Extra)
+	if tag == nil {
+		return nil, ErrNoTag
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, wrapError(ErrIO, err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{newEntryReader(rc, h, tag, f.Name, f.UncompressedSize64), rc}, nil
+}
+
+// zipExtraTag returns the tag in the zip extra fields, or nil if there is none.
+func zipExtraTag(extra []byte) []byte {
+	for len(extra) >= 4 {
+		id := binary.LittleEndian.Uint16(extra)
+		n := int(binary.LittleEndian.Uint16(extra[2:]))
+		if len(extra) < 4+n {
+			return nil
+		}
+		if id == ZipExtraID {
+			return extra[4 : 4+n]
+		}
+		extra = extra[4+n:]
+	}
+	return nil
+}
+
+// sumSeeker returns the tag of the entry with the name and the data from
+// the current position of r up to the end, computed with h, and the length
+// of the data. It seeks back to the current position.
+func sumSeeker(r io.ReadSeeker, h hash.Hash, name string) ([]byte, int64, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, 0, wrapError(ErrIO, err)
+	}
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, 0, wrapError(ErrIO, err)
+	}
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return nil, 0, wrapError(ErrIO, err)
+	}
+	h.Reset()
+	writeEntryPrefix(h, name, uint64(end-start))
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return nil, 0, wrapError(ErrIO, err)
+	}
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return nil, 0, wrapError(ErrIO, err)
+	}
+	return h.Sum(nil), n, nil
+}
+
+// newEntryReader returns a verifying reader of the content of the entry
+// with the name and size read from r. See NewVerifyingReader.
+func newEntryReader(r io.Reader, h hash.Hash, tag []byte, name string, size uint64) io.Reader {
+	h.Reset()
+	writeEntryPrefix(h, name, size)
+	return &verifyingReader{r: r, h: h, tag: tag, limit: -1}
+}
+
+// writeEntryPrefix writes the name and size of an archive entry to h.
+func writeEntryPrefix(h hash.Hash, name string, size uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint32(b[:4], uint32(len(name)))
+	h.Write(b[:4])
+	h.Write([]byte(name))
+	binary.BigEndian.PutUint64(b[:], size)
+	h.Write(b[:])
+}
@@ -0,0 +1,139 @@
+//go:build !cmac_small
+// +build !cmac_small
+
+package cmac
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"crypto/aes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestTarEntry(t *testing.T) {
+	h, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	files := map[string]string{"a.txt": "content of a", "b.txt": ""}
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range []string{"a.txt", "b.txt"} {
+		hdr := &tar.Header{Name: name, Mode: 0644}
+		if err := WriteTarEntry(tw, hdr, bytes.NewReader([]byte(files[name])), h); err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+	}
+	tw.WriteHeader(&tar.Header{Name: "untagged", Mode: 0644})
+	tw.Close()
+
+	archive := buf.Bytes()
+	tr := tar.NewReader(bytes.NewReader(archive))
+	for i := 0; i < 2; i++ {
+		hdr, err := tr.Next()
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		r, err := VerifyTarEntry(tr, hdr, h)
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		b, err := io.ReadAll(r)
+		if err != nil || string(b) != files[hdr.Name] {
+			t.Errorf("%s: got %q, %v", hdr.Name, b, err)
+		}
+	}
+	hdr, _ := tr.Next()
+	if _, err := VerifyTarEntry(tr, hdr, h); err != ErrNoTag {
+		t.Errorf("got error %v, expected %v", err, ErrNoTag)
+	}
+
+	i := bytes.Index(archive, []byte("content of a"))
+	archive[i] = 'C'
+	tr = tar.NewReader(bytes.NewReader(archive))
+	hdr, _ = tr.Next()
+	r, _ := VerifyTarEntry(tr, hdr, h)
+	if _, err := io.ReadAll(r); err != ErrMismatch {
+		t.Errorf("got error %v, expected %v", err, ErrMismatch)
+	}
+
+	// swapping the names of two entries, with their content and tags
+	archive[i] = 'c'
+	var swapped bytes.Buffer
+	tr = tar.NewReader(bytes.NewReader(buf.Bytes()))
+	tw = tar.NewWriter(&swapped)
+	for _, name := range []string{"b.txt", "a.txt"} {
+		hdr, _ := tr.Next()
+		content, _ := io.ReadAll(tr)
+		hdr.Name = name
+		tw.WriteHeader(hdr)
+		tw.Write(content)
+	}
+	tw.Close()
+	tr = tar.NewReader(&swapped)
+	for i := 0; i < 2; i++ {
+		hdr, _ := tr.Next()
+		r, err := VerifyTarEntry(tr, hdr, h)
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		if _, err := io.ReadAll(r); err != ErrMismatch {
+			t.Errorf("%s: got error %v, expected %v", hdr.Name, err, ErrMismatch)
+		}
+	}
+
+	failing := struct {
+		io.Reader
+		io.Seeker
+	}{&errReader{io.ErrClosedPipe}, bytes.NewReader(nil)}
+	err := WriteTarEntry(tar.NewWriter(io.Discard), &tar.Header{Name: "a.txt"}, failing, h)
+	if !errors.Is(err, ErrIO) || !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("got error %v, expected category %v and cause %v", err, ErrIO, io.ErrClosedPipe)
+	}
+}
+
+func TestZipEntry(t *testing.T) {
+	h, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	content := bytes.Repeat([]byte("zip content "), 10)
+	if err := CreateZipEntry(zw, &zip.FileHeader{Name: "a.txt", Method: zip.Store}, bytes.NewReader(content), h); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	w, _ := zw.Create("untagged")
+	w.Write([]byte("x"))
+	zw.Close()
+
+	archive := buf.Bytes()
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	rc, err := VerifyZipEntry(zr.File[0], h)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	b, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil || !bytes.Equal(b, content) {
+		t.Errorf("got %q, %v", b, err)
+	}
+	if _, err := VerifyZipEntry(zr.File[1], h); err != ErrNoTag {
+		t.Errorf("got error %v, expected %v", err, ErrNoTag)
+	}
+
+	// the tag is bound to the entry name
+	zr.File[0].Name = "b.txt"
+	rc, _ = VerifyZipEntry(zr.File[0], h)
+	if _, err := io.ReadAll(rc); err != ErrMismatch {
+		t.Errorf("got error %v, expected %v for renamed entry", err, ErrMismatch)
+	}
+	zr.File[0].Name = "a.txt"
+
+	// content changes are also caught by the zip CRC, so alter the tag
+	zipExtraTag(zr.File[0].Extra)[0] ^= 1
+	rc, _ = VerifyZipEntry(zr.File[0], h)
+	if _, err := io.ReadAll(rc); err != ErrMismatch {
+		t.Errorf("got error %v, expected %v", err, ErrMismatch)
+	}
+}
@@ -0,0 +1,37 @@
+package cmac
+
+import "sync/atomic"
+
+// AuditEvent describes a tag rejected by a verification function. It never
+// holds key material nor the authenticated data.
+type AuditEvent struct {
+	// Source is the name of the rejecting function, e.g. "VerifyToken".
+	Source string
+
+	// KeyID is the ID of the key when it is known, and empty otherwise.
+	KeyID string
+
+	// Length is the byte length of the authenticated data, or -1 when it
+	// is unknown.
+	Length int64
+}
+
+// AuditFunc is called with the event of each rejected tag.
+type AuditFunc func(AuditEvent)
+
+var auditFunc atomic.Value // holds an AuditFunc
+
+// SetAuditFunc sets the function called synchronously each time a
+// verification function of the package rejects a tag, or removes it when fn
+// is nil. The function may be called concurrently and should return quickly.
+// Malformed inputs and unknown key IDs are not reported.
+func SetAuditFunc(fn AuditFunc) {
+	auditFunc.Store(fn)
+}
+
+// audit reports a rejected tag to the audit function, if any.
+func audit(source, keyID string, length int64) {
+	if fn, _ := auditFunc.Load().(AuditFunc); fn != nil {
+		fn(AuditEvent{Source: source, KeyID: keyID, Length: length})
+	}
+}
@@ -0,0 +1,118 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAudit(t *testing.T) {
+	var events []AuditEvent
+	SetAuditFunc(func(e AuditEvent) { events = append(events, e) })
+	defer SetAuditFunc(nil)
+
+	r := NewRegistry(nil)
+	r.Add("k1", AES128, []byte("0123456789abcdef"))
+	env, _ := r.Sign("k1", []byte("hello"))
+	if err := r.Verify([]byte("hello"), env); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	r.Verify([]byte("hello!"), env)
+	r.Verify([]byte("hello"), []byte{1}) // malformed, not reported
+
+	h, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	VerifyTuple(h, []byte("bad tag"), Field{"a", []byte("b")})
+	io.ReadAll(NewVerifyingReader(strings.NewReader("some data"), h, make([]byte, 16)))
+
+	exp := []AuditEvent{
+		{Source: "Registry.Verify", KeyID: "k1", Length: 6},
+		{Source: "VerifyTuple", Length: -1},
+		{Source: "NewVerifyingReader", Length: 9},
+	}
+	if len(events) != len(exp) {
+		t.Fatalf("got events %+v, expected %+v", events, exp)
+	}
+	for i := range exp {
+		if events[i] != exp[i] {
+			t.Errorf("%d: got %+v, expected %+v", i, events[i], exp[i])
+		}
+	}
+
+	SetAuditFunc(nil)
+	r.Verify([]byte("hello!"), env)
+	io.Copy(io.Discard, NewVerifyingReader(strings.NewReader("x"), h, make([]byte, 16)))
+	if len(events) != len(exp) {
+		t.Errorf("got %d events with audit disabled, expected %d", len(events), len(exp))
+	}
+}
+
+func TestAuditSources(t *testing.T) {
+	var events []AuditEvent
+	SetAuditFunc(func(e AuditEvent) { events = append(events, e) })
+	defer SetAuditFunc(nil)
+	check := func(name string, exp ...AuditEvent) {
+		t.Helper()
+		if len(events) != len(exp) {
+			t.Errorf("%s: got events %+v, expected %+v", name, events, exp)
+		} else {
+			for i := range exp {
+				if events[i] != exp[i] {
+					t.Errorf("%s %d: got %+v, expected %+v", name, i, events[i], exp[i])
+				}
+			}
+		}
+		events = nil
+	}
+	key := []byte("0123456789abcdef")
+
+	s, _ := NewSIV(make([]byte, 32))
+	out := s.SealVec(nil, []byte("plaintext"))
+	out[0] ^= 1
+	s.OpenVec(nil, out)
+	check("SIV", AuditEvent{Source: "SIV.Open", Length: 9})
+
+	h, _ := New(aes.NewCipher, key)
+	ck, _ := New(aes.NewCipher, []byte("fedcba9876543210"))
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	path := filepath.Join(t.TempDir(), "ckpt")
+	c := &Checkpointer{Hash: h, CheckpointHash: ck, Path: path, Interval: 999}
+	c.Sum(&failingReader{r: bytes.NewReader(data), n: 2000})
+	b, _ := os.ReadFile(path)
+	b[len(b)-1] ^= 1
+	os.WriteFile(path, b, 0600)
+	c.Sum(bytes.NewReader(data))
+	check("Checkpointer", AuditEvent{Source: "Checkpointer.Sum", Length: int64(len(b) - 16)})
+
+	enc, _ := NewSerialEncoder(MustNew(aes.NewCipher, key), 8)
+	dec, _ := NewSerialDecoder(MustNew(aes.NewCipher, key), 8)
+	f0, _ := enc.Encode(nil, []byte("hello"))
+	f1, _ := enc.Encode(nil, []byte("world"))
+	bad := append([]byte(nil), f1...)
+	bad[len(bad)-1] ^= 1
+	dec.Feed(bad)
+	dec.Feed(f1)
+	dec.Feed(f0) // replayed
+	n := int64(len(f0) - 10)
+	check("SerialDecoder", AuditEvent{Source: "SerialDecoder.Feed", Length: n}, AuditEvent{Source: "SerialDecoder.Feed", Length: n})
+
+	fsys := fstest.MapFS{"a.txt": {Data: bytes.Repeat([]byte("a"), 50)}}
+	m := testManifest(t, fsys, key, 10, "a.txt")
+	m.KeyID = "k1"
+	m.Tag = m.sum(MustNew(aes.NewCipher, key))
+	vfs, err := VerifyFS(fsys, m, key)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	fsys["a.txt"].Data[25] = 'x'
+	f, _ := vfs.Open("a.txt")
+	io.ReadAll(f)
+	check("VerifyFS chunk", AuditEvent{Source: "VerifyFS", KeyID: "k1", Length: 10})
+	m.Files[0].Chunks[0][0] ^= 1
+	vfs.Open("a.txt")
+	check("VerifyFS chunk list", AuditEvent{Source: "VerifyFS", KeyID: "k1", Length: 50})
+}
@@ -0,0 +1,72 @@
+package cmac
+
+import (
+	"hash"
+	"runtime"
+	"sync"
+)
+
+// BatchItem is a message and its tag computed with the key of KeyID.
+type BatchItem struct {
+	KeyID string
+	Msg   []byte
+	Tag   []byte
+}
+
+// VerifyBatch verifies the items concurrently with the keys returned by
+// keys, which may be a *Registry, and returns the error of each item: nil
+// when its tag is valid, ErrMismatch when it is invalid, or the error
+// returned by keys. All items are verified. When keys is a *Registry, the
+// verifications are counted in its statistics.
+func VerifyBatch(keys KeyProvider, items []BatchItem) []error {
+	errs := make([]error, len(items))
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(items) {
+		workers = len(items)
+	}
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hashes := make(map[string]hash.Hash)
+			for i := range next {
+				errs[i] = verifyBatchItem(keys, hashes, &items[i])
+			}
+		}()
+	}
+	for i := range items {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return errs
+}
+
+// verifyBatchItem verifies the item with the hash of its key ID, cached in
+// hashes.
+func verifyBatchItem(keys KeyProvider, hashes map[string]hash.Hash, it *BatchItem) error {
+	h, ok := hashes[it.KeyID]
+	if !ok {
+		alg, key, err := keys.Key(it.KeyID)
+		if err != nil {
+			return err
+		}
+		if h, err = alg.New(key); err != nil {
+			return err
+		}
+		hashes[it.KeyID] = h
+	}
+	h.Reset()
+	h.Write(it.Msg)
+	ok = Equal(h.Sum(nil), it.Tag)
+	if r, isRegistry := keys.(*Registry); isRegistry {
+		r.record(it.KeyID, len(it.Msg), !ok)
+	}
+	if !ok {
+		audit("VerifyBatch", it.KeyID, int64(len(it.Msg)))
+		return ErrMismatch
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package cmac
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestVerifyBatch(t *testing.T) {
+	r := NewRegistry(nil)
+	r.Add("k1", AES128, []byte("0123456789abcdef"))
+	r.Add("k2", AES256, []byte("0123456789abcdef0123456789abcdef"))
+
+	var items []BatchItem
+	for i := 0; i < 100; i++ {
+		keyID := "k" + strconv.Itoa(1+i%2)
+		msg := []byte(strconv.Itoa(i))
+		h, _ := r.Hash(keyID)
+		h.Write(msg)
+		items = append(items, BatchItem{KeyID: keyID, Msg: msg, Tag: h.Sum(nil)})
+	}
+	items[10].Tag[0] ^= 1
+	items[11].Msg = []byte("x")
+	items[12].KeyID = "k3"
+	items[13].Tag = items[13].Tag[:8]
+
+	errs := VerifyBatch(r, items)
+	if len(errs) != len(items) {
+		t.Fatalf("got %d errors, expected %d", len(errs), len(items))
+	}
+	for i, err := range errs {
+		var exp error
+		switch i {
+		case 10, 11, 13:
+			exp = ErrMismatch
+		case 12:
+			exp = ErrUnknownKey
+		}
+		if err != exp {
+			t.Errorf("%d: got error %v, expected %v", i, err, exp)
+		}
+	}
+	if errs := VerifyBatch(r, nil); len(errs) != 0 {
+		t.Errorf("got %d errors for empty batch", len(errs))
+	}
+}
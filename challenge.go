@@ -0,0 +1,85 @@
+package cmac
+
+import (
+	"crypto/rand"
+	"errors"
+	"hash"
+	"io"
+	"sync"
+	"time"
+)
+
+// ChallengeSize is the byte size of the challenges issued by a Challenger.
+const ChallengeSize = 16
+
+// ErrUnknownChallenge is returned when a challenge was not issued, has
+// expired, or was already used.
+var ErrUnknownChallenge = errors.New("cmac: unknown challenge")
+
+// ChallengeResponse returns the response of a device to the challenge. It is
+// the TupleMAC, computed with h, of the fields "challenge", "device" and
+// "context" with the challenge, device ID and context as values.
+func ChallengeResponse(h hash.Hash, challenge []byte, deviceID string, context []byte) []byte {
+	return SumTuple(h,
+		Field{"challenge", challenge},
+		Field{"device", []byte(deviceID)},
+		Field{"context", context})
+}
+
+// Challenger issues random challenges and verifies the device responses.
+// Each challenge may be used once, before it expires. A Challenger is safe
+// for concurrent use.
+type Challenger struct {
+	keys    KeyFunc
+	ttl     time.Duration
+	mu      sync.Mutex
+	pending map[string]time.Time
+}
+
+// NewChallenger returns a challenger finding the device keys with keys,
+// and whose challenges expire after ttl.
+func NewChallenger(keys KeyFunc, ttl time.Duration) *Challenger {
+	return &Challenger{keys: keys, ttl: ttl, pending: make(map[string]time.Time)}
+}
+
+// NewChallenge returns a new random challenge issued at time now. Expired
+// challenges are forgotten.
+func (c *Challenger) NewChallenge(now time.Time) ([]byte, error) {
+	b := make([]byte, ChallengeSize)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, exp := range c.pending {
+		if now.After(exp) {
+			delete(c.pending, k)
+		}
+	}
+	c.pending[string(b)] = now.Add(c.ttl)
+	return b, nil
+}
+
+// Verify returns nil if response is the response of the device to the
+// challenge with the context. The challenge is consumed, even when the
+// response is invalid. It returns ErrUnknownChallenge when the challenge
+// is unknown or has expired at time now, and ErrMismatch when the response
+// is invalid.
+func (c *Challenger) Verify(challenge []byte, deviceID string, context, response []byte, now time.Time) error {
+	c.mu.Lock()
+	exp, ok := c.pending[string(challenge)]
+	delete(c.pending, string(challenge))
+	c.mu.Unlock()
+	if !ok || now.After(exp) {
+		return ErrUnknownChallenge
+	}
+	h, err := c.keys(deviceID)
+	if err != nil {
+		return err
+	}
+	if !Equal(ChallengeResponse(h, challenge, deviceID, context), response) {
+		audit("Challenger.Verify", deviceID, int64(len(context)))
+		return ErrMismatch
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"testing"
+	"time"
+)
+
+func TestChallenger(t *testing.T) {
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	keys := func(deviceID string) (hash.Hash, error) {
+		if deviceID != "dev1" {
+			return nil, errors.New("unknown device")
+		}
+		return New(aes.NewCipher, key)
+	}
+	device, _ := keys("dev1")
+	now := time.Unix(1600000000, 0)
+	ctx := []byte("unlock")
+
+	c := NewChallenger(keys, time.Minute)
+	ch, err := c.NewChallenge(now)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if len(ch) != ChallengeSize {
+		t.Errorf("got challenge size %d, expected %d", len(ch), ChallengeSize)
+	}
+	resp := ChallengeResponse(device, ch, "dev1", ctx)
+	exp := SumTuple(device, Field{"challenge", ch}, Field{"device", []byte("dev1")}, Field{"context", ctx})
+	if !bytes.Equal(resp, exp) {
+		t.Errorf("got response %x, expected %x", resp, exp)
+	}
+	if err := c.Verify(ch, "dev1", ctx, resp, now); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := c.Verify(ch, "dev1", ctx, resp, now); err != ErrUnknownChallenge {
+		t.Errorf("got error %v, expected %v for replay", err, ErrUnknownChallenge)
+	}
+
+	ch, _ = c.NewChallenge(now)
+	resp = ChallengeResponse(device, ch, "dev1", ctx)
+	if err := c.Verify(ch, "dev1", []byte("lock"), resp, now); err != ErrMismatch {
+		t.Errorf("got error %v, expected %v", err, ErrMismatch)
+	}
+	if err := c.Verify(ch, "dev1", ctx, resp, now); err != ErrUnknownChallenge {
+		t.Errorf("got error %v, expected %v after failed attempt", err, ErrUnknownChallenge)
+	}
+
+	ch, _ = c.NewChallenge(now)
+	resp = ChallengeResponse(device, ch, "dev1", ctx)
+	if err := c.Verify(ch, "dev1", ctx, resp, now.Add(2*time.Minute)); err != ErrUnknownChallenge {
+		t.Errorf("got error %v, expected %v for expired challenge", err, ErrUnknownChallenge)
+	}
+	ch, _ = c.NewChallenge(now)
+	c.NewChallenge(now.Add(2 * time.Minute))
+	if len(c.pending) != 1 {
+		t.Errorf("got %d pending challenges, expected 1", len(c.pending))
+	}
+	if err := c.Verify(ch, "dev2", ctx, resp, now); err != ErrUnknownChallenge {
+		t.Errorf("got error %v, expected %v for purged challenge", err, ErrUnknownChallenge)
+	}
+	ch, _ = c.NewChallenge(now)
+	if err := c.Verify(ch, "dev2", ctx, resp, now); err == nil {
+		t.Errorf("unexpected nil error for unknown device")
+	}
+}
@@ -0,0 +1,145 @@
+package cmac
+
+import (
+	"encoding"
+	"encoding/binary"
+	"hash"
+	"io"
+	"os"
+)
+
+/* A checkpoint file has the following content, where integers are big endian:
+
+   "cmacckpt" || version || uint64(offset) || uint32(len(state)) || state || tag
+
+version is the byte 1, offset is the number of bytes of the stream
+processed, state is the marshaled CMAC state, and tag is the CMAC of the
+preceding bytes computed with the checkpoint hash.
+*/
+
+const checkpointMagic = "cmacckpt\x01"
+
+// ErrInvalidCheckpoint is returned when a checkpoint file is malformed or its
+// tag is invalid.
+var ErrInvalidCheckpoint = newError(ErrInvalidArgument, "cmac: invalid checkpoint")
+
+// Checkpointer computes the CMAC of a long stream and periodically saves the
+// state of the computation in a checkpoint file, so that the computation
+// can resume from the last checkpoint after a crash.
+type Checkpointer struct {
+	// Hash computes the CMAC of the stream. It must implement
+	// encoding.BinaryMarshaler and encoding.BinaryUnmarshaler as the
+	// hashes returned by New.
+	Hash hash.Hash
+
+	// CheckpointHash authenticates the checkpoint file. Its key must differ
+	// from the key of Hash.
+	CheckpointHash hash.Hash
+
+	// Path is the checkpoint file name.
+	Path string
+
+	// Interval is the number of bytes processed between checkpoints.
+	Interval int64
+}
+
+// Sum returns the CMAC of the data read from r. It resumes the computation
+// from the checkpoint file when it exists, and removes it when done. It
+// returns ErrInvalidCheckpoint when the checkpoint file is invalid.
+func (c *Checkpointer) Sum(r io.ReadSeeker) ([]byte, error) {
+	m, ok1 := c.Hash.(encoding.BinaryMarshaler)
+	u, ok2 := c.Hash.(encoding.BinaryUnmarshaler)
+	if !ok1 || !ok2 || c.Interval <= 0 {
+		return nil, newError(ErrInvalidArgument, "cmac: invalid checkpointer")
+	}
+	c.Hash.Reset()
+	offset, err := c.load(u)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return nil, wrapError(ErrIO, err)
+	}
+	for {
+		n, err := io.CopyN(c.Hash, r, c.Interval)
+		offset += n
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, wrapError(ErrIO, err)
+		}
+		if err := c.save(m, offset); err != nil {
+			return nil, wrapError(ErrIO, err)
+		}
+	}
+	if err := os.Remove(c.Path); err != nil && !os.IsNotExist(err) {
+		return nil, wrapError(ErrIO, err)
+	}
+	return c.Hash.Sum(nil), nil
+}
+
+// load restores the state of the checkpoint file and returns its offset. It
+// returns 0 when there is no checkpoint file.
+func (c *Checkpointer) load(u encoding.BinaryUnmarshaler) (int64, error) {
+	b, err := os.ReadFile(c.Path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, wrapError(ErrIO, err)
+	}
+	n := len(b) - c.CheckpointHash.Size()
+	h := len(checkpointMagic) + 12
+	if n < h || string(b[:len(checkpointMagic)]) != checkpointMagic {
+		return 0, ErrInvalidCheckpoint
+	}
+	offset := binary.BigEndian.Uint64(b[len(checkpointMagic):])
+	if int(binary.BigEndian.Uint32(b[h-4:])) != n-h || offset > 1<<63-1 {
+		return 0, ErrInvalidCheckpoint
+	}
+	c.CheckpointHash.Reset()
+	c.CheckpointHash.Write(b[:n])
+	if !Equal(c.CheckpointHash.Sum(nil), b[n:]) {
+		audit("Checkpointer.Sum", "", int64(n))
+		return 0, ErrInvalidCheckpoint
+	}
+	if err := u.UnmarshalBinary(b[h:n]); err != nil {
+		return 0, ErrInvalidCheckpoint
+	}
+	return int64(offset), nil
+}
+
+// save atomically replaces the checkpoint file with the current state.
+func (c *Checkpointer) save(m encoding.BinaryMarshaler, offset int64) error {
+	state, err := m.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	var hdr [12]byte
+	binary.BigEndian.PutUint64(hdr[:], uint64(offset))
+	binary.BigEndian.PutUint32(hdr[8:], uint32(len(state)))
+	b := append([]byte(checkpointMagic), hdr[:]...)
+	b = append(b, state...)
+	c.CheckpointHash.Reset()
+	c.CheckpointHash.Write(b)
+	b = c.CheckpointHash.Sum(b)
+
+	tmp := c.Path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, c.Path)
+}
@@ -0,0 +1,82 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// failingReader returns an error after n bytes.
+type failingReader struct {
+	r io.ReadSeeker
+	n int64
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	if f.n <= 0 {
+		return 0, errors.New("crash")
+	}
+	if int64(len(p)) > f.n {
+		p = p[:f.n]
+	}
+	n, err := f.r.Read(p)
+	f.n -= int64(n)
+	return n, err
+}
+
+func (f *failingReader) Seek(offset int64, whence int) (int64, error) {
+	return f.r.Seek(offset, whence)
+}
+
+func TestCheckpointer(t *testing.T) {
+	h, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	ck, _ := New(aes.NewCipher, []byte("fedcba9876543210"))
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+	h.Write(data)
+	exp := h.Sum(nil)
+
+	path := filepath.Join(t.TempDir(), "ckpt")
+	c := &Checkpointer{Hash: h, CheckpointHash: ck, Path: path, Interval: 999}
+	tag, err := c.Sum(bytes.NewReader(data))
+	if err != nil || !bytes.Equal(tag, exp) {
+		t.Fatalf("got %x, %v, expected %x", tag, err, exp)
+	}
+
+	// crash after 5000 bytes, and resume with a reader failing at the start
+	if _, err := c.Sum(&failingReader{r: bytes.NewReader(data), n: 5000}); err == nil {
+		t.Fatal("unexpected nil error")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal("missing checkpoint file: ", err)
+	}
+	// the resumed computation must not read the first 4995 bytes
+	tag, err = c.Sum(&failingReader{r: bytes.NewReader(data), n: int64(len(data)) - 4995 + 1})
+	if err != nil || !bytes.Equal(tag, exp) {
+		t.Fatalf("got %x, %v, expected %x", tag, err, exp)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("checkpoint file not removed")
+	}
+
+	// tampered checkpoint
+	c.Sum(&failingReader{r: bytes.NewReader(data), n: 2000})
+	b, _ := os.ReadFile(path)
+	b[15] ^= 1
+	os.WriteFile(path, b, 0600)
+	if _, err := c.Sum(bytes.NewReader(data)); err != ErrInvalidCheckpoint {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidCheckpoint)
+	}
+	os.WriteFile(path, b[:10], 0600)
+	if _, err := c.Sum(bytes.NewReader(data)); err != ErrInvalidCheckpoint {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidCheckpoint)
+	}
+
+	c.Interval = 0
+	if _, err := c.Sum(bytes.NewReader(data)); err == nil {
+		t.Errorf("unexpected nil error for invalid interval")
+	}
+}
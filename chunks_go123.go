@@ -0,0 +1,77 @@
+//go:build go1.23
+// +build go1.23
+
+package cmac
+
+import (
+	"encoding/binary"
+	"hash"
+	"io"
+	"iter"
+)
+
+// ChunkTags computes the chunk tags of a chunked Sidecar while the data is
+// read, and yields them with All. It requires Go 1.23 or later.
+type ChunkTags struct {
+	h         hash.Hash
+	r         io.Reader
+	chunkSize int64
+	chunks    [][]byte
+	total     uint64
+	done      bool
+	err       error
+}
+
+// NewChunkTags returns the chunk tags, computed with h, of the data read
+// from r in chunks of chunkSize bytes.
+func NewChunkTags(h hash.Hash, r io.Reader, chunkSize int64) *ChunkTags {
+	c := &ChunkTags{h: h, r: r, chunkSize: chunkSize}
+	if chunkSize <= 0 {
+		c.err = newError(ErrInvalidArgument, "cmac: invalid chunk size")
+	}
+	return c
+}
+
+// All returns an iterator over the chunk indexes and tags, in order, as
+// they are read. The tags may be retained. The iteration stops at the end
+// of the data or at the first error, returned by Err. It may only be
+// iterated once.
+func (c *ChunkTags) All() iter.Seq2[int, []byte] {
+	return func(yield func(int, []byte) bool) {
+		var hdr [8]byte
+		for !c.done && c.err == nil {
+			i := len(c.chunks)
+			c.h.Reset()
+			binary.BigEndian.PutUint64(hdr[:], uint64(i))
+			c.h.Write(hdr[:])
+			n, err := io.CopyN(c.h, c.r, c.chunkSize)
+			if err == io.EOF {
+				c.done = true
+			} else if err != nil {
+				c.err = wrapError(ErrIO, err)
+				return
+			}
+			if n == 0 {
+				return
+			}
+			tag := c.h.Sum(nil)
+			c.chunks = append(c.chunks, tag)
+			c.total += uint64(n)
+			if !yield(i, tag) {
+				return
+			}
+		}
+	}
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (c *ChunkTags) Err() error { return c.err }
+
+// Tag returns the tag of the whole data, as in the Sidecar, once all the
+// chunks have been iterated, or nil otherwise.
+func (c *ChunkTags) Tag() []byte {
+	if !c.done || c.err != nil {
+		return nil
+	}
+	return sumChunkTags(c.h, c.total, c.chunks)
+}
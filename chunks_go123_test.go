@@ -0,0 +1,58 @@
+//go:build go1.23
+// +build go1.23
+
+package cmac
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestChunkTags(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	for _, size := range []int{0, 1, 99, 100, 250} {
+		data := bytes.Repeat([]byte{'d'}, size)
+		s, err := NewSidecar(AES128, key, "", bytes.NewReader(data), 100)
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		h, _ := AES128.New(key)
+		c := NewChunkTags(h, bytes.NewReader(data), 100)
+		n := 0
+		for i, tag := range c.All() {
+			if i != n || i >= len(s.Chunks) || !bytes.Equal(tag, s.Chunks[i]) {
+				t.Errorf("%d: got chunk %d tag %x", size, i, tag)
+			}
+			n++
+		}
+		if c.Err() != nil || n != len(s.Chunks) || !bytes.Equal(c.Tag(), s.Tag) {
+			t.Errorf("%d: got %d chunks, tag %x, %v, expected %d, %x", size, n, c.Tag(), c.Err(), len(s.Chunks), s.Tag)
+		}
+	}
+
+	// early break
+	h, _ := AES128.New(key)
+	c := NewChunkTags(h, bytes.NewReader(make([]byte, 300)), 100)
+	for range c.All() {
+		break
+	}
+	if c.Tag() != nil {
+		t.Errorf("got tag %x for incomplete iteration", c.Tag())
+	}
+
+	errRead := errors.New("read error")
+	c = NewChunkTags(h, io.MultiReader(bytes.NewReader(make([]byte, 150)), iotest.ErrReader(errRead)), 100)
+	n := 0
+	for range c.All() {
+		n++
+	}
+	if n != 1 || !errors.Is(c.Err(), errRead) || !errors.Is(c.Err(), ErrIO) || c.Tag() != nil {
+		t.Errorf("got %d chunks, %v, expected 1, %v", n, c.Err(), errRead)
+	}
+	if c = NewChunkTags(h, bytes.NewReader(nil), 0); !errors.Is(c.Err(), ErrInvalidArgument) {
+		t.Errorf("got error %v, expected %v", c.Err(), ErrInvalidArgument)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build go1.25
+// +build go1.25
+
+package cmac
+
+import "hash"
+
+// Clone returns a copy of the hash with an independent state. It implements
+// hash.Cloner and never fails.
+func (c *cmac) Clone() (hash.Cloner, error) {
+	return c.clone(), nil
+}
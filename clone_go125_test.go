@@ -0,0 +1,37 @@
+//go:build go1.25
+// +build go1.25
+
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"hash"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	h, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	c, ok := h.(hash.Cloner)
+	if !ok {
+		t.Fatal("hash doesn't implement hash.Cloner")
+	}
+	h.Write([]byte("a message longer than a block, "))
+	d, err := c.Clone()
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	h.Write([]byte("first end"))
+	d.Write([]byte("second end"))
+
+	ref, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	ref.Write([]byte("a message longer than a block, second end"))
+	if got, exp := d.Sum(nil), ref.Sum(nil); !bytes.Equal(got, exp) {
+		t.Errorf("got clone tag %x, expected %x", got, exp)
+	}
+	ref.Reset()
+	ref.Write([]byte("a message longer than a block, first end"))
+	if got, exp := h.Sum(nil), ref.Sum(nil); !bytes.Equal(got, exp) {
+		t.Errorf("got tag %x, expected %x", got, exp)
+	}
+}
@@ -15,17 +15,58 @@ timing side-channels:
 
 	// CheckMAC reports whether messageMAC is a valid HMAC tag for message.
 	func CheckMAC(message, messageMAC, key []byte) bool {
-		mac := cmac.New(aes.New, key)
+		mac, _ := cmac.NewAES(key)
 		mac.Write(message)
 		expectedMAC := mac.Sum(nil)
 		return cmac.Equal(messageMAC, expectedMAC)
 	}
+
+The hash returned by New implements encoding.BinaryMarshaler and
+encoding.BinaryUnmarshaler, as well as gob.GobEncoder and gob.GobDecoder, to
+save and restore the state of a computation. The state doesn't contain the
+key, so it must be restored into a hash created with the same key. It can't
+be decoded into an interface value of a gob encoded struct; a struct should
+hold the []byte state instead.
+
+The hash returned by New also has the following methods, accessible with a
+type assertion:
+
+	// WriteVec accumulates the bytes of all buffers, e.g. net.Buffers.
+	WriteVec(bufs [][]byte) (n int, err error)
+
+	// SumN appends the CMAC truncated to n bytes to dst.
+	SumN(dst []byte, n int) []byte
+
+	// Cipher returns the block cipher.
+	Cipher() cipher.Block
+
+	// SetCipher replaces the block cipher and resets the hash.
+	SetCipher(c cipher.Block) error
+
+	// Len returns the number of bytes written since the last Reset.
+	Len() uint64
+
+	// SetMaxLen sets the maximum message length in bytes.
+	SetMaxLen(max uint64)
+
+With Go 1.25 or later, it implements hash.Cloner. The clone shares the
+block cipher and has an independent state.
+
+The cmacselftest build tag makes the package initialization run known answer
+tests and panic when one fails.
+
+The cmac_small build tag removes the HTTP, archive, JSON, SQL row and struct
+helpers, which depend on large or reflection based packages, to reduce the
+size of binaries, e.g. for js/wasm.
 */
 package cmac
 
 import (
+	"crypto/aes"
 	"crypto/cipher"
+	"encoding/binary"
 	"hash"
+	"math"
 )
 
 /* CMAC uses mac with no iv to compute the MAC.
@@ -56,7 +97,8 @@ followed by as many bit 0 as required.
 K1 and K2 have the size of a block and are computed as follow:
 
    const_zero = [0, ..., 0, 0]
-   const_Rb   = [0, ..., 0, 0x87]
+   const_Rb   = [0, ..., 0, 0x87] for 128 and 192 bit blocks
+              = [0, ..., 0, 0x04, 0x25] for 256 bit blocks
 
    Step 1.  L := AES-128(K, const_Zero);
    Step 2.  if MostSignificantBit(L) is equal to 0
@@ -69,6 +111,8 @@ K1 and K2 have the size of a block and are computed as follow:
 
 type cmac struct {
 	blockSize, n   int
+	size           int // tag size
+	len, maxLen    uint64
 	mac, k1, k2, x []byte
 	cipher         cipher.Block
 }
@@ -77,28 +121,168 @@ type cmac struct {
 type NewCipherFunc func(key []byte) (cipher.Block, error)
 
 // New returns a new CMAC hash using the given cipher instantiation function and key.
+// The block size of the cipher must be 8, 16, 24 or 32 bytes.
 func New(newCipher NewCipherFunc, key []byte) (hash.Hash, error) {
 	c, err := newCipher(key)
+	if err != nil {
+		return nil, wrapError(ErrInvalidKey, err)
+	}
+	cm, err := newCMAC(c)
+	if err != nil {
+		return nil, err
+	}
+	return cm, nil
+}
+
+// NewWithTagSize returns a new CMAC hash like New, whose tags are truncated
+// to their first tagSize bytes, e.g. 12 for AES-CMAC-96 of RFC 4494. Size
+// returns tagSize, and Sum appends tagSize bytes. tagSize must be at least
+// 8 bytes, the minimum recommended by NIST SP 800-38B, and at most the
+// block size.
+func NewWithTagSize(newCipher NewCipherFunc, key []byte, tagSize int) (hash.Hash, error) {
+	c, err := newCipher(key)
+	if err != nil {
+		return nil, wrapError(ErrInvalidKey, err)
+	}
+	if tagSize < minTagSize || tagSize > c.BlockSize() {
+		return nil, newError(ErrInvalidArgument, "cmac: invalid tag size")
+	}
+	cm, err := newCMAC(c)
 	if err != nil {
 		return nil, err
 	}
+	cm.size = tagSize
+	return cm, nil
+}
+
+// newCMAC returns a new CMAC with the block cipher c. It returns an error
+// when the block size has no Rb constant.
+func newCMAC(c cipher.Block) (*cmac, error) {
 	var bs = c.BlockSize()
+	if rbConst(bs) == 0 {
+		return nil, errBlockSize
+	}
 	var cm = new(cmac)
 	cm.blockSize = bs
+	cm.size = bs
+	cm.maxLen = math.MaxUint64
 	b := make([]byte, 4*bs)
 	cm.mac, cm.k1, cm.k2, cm.x = b[:bs], b[bs:2*bs], b[2*bs:3*bs], b[3*bs:4*bs]
 	cm.cipher = c
-	c.Encrypt(cm.k1, cm.k1)
-	tmp := cm.k1[0]
-	shiftLeftOneBit(cm.k1, cm.k1)
-	cm.k1[bs-1] ^= 0x87 & byte(int8(tmp)>>7) // xor with 0x87 when most significant bit of tmp is 1
-	tmp = cm.k1[0]
-	shiftLeftOneBit(cm.k2, cm.k1)
-	cm.k2[bs-1] ^= 0x87 & byte(int8(tmp)>>7) // xor with 0x87 when most significant bit of tmp is 1
+	cm.deriveSubkeys()
 	return cm, nil
 }
 
-func (c *cmac) Size() int { return c.blockSize }
+// deriveSubkeys computes k1 and k2 with the cipher.
+func (c *cmac) deriveSubkeys() {
+	for i := range c.k1 {
+		c.k1[i] = 0
+	}
+	c.cipher.Encrypt(c.k1, c.k1)
+	rb := rbConst(c.blockSize)
+	tmp := c.k1[0]
+	shiftLeftOneBit(c.k1, c.k1)
+	xorRb(c.k1, rb, tmp)
+	tmp = c.k1[0]
+	shiftLeftOneBit(c.k2, c.k1)
+	xorRb(c.k2, rb, tmp)
+}
+
+// Cipher returns the block cipher of the CMAC.
+func (c *cmac) Cipher() cipher.Block { return c.cipher }
+
+// SetCipher replaces the block cipher of the CMAC, derives the subkeys of
+// the new cipher and resets the computation, so that data written before
+// is discarded. The new cipher must have the same block size.
+func (c *cmac) SetCipher(b cipher.Block) error {
+	if b.BlockSize() != c.blockSize {
+		return newError(ErrInvalidArgument, "cmac: invalid cipher block size")
+	}
+	c.cipher = b
+	c.deriveSubkeys()
+	c.Reset()
+	return nil
+}
+
+// NewAES returns a new AES-CMAC hash with the key. The AES variant is
+// selected by the key size of 16, 24 or 32 bytes.
+func NewAES(key []byte) (hash.Hash, error) {
+	return New(aes.NewCipher, key)
+}
+
+// SumAES128 returns the AES-128-CMAC of msg with the 16 byte key. It panics
+// if the key size is invalid.
+func SumAES128(key, msg []byte) [16]byte { return sumAES(key, msg, 16) }
+
+// SumAES192 returns the AES-192-CMAC of msg with the 24 byte key. It panics
+// if the key size is invalid.
+func SumAES192(key, msg []byte) [16]byte { return sumAES(key, msg, 24) }
+
+// SumAES256 returns the AES-256-CMAC of msg with the 32 byte key. It panics
+// if the key size is invalid.
+func SumAES256(key, msg []byte) [16]byte { return sumAES(key, msg, 32) }
+
+func sumAES(key, msg []byte, keySize int) (tag [16]byte) {
+	if len(key) != keySize {
+		panic("cmac: invalid key size")
+	}
+	h, _ := NewAES(key)
+	h.Write(msg)
+	h.Sum(tag[:0])
+	return tag
+}
+
+// MustNew is like New but panics if the hash can't be created. It simplifies
+// the initialization of package variables and tests with constant keys.
+func MustNew(newCipher NewCipherFunc, key []byte) hash.Hash {
+	h, err := New(newCipher, key)
+	if err != nil {
+		panic("cmac: MustNew: " + err.Error())
+	}
+	return h
+}
+
+// errBlockSize is returned for a block cipher whose block size isn't 8, 16,
+// 24 or 32 bytes.
+var errBlockSize = newError(ErrInvalidArgument, "cmac: unsupported cipher block size")
+
+// rbConst returns the constant Rb of the block size in bytes, or 0 for an
+// unsupported block size. It is defined by the irreducible polynomial of
+// degree 8*blockSize with the fewest nonzero terms, e.g. x^128 + x^7 + x^2 +
+// x + 1 for 128 bit blocks.
+func rbConst(blockSize int) uint16 {
+	switch blockSize {
+	case 8:
+		return 0x1b // x^64 + x^4 + x^3 + x + 1
+	case 16, 24:
+		return 0x87 // x^128 + x^7 + x^2 + x + 1 and x^192 + x^7 + x^2 + x + 1
+	case 32:
+		return 0x425 // x^256 + x^10 + x^5 + x^2 + 1
+	}
+	return 0
+}
+
+// xorRb xors the last bytes of k with rb when the most significant bit of
+// msb is 1, in constant time.
+func xorRb(k []byte, rb uint16, msb byte) {
+	mask := byte(int8(msb) >> 7)
+	k[len(k)-1] ^= byte(rb) & mask
+	k[len(k)-2] ^= byte(rb>>8) & mask
+}
+
+// clone returns a copy of c with an independent state sharing the cipher.
+func (c *cmac) clone() *cmac {
+	bs := c.blockSize
+	b := make([]byte, 4*bs)
+	d := &cmac{blockSize: bs, n: c.n, size: c.size, len: c.len, maxLen: c.maxLen, cipher: c.cipher}
+	d.mac, d.k1, d.k2, d.x = b[:bs], b[bs:2*bs], b[2*bs:3*bs], b[3*bs:4*bs]
+	copy(d.k1, c.k1)
+	copy(d.k2, c.k2)
+	copy(d.x, c.x)
+	return d
+}
+
+func (c *cmac) Size() int { return c.size }
 
 func (c *cmac) BlockSize() int { return c.blockSize }
 
@@ -111,8 +295,24 @@ func shiftLeftOneBit(dst, src []byte) {
 	}
 }
 
-// Write accumulates the bytes in m in the cmac computation.
+// Len returns the number of bytes written since the last Reset, which is
+// kept in the marshaled state.
+func (c *cmac) Len() uint64 { return c.len }
+
+// SetMaxLen sets the maximum length in bytes of the messages. Write returns
+// ErrTooLong, without accumulating any byte, when the message would
+// become longer. The default maximum is 2^64-1, so that the length never
+// wraps. max may be smaller than the bytes already written, in which case
+// Reset must be called to write more.
+func (c *cmac) SetMaxLen(max uint64) { c.maxLen = max }
+
+// Write accumulates the bytes in m in the cmac computation. It returns
+// ErrTooLong when the message would exceed the maximum length.
 func (c *cmac) Write(m []byte) (n int, err error) {
+	if c.len > c.maxLen || uint64(len(m)) > c.maxLen-c.len {
+		return 0, ErrTooLong
+	}
+	c.len += uint64(len(m))
 	n = len(m)
 	if l := c.blockSize - c.n; len(m) > l {
 		xor(c.x[c.n:], m[:l])
@@ -132,8 +332,27 @@ func (c *cmac) Write(m []byte) (n int, err error) {
 	return
 }
 
-// Sum returns the CMAC appended to m. m may be nil. Write may be called after Sum.
+// WriteVec accumulates the bytes of all buffers in the cmac computation, as
+// if they were concatenated. It accepts net.Buffers.
+func (c *cmac) WriteVec(bufs [][]byte) (n int, err error) {
+	for _, b := range bufs {
+		if _, err = c.Write(b); err != nil {
+			return
+		}
+		n += len(b)
+	}
+	return
+}
+
+// Sum returns the CMAC, truncated to the tag size, appended to m. m may be
+// nil. Write may be called after Sum.
 func (c *cmac) Sum(m []byte) []byte {
+	c.sum()
+	return append(m, c.mac[:c.size]...)
+}
+
+// sum computes the untruncated CMAC in c.mac.
+func (c *cmac) sum() {
 	if c.n == c.blockSize {
 		copy(c.mac, c.k1)
 	} else {
@@ -142,7 +361,17 @@ func (c *cmac) Sum(m []byte) []byte {
 	}
 	xor(c.mac, c.x)
 	c.cipher.Encrypt(c.mac, c.mac)
-	return append(m, c.mac...)
+}
+
+// SumN returns the CMAC truncated to its first n bytes appended to dst. It
+// panics if n is smaller than the minimum tag size of 8 bytes or larger than
+// the tag size, which is the block size unless set with NewWithTagSize.
+func (c *cmac) SumN(dst []byte, n int) []byte {
+	if n < minTagSize || n > c.size {
+		panic("cmac: invalid tag size")
+	}
+	c.sum()
+	return append(dst, c.mac[:n]...)
 }
 
 // Reset the the CMAC
@@ -151,6 +380,68 @@ func (c *cmac) Reset() {
 		c.x[i] = 0
 	}
 	c.n = 0
+	c.len = 0
+}
+
+// The marshaled state is magic || block size || n || length || x, where
+// length is the uint64 message length in big endian. The last byte of magic
+// is the state version. A new version may only be introduced with a new
+// magic, and UnmarshalBinary must keep accepting the previous versions so
+// that stored states remain valid. The state never contains the key.
+//
+// The version 1 state, magic1 || block size || n || x, has no length. The
+// length of a restored version 1 state restarts at 0.
+const (
+	magic          = "cmac\x02"
+	marshaledSize  = len(magic) + 10
+	magic1         = "cmac\x01"
+	marshaledSize1 = len(magic1) + 2
+)
+
+// MarshalBinary returns the state of the CMAC computation. The state doesn't
+// contain the key. It implements encoding.BinaryMarshaler.
+func (c *cmac) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, marshaledSize+c.blockSize)
+	b = append(b, magic...)
+	b = append(b, byte(c.blockSize), byte(c.n))
+	var l [8]byte
+	binary.BigEndian.PutUint64(l[:], c.len)
+	b = append(b, l[:]...)
+	return append(b, c.x...), nil
+}
+
+// UnmarshalBinary restores a state returned by MarshalBinary. The CMAC must
+// have been created with the same cipher and key as the one that returned
+// the state. It implements encoding.BinaryUnmarshaler.
+func (c *cmac) UnmarshalBinary(b []byte) error {
+	size, l := marshaledSize, uint64(0)
+	if len(b) >= len(magic1) && string(b[:len(magic1)]) == magic1 {
+		size = marshaledSize1
+	} else if len(b) >= marshaledSize && string(b[:len(magic)]) == magic {
+		l = binary.BigEndian.Uint64(b[len(magic)+2:])
+	} else {
+		return newError(ErrInvalidArgument, "cmac: invalid hash state")
+	}
+	if len(b) != size+c.blockSize || int(b[len(magic)]) != c.blockSize ||
+		int(b[len(magic)+1]) > c.blockSize {
+		return newError(ErrInvalidArgument, "cmac: invalid hash state")
+	}
+	c.n = int(b[len(magic)+1])
+	c.len = l
+	copy(c.x, b[size:])
+	return nil
+}
+
+// GobEncode returns the state of the CMAC computation, as MarshalBinary. It
+// implements gob.GobEncoder.
+func (c *cmac) GobEncode() ([]byte, error) {
+	return c.MarshalBinary()
+}
+
+// GobDecode restores a state returned by GobEncode or MarshalBinary, as
+// UnmarshalBinary. It implements gob.GobDecoder.
+func (c *cmac) GobDecode(b []byte) error {
+	return c.UnmarshalBinary(b)
 }
 
 // xor stores a xor b in a. The length of b must be smaller or equal to a.
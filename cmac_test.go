@@ -3,7 +3,14 @@ package cmac
 import (
 	"bytes"
 	"crypto/aes"
+	"crypto/cipher"
+	"encoding"
+	"encoding/gob"
 	"encoding/hex"
+	"errors"
+	"math"
+	"math/big"
+	"net"
 	"testing"
 )
 
@@ -213,3 +220,416 @@ func TestMultiWrite(t *testing.T) {
 		t.Fatalf("mac mismatch")
 	}
 }
+
+func TestMarshal(t *testing.T) {
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	msg, _ := hex.DecodeString("6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411")
+	mac := "dfa66747de9ae63030ca32611497c827"
+
+	for _, split := range []int{0, 1, 16, 17, 32, 40} {
+		cm1, _ := New(aes.NewCipher, key)
+		cm1.Write(msg[:split])
+		state, err := cm1.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			t.Fatalf("%2d: unexpected error: %s", split, err)
+		}
+		cm2, _ := New(aes.NewCipher, key)
+		cm2.Write([]byte("garbage"))
+		if err := cm2.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+			t.Fatalf("%2d: unexpected error: %s", split, err)
+		}
+		cm2.Write(msg[split:])
+		if hex.EncodeToString(cm2.Sum(nil)) != mac {
+			t.Errorf("%2d: mac mismatch", split)
+		}
+	}
+
+	cm, _ := New(aes.NewCipher, key)
+	state, _ := cm.(encoding.BinaryMarshaler).MarshalBinary()
+	tests := [][]byte{
+		nil,
+		state[:len(state)-1],
+		append([]byte("cmac\x03"), state[5:]...),
+		append([]byte("cmac\x02\x08"), state[6:]...),
+		append([]byte("cmac\x02\x10\x11"), state[7:]...),
+		append([]byte("cmac\x01"), state[5:]...),
+	}
+	for i, test := range tests {
+		if err := cm.(encoding.BinaryUnmarshaler).UnmarshalBinary(test); err == nil {
+			t.Errorf("%2d: unexpected nil error", i)
+		}
+	}
+
+	// version 1 states are accepted
+	cm.Write(msg[:17])
+	state, _ = cm.(encoding.BinaryMarshaler).MarshalBinary()
+	v1 := append([]byte("cmac\x01"), state[5:7]...)
+	v1 = append(v1, state[15:]...)
+	cm.Reset()
+	if err := cm.(encoding.BinaryUnmarshaler).UnmarshalBinary(v1); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	cm.Write(msg[17:])
+	if hex.EncodeToString(cm.Sum(nil)) != mac {
+		t.Errorf("mac mismatch for version 1 state")
+	}
+}
+
+func TestLen(t *testing.T) {
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	cm, _ := New(aes.NewCipher, key)
+	l := cm.(interface{ Len() uint64 })
+	var expected uint64
+	for _, n := range []int{0, 3, 16, 29} {
+		cm.Write(make([]byte, n))
+		cm.Sum(nil)
+		expected += uint64(n)
+		if l.Len() != expected {
+			t.Errorf("got length %d, expected %d", l.Len(), expected)
+		}
+	}
+	state, _ := cm.(encoding.BinaryMarshaler).MarshalBinary()
+	cm.Reset()
+	if l.Len() != 0 {
+		t.Errorf("got length %d after Reset, expected 0", l.Len())
+	}
+	cm.(encoding.BinaryUnmarshaler).UnmarshalBinary(state)
+	if l.Len() != expected {
+		t.Errorf("got length %d after restore, expected %d", l.Len(), expected)
+	}
+}
+
+func TestMaxLen(t *testing.T) {
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	cm, _ := New(aes.NewCipher, key)
+	cm.(interface{ SetMaxLen(max uint64) }).SetMaxLen(20)
+	for i, test := range []struct {
+		n   int
+		err error
+	}{{10, nil}, {11, ErrTooLong}, {10, nil}, {1, ErrTooLong}, {0, nil}} {
+		if n, err := cm.Write(make([]byte, test.n)); err != test.err || (err == nil && n != test.n) || (err != nil && n != 0) {
+			t.Errorf("%d: got %d, %v, expected %d, %v", i, n, err, test.n, test.err)
+		}
+	}
+	if !errors.Is(ErrTooLong, ErrInvalidArgument) {
+		t.Errorf("expected ErrTooLong to be an ErrInvalidArgument")
+	}
+
+	// the length is kept in the state
+	state, _ := cm.(encoding.BinaryMarshaler).MarshalBinary()
+	cm.Reset()
+	cm.Write(make([]byte, 20))
+	cm.Reset()
+	if err := cm.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if _, err := cm.Write([]byte{0}); err != ErrTooLong {
+		t.Errorf("got error %v, expected %v after restore", err, ErrTooLong)
+	}
+
+	// the default maximum prevents the length from wrapping
+	c := cm.(*cmac)
+	c.SetMaxLen(math.MaxUint64)
+	c.len = math.MaxUint64 - 1
+	if _, err := c.Write([]byte{0, 0}); err != ErrTooLong {
+		t.Errorf("got error %v, expected %v on overflow", err, ErrTooLong)
+	}
+}
+
+func TestWriteVec(t *testing.T) {
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	msg, _ := hex.DecodeString("6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411")
+	mac := "dfa66747de9ae63030ca32611497c827"
+
+	cm, _ := New(aes.NewCipher, key)
+	w := cm.(interface {
+		WriteVec(bufs [][]byte) (int, error)
+	})
+	n, err := w.WriteVec(net.Buffers{msg[:3], nil, msg[3:16], msg[16:33], msg[33:]})
+	if err != nil || n != len(msg) {
+		t.Fatalf("got %d, %v, expected %d", n, err, len(msg))
+	}
+	if hex.EncodeToString(cm.Sum(nil)) != mac {
+		t.Errorf("mac mismatch")
+	}
+}
+
+func TestSumN(t *testing.T) {
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	cm, _ := New(aes.NewCipher, key)
+	s := cm.(interface {
+		SumN(dst []byte, n int) []byte
+	})
+	for _, n := range []int{8, 12, 16} {
+		if mac := s.SumN([]byte{1}, n); hex.EncodeToString(mac) != "01"+"bb1d6929e95937287fa37d129b756746"[:2*n] {
+			t.Errorf("%d: got %x", n, mac)
+		}
+	}
+	for _, n := range []int{0, 7, 17} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%d: expected panic", n)
+				}
+			}()
+			s.SumN(nil, n)
+		}()
+	}
+}
+
+// wideBlock is a test cipher with a block of size bytes made of AES blocks.
+type wideBlock struct {
+	size int
+	c    cipher.Block
+}
+
+func (w wideBlock) BlockSize() int { return w.size }
+
+func (w wideBlock) Encrypt(dst, src []byte) {
+	copy(dst, src)
+	for i := 0; i < w.size; i += aes.BlockSize {
+		if i+aes.BlockSize > w.size {
+			i = w.size - aes.BlockSize // overlapping last window
+		}
+		w.c.Encrypt(dst[i:i+aes.BlockSize], dst[i:i+aes.BlockSize])
+	}
+}
+
+func (w wideBlock) Decrypt(dst, src []byte) { panic("not implemented") }
+
+func TestNewWithTagSize(t *testing.T) {
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	msg, _ := hex.DecodeString("6bc1bee22e409f96e93d7e117393172a")
+	mac := "070a16b46b4d4144f79bdd9dd04a287c"
+	for _, n := range []int{8, 12, 16} {
+		h, err := NewWithTagSize(aes.NewCipher, key, n)
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		h.Write(msg)
+		if tag := h.Sum([]byte{1}); h.Size() != n || hex.EncodeToString(tag) != "01"+mac[:2*n] {
+			t.Errorf("%d: got size %d and tag %x", n, h.Size(), tag)
+		}
+		// SumN is limited by the tag size
+		s := h.(interface {
+			SumN(dst []byte, n int) []byte
+		})
+		if tag := s.SumN(nil, n); hex.EncodeToString(tag) != mac[:2*n] {
+			t.Errorf("%d: got %x from SumN", n, tag)
+		}
+		if n < 16 {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("%d: expected panic for %d bytes", n, n+1)
+					}
+				}()
+				s.SumN(nil, n+1)
+			}()
+		}
+		if c, _ := h.(*cmac); c.clone().Size() != n {
+			t.Errorf("%d: got clone size %d", n, c.clone().Size())
+		}
+	}
+	for _, n := range []int{0, 7, 17} {
+		if _, err := NewWithTagSize(aes.NewCipher, key, n); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%d: got error %v, expected %v", n, err, ErrInvalidArgument)
+		}
+	}
+	if _, err := NewWithTagSize(aes.NewCipher, key[:5], 12); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidKey)
+	}
+}
+
+func TestUnsupportedBlockSize(t *testing.T) {
+	c, _ := aes.NewCipher(make([]byte, 16))
+	for _, size := range []int{20, 48, 64} {
+		wb := wideBlock{size: size, c: c}
+		h, err := New(func([]byte) (cipher.Block, error) { return wb, nil }, nil)
+		if h != nil || !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%d: got %v, %v, expected %v", size, h, err, ErrInvalidArgument)
+		}
+	}
+}
+
+func TestLargeBlock(t *testing.T) {
+	key := make([]byte, 16)
+	for _, tc := range []struct {
+		size int
+		rb   int64
+	}{
+		{16, 0x87},
+		{24, 0x87},
+		{32, 0x425},
+	} {
+		c, _ := aes.NewCipher(key)
+		wb := wideBlock{size: tc.size, c: c}
+		h, err := New(func([]byte) (cipher.Block, error) { return wb, nil }, key)
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		if h.Size() != tc.size || h.BlockSize() != tc.size {
+			t.Errorf("%d: got sizes %d and %d", tc.size, h.Size(), h.BlockSize())
+		}
+
+		// doubling in GF(2^n) with math/big
+		n := uint(8 * tc.size)
+		mod := new(big.Int).Lsh(big.NewInt(1), n)
+		mod.Or(mod, big.NewInt(tc.rb))
+		dbl := func(b []byte) []byte {
+			v := new(big.Int).SetBytes(b)
+			v.Lsh(v, 1)
+			if v.Bit(int(n)) == 1 {
+				v.Xor(v, mod)
+			}
+			return v.FillBytes(make([]byte, tc.size))
+		}
+		l := make([]byte, tc.size)
+		wb.Encrypt(l, l)
+		k1 := dbl(l)
+		k2 := dbl(k1)
+		cm := h.(*cmac)
+		if !bytes.Equal(cm.k1, k1) || !bytes.Equal(cm.k2, k2) {
+			t.Errorf("%d: got subkeys %x %x, expected %x %x", tc.size, cm.k1, cm.k2, k1, k2)
+		}
+
+		// CBC-MAC of a message with a partial last block
+		msg := bytes.Repeat([]byte{0x5a}, tc.size+3)
+		x := make([]byte, tc.size)
+		xor(x, msg[:tc.size])
+		wb.Encrypt(x, x)
+		last := make([]byte, tc.size)
+		copy(last, msg[tc.size:])
+		last[3] = 0x80
+		xor(x, last)
+		xor(x, k2)
+		wb.Encrypt(x, x)
+		h.Write(msg)
+		if got := h.Sum(nil); !bytes.Equal(got, x) {
+			t.Errorf("%d: got %x, expected %x", tc.size, got, x)
+		}
+	}
+}
+
+func TestMustNew(t *testing.T) {
+	h := MustNew(aes.NewCipher, []byte("0123456789abcdef"))
+	ref, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	if !bytes.Equal(h.Sum(nil), ref.Sum(nil)) {
+		t.Errorf("got %x, expected %x", h.Sum(nil), ref.Sum(nil))
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic for invalid key")
+		}
+	}()
+	MustNew(aes.NewCipher, []byte("short"))
+}
+
+func TestNewAES(t *testing.T) {
+	for _, alg := range []Algorithm{AES128, AES192, AES256} {
+		key := make([]byte, alg.KeySize())
+		h, err := NewAES(key)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", alg, err)
+		}
+		ref, _ := alg.New(key)
+		if !bytes.Equal(h.Sum(nil), ref.Sum(nil)) {
+			t.Errorf("%s: got %x, expected %x", alg, h.Sum(nil), ref.Sum(nil))
+		}
+	}
+	if _, err := NewAES(make([]byte, 20)); err == nil {
+		t.Errorf("unexpected nil error for invalid key size")
+	}
+}
+
+func TestSumAES(t *testing.T) {
+	msg, _ := hex.DecodeString("6bc1bee22e409f96e93d7e117393172a")
+	tests := []struct {
+		key, mac string
+		sum      func(key, msg []byte) [16]byte
+	}{
+		// RFC 4493 and NIST SP 800-38B examples with a 16 byte message
+		{"2b7e151628aed2a6abf7158809cf4f3c", "070a16b46b4d4144f79bdd9dd04a287c", SumAES128},
+		{"8e73b0f7da0e6452c810f32b809079e562f8ead2522c6b7b", "9e99a7bf31e710900662f65e617c5184", SumAES192},
+		{"603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4", "28a7023f452e8f82bd4bf28d8c37c35c", SumAES256},
+	}
+	for i, test := range tests {
+		key, _ := hex.DecodeString(test.key)
+		if tag := test.sum(key, msg); hex.EncodeToString(tag[:]) != test.mac {
+			t.Errorf("%d: got %x, expected %s", i, tag, test.mac)
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic for invalid key size")
+		}
+	}()
+	SumAES256(make([]byte, 16), msg)
+}
+
+func TestSetCipher(t *testing.T) {
+	h, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	c := h.(interface {
+		Cipher() cipher.Block
+		SetCipher(cipher.Block) error
+	})
+	if c.Cipher() == nil {
+		t.Fatal("got nil cipher")
+	}
+	b, _ := aes.NewCipher([]byte("fedcba9876543210"))
+	h.Write([]byte("discarded"))
+	if err := c.SetCipher(b); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if c.Cipher() != b {
+		t.Errorf("cipher not replaced")
+	}
+	h.Write([]byte("message"))
+	ref, _ := New(aes.NewCipher, []byte("fedcba9876543210"))
+	ref.Write([]byte("message"))
+	if got, exp := h.Sum(nil), ref.Sum(nil); !bytes.Equal(got, exp) {
+		t.Errorf("got %x, expected %x", got, exp)
+	}
+	if err := c.SetCipher(wideBlock{size: 32}); err == nil {
+		t.Errorf("unexpected nil error for invalid block size")
+	}
+}
+
+func TestGob(t *testing.T) {
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	msg, _ := hex.DecodeString("6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411")
+	mac := "dfa66747de9ae63030ca32611497c827"
+
+	// the state follows the job in a gob stream
+	type job struct {
+		Name   string
+		Offset int
+	}
+	cm1, _ := New(aes.NewCipher, key)
+	cm1.Write(msg[:21])
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	if err := enc.Encode(job{Name: "j1", Offset: 21}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if err := enc.Encode(cm1); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if bytes.Contains(buf.Bytes(), key) {
+		t.Errorf("gob encoding contains the key")
+	}
+
+	var j job
+	cm2, _ := New(aes.NewCipher, key)
+	dec := gob.NewDecoder(&buf)
+	if err := dec.Decode(&j); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if err := dec.Decode(cm2); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	cm2.Write(msg[j.Offset:])
+	if got := hex.EncodeToString(cm2.Sum(nil)); got != mac {
+		t.Errorf("got %s, expected %s", got, mac)
+	}
+}
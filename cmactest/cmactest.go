@@ -0,0 +1,93 @@
+// Package cmactest implements support for testing the MAC verification of
+// protocols built on the cmac package.
+package cmactest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SignFunc returns the tag of msg.
+type SignFunc func(msg []byte) ([]byte, error)
+
+// VerifyFunc returns nil if tag is a valid tag of msg.
+type VerifyFunc func(msg, tag []byte) error
+
+// maxErrors is the maximum number of failures reported by CheckMutations.
+const maxErrors = 10
+
+// CheckMutations checks that verify accepts the tag of msg returned by
+// sign, and rejects it when any bit of the tag or of msg is flipped, when
+// the tag or msg is truncated to any shorter length, and when a byte is
+// appended to the tag or msg. It returns an error describing the first
+// failures, or nil when all the checks pass.
+//
+// A test would typically call it as
+//
+//	if err := cmactest.CheckMutations(sign, verify, msg); err != nil {
+//		t.Fatal(err)
+//	}
+//
+// Verifiers accepting truncated tags, such as cmac.Factory.Verify, are
+// checked with CheckMutationsMinTagSize.
+func CheckMutations(sign SignFunc, verify VerifyFunc, msg []byte) error {
+	return CheckMutationsMinTagSize(sign, verify, msg, -1)
+}
+
+// CheckMutationsMinTagSize is like CheckMutations, but verify may accept the
+// tag truncated to minTagSize bytes or more. It must reject the shorter
+// truncations, and the truncated tags with any bit flipped. A negative
+// minTagSize requires the truncations to any shorter length to be rejected.
+func CheckMutationsMinTagSize(sign SignFunc, verify VerifyFunc, msg []byte, minTagSize int) error {
+	tag, err := sign(msg)
+	if err != nil {
+		return fmt.Errorf("cmactest: sign: %v", err)
+	}
+	if err := verify(msg, tag); err != nil {
+		return fmt.Errorf("cmactest: valid tag rejected: %v", err)
+	}
+	var failures []string
+	reject := func(m, t []byte, format string, args ...interface{}) {
+		if len(failures) <= maxErrors && verify(m, t) == nil {
+			failures = append(failures, fmt.Sprintf(format, args...))
+		}
+	}
+	for i := 0; i < 8*len(tag); i++ {
+		reject(msg, flip(tag, i), "tag with bit %d flipped accepted", i)
+	}
+	for i := 0; i < 8*len(msg); i++ {
+		reject(flip(msg, i), tag, "message with bit %d flipped accepted", i)
+	}
+	for n := 0; n < len(tag); n++ {
+		if minTagSize < 0 || n < minTagSize {
+			reject(msg, tag[:n:n], "tag truncated to %d bytes accepted", n)
+			continue
+		}
+		if err := verify(msg, tag[:n:n]); err != nil {
+			continue
+		}
+		for i := 0; i < 8*n; i++ {
+			reject(msg, flip(tag[:n], i), "tag truncated to %d bytes with bit %d flipped accepted", n, i)
+		}
+	}
+	for n := 0; n < len(msg); n++ {
+		reject(msg[:n:n], tag, "message truncated to %d bytes accepted", n)
+	}
+	reject(msg, append(tag[:len(tag):len(tag)], 0), "tag with appended byte accepted")
+	reject(append(msg[:len(msg):len(msg)], 0), tag, "message with appended byte accepted")
+	if len(failures) == 0 {
+		return nil
+	}
+	if len(failures) > maxErrors {
+		failures = append(failures[:maxErrors], "...")
+	}
+	return errors.New("cmactest: " + strings.Join(failures, "\n\t"))
+}
+
+// flip returns a copy of b with bit i flipped.
+func flip(b []byte, i int) []byte {
+	c := append([]byte(nil), b...)
+	c[i/8] ^= 0x80 >> uint(i%8)
+	return c
+}
@@ -0,0 +1,85 @@
+package cmactest
+
+import (
+	"bytes"
+	"crypto/aes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chmike/cmac-go"
+)
+
+func TestCheckMutations(t *testing.T) {
+	h, _ := cmac.New(aes.NewCipher, []byte("0123456789abcdef"))
+	sign := func(msg []byte) ([]byte, error) {
+		h.Reset()
+		h.Write(msg)
+		return h.Sum(nil), nil
+	}
+	verify := func(msg, tag []byte) error {
+		expected, _ := sign(msg)
+		if !cmac.Equal(expected, tag) {
+			return cmac.ErrMismatch
+		}
+		return nil
+	}
+	for _, n := range []int{0, 1, 16, 40} {
+		if err := CheckMutations(sign, verify, bytes.Repeat([]byte{'m'}, n)); err != nil {
+			t.Errorf("%d: unexpected error: %v", n, err)
+		}
+	}
+
+	// a verifier accepting truncated tags
+	truncating := func(msg, tag []byte) error {
+		expected, _ := sign(msg)
+		if len(tag) > len(expected) || !bytes.Equal(expected[:len(tag)], tag) {
+			return cmac.ErrMismatch
+		}
+		return nil
+	}
+	err := CheckMutations(sign, truncating, []byte("message"))
+	if err == nil || !strings.Contains(err.Error(), "tag truncated to 0 bytes accepted") {
+		t.Errorf("got error %v, expected truncated tags to be reported", err)
+	}
+	if n := strings.Count(err.Error(), "\n"); n != maxErrors {
+		t.Errorf("got %d reported failures, expected %d", n+1, maxErrors+1)
+	}
+
+	// a verifier ignoring the message
+	ignoring := func(msg, tag []byte) error { return truncating([]byte("message"), tag) }
+	err = CheckMutations(sign, ignoring, []byte("message"))
+	if err == nil || !strings.Contains(err.Error(), "message with bit 0 flipped accepted") {
+		t.Errorf("got error %v, expected message mutations to be reported", err)
+	}
+
+	// a verifier accepting tags truncated to 8 bytes or more
+	factory, _ := cmac.NewFactory(aes.NewCipher, []byte("0123456789abcdef"))
+	factorySign := func(msg []byte) ([]byte, error) { return factory.Sum(nil, msg), nil }
+	for _, n := range []int{0, 1, 16, 40} {
+		msg := bytes.Repeat([]byte{'m'}, n)
+		if err := CheckMutationsMinTagSize(factorySign, factory.Verify, msg, 8); err != nil {
+			t.Errorf("%d: unexpected error: %v", n, err)
+		}
+	}
+	err = CheckMutations(factorySign, factory.Verify, []byte("message"))
+	if err == nil || !strings.Contains(err.Error(), "tag truncated to 8 bytes accepted") {
+		t.Errorf("got error %v, expected truncated tags to be reported", err)
+	}
+	err = CheckMutationsMinTagSize(factorySign, factory.Verify, []byte("message"), 12)
+	if err == nil || !strings.Contains(err.Error(), "tag truncated to 11 bytes accepted") {
+		t.Errorf("got error %v, expected tags shorter than 12 bytes to be reported", err)
+	}
+	err = CheckMutationsMinTagSize(sign, truncating, []byte("message"), 8)
+	if err == nil || !strings.Contains(err.Error(), "tag truncated to 0 bytes accepted") {
+		t.Errorf("got error %v, expected tags shorter than 8 bytes to be reported", err)
+	}
+
+	errSign := errors.New("sign error")
+	if err := CheckMutations(func([]byte) ([]byte, error) { return nil, errSign }, verify, nil); err == nil {
+		t.Errorf("unexpected nil error")
+	}
+	if err := CheckMutations(sign, func([]byte, []byte) error { return errSign }, nil); err == nil {
+		t.Errorf("unexpected nil error")
+	}
+}
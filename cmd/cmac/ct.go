@@ -0,0 +1,185 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chmike/cmac-go"
+)
+
+/* The ct command is a dudect-style test ("Dude, is my code constant
+time?", Reproducible Research 2017). For each target, the measures of two
+classes of inputs, a fixed one and random ones, are interleaved in random
+order. The measures above the cropping percentile of all measures are
+dropped, to remove the interrupts and scheduling outliers, and the means of
+the two classes are compared with Welch's t-test. A |t| above ctThreshold
+is a statistically significant timing difference.
+*/
+
+// ctThreshold is the |t| above which a target is reported as leaking.
+const ctThreshold = 10
+
+// errLeak is returned by the ct command when a target leaks.
+var errLeak = errors.New("timing leakage detected")
+
+// CTResult is the result of the constant-time test of one target.
+type CTResult struct {
+	Target string  `json:"target"`
+	N      int     `json:"n"`
+	T      float64 `json:"t"`
+	Leak   bool    `json:"leak"`
+}
+
+// ctTarget is a function whose timing is tested. It is called with the
+// fixed or a random input prepared by input.
+type ctTarget struct {
+	name  string
+	input func(fixed bool) []byte
+	run   func(in []byte)
+}
+
+func ctTargets() ([]ctTarget, error) {
+	key := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	f, err := cmac.NewFactory(aes.NewCipher, key)
+	if err != nil {
+		return nil, err
+	}
+	msg := make([]byte, 64)
+	tag := f.Sum(nil, msg)
+	dst := make([]byte, 0, f.Size())
+	// fixed inputs are the valid tag, random inputs differ from it
+	tagInput := func(fixed bool) []byte {
+		if fixed {
+			return tag
+		}
+		return random(len(tag))
+	}
+	return []ctTarget{
+		{"Equal", tagInput, func(in []byte) { cmac.Equal(tag, in) }},
+		{"Factory.Verify", tagInput, func(in []byte) { f.Verify(msg, in) }},
+		// a partial last block tests the padding and finalization path
+		{"Factory.Sum", func(fixed bool) []byte {
+			if fixed {
+				return make([]byte, 15)
+			}
+			return random(15)
+		}, func(in []byte) { f.Sum(dst[:0], in) }},
+	}, nil
+}
+
+func random(n int) []byte {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func runCT(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("ct", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the results in JSON")
+	n := fs.Int("n", 100000, "number of measures per target")
+	batch := fs.Int("batch", 8, "number of calls per measure")
+	crop := fs.Float64("crop", 0.9, "percentile above which measures are dropped")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *n < 2 || *batch < 1 || *crop <= 0 || *crop > 1 {
+		return errors.New("invalid ct parameters")
+	}
+	targets, err := ctTargets()
+	if err != nil {
+		return err
+	}
+	var results []CTResult
+	leak := false
+	for _, tg := range targets {
+		r := ctMeasure(tg, *n, *batch, *crop)
+		leak = leak || r.Leak
+		results = append(results, r)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(results)
+	} else {
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "target\tn\tt\tresult")
+		for _, r := range results {
+			res := "ok"
+			if r.Leak {
+				res = "LEAK"
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%.2f\t%s\n", r.Target, r.N, r.T, res)
+		}
+		err = tw.Flush()
+	}
+	if err == nil && leak {
+		err = errLeak
+	}
+	return err
+}
+
+// ctMeasure returns the t statistic of n measures of the target.
+func ctMeasure(tg ctTarget, n, batch int, crop float64) CTResult {
+	classes := random(n)
+	inputs := make([][]byte, n)
+	for i := range inputs {
+		inputs[i] = tg.input(classes[i]&1 == 0)
+	}
+	times := make([]float64, n)
+	for i, in := range inputs {
+		start := time.Now()
+		for j := 0; j < batch; j++ {
+			tg.run(in)
+		}
+		times[i] = float64(time.Since(start))
+	}
+	sorted := append([]float64(nil), times...)
+	sort.Float64s(sorted)
+	limit := sorted[int(crop*float64(n-1))]
+	var s [2]welford
+	for i, t := range times {
+		if t <= limit {
+			s[classes[i]&1].add(t)
+		}
+	}
+	r := CTResult{Target: tg.name, N: s[0].n + s[1].n}
+	if s[0].n > 1 && s[1].n > 1 {
+		d := math.Sqrt(s[0].variance()/float64(s[0].n) + s[1].variance()/float64(s[1].n))
+		if d > 0 {
+			r.T = (s[0].mean - s[1].mean) / d
+		}
+	}
+	r.Leak = math.Abs(r.T) > ctThreshold
+	return r
+}
+
+// welford computes the mean and variance of a sample online.
+type welford struct {
+	n        int
+	mean, m2 float64
+}
+
+func (w *welford) add(x float64) {
+	w.n++
+	d := x - w.mean
+	w.mean += d / float64(w.n)
+	w.m2 += d * (x - w.mean)
+}
+
+func (w *welford) variance() float64 {
+	return w.m2 / float64(w.n-1)
+}
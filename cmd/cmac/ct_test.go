@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestWelford(t *testing.T) {
+	var w welford
+	for _, x := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+		w.add(x)
+	}
+	if w.mean != 5 || math.Abs(w.variance()-32.0/7) > 1e-12 {
+		t.Errorf("got mean %v and variance %v, expected 5 and %v", w.mean, w.variance(), 32.0/7)
+	}
+}
+
+func TestCT(t *testing.T) {
+	var buf bytes.Buffer
+	err := run([]string{"ct", "-json", "-n", "2000"}, &buf)
+	if err != nil && !errors.Is(err, errLeak) {
+		t.Fatal("unexpected error: ", err)
+	}
+	var res []CTResult
+	if err := json.Unmarshal(buf.Bytes(), &res); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if len(res) != 3 {
+		t.Fatalf("got %d results, expected 3", len(res))
+	}
+	for _, r := range res {
+		if r.N < 1000 || r.N > 2000 || r.Leak != (math.Abs(r.T) > ctThreshold) {
+			t.Errorf("%s: got invalid result %+v", r.Target, r)
+		}
+	}
+	for _, args := range [][]string{{"ct", "-n", "1"}, {"ct", "-crop", "0"}, {"ct", "-batch", "0"}} {
+		if err := run(args, &buf); err == nil {
+			t.Errorf("%q: unexpected nil error", args)
+		}
+	}
+}
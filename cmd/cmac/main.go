@@ -0,0 +1,132 @@
+// Command cmac provides tools around the cmac package.
+//
+// Usage:
+//
+//	cmac bench [-json] [-time d] [-keysize n]
+//	cmac ct [-json] [-n measures] [-batch calls] [-crop percentile]
+//
+// The bench command measures the AES-CMAC throughput for message sizes from
+// 64 bytes to 1 MiB, and prints the time per MAC and the throughput in MB/s.
+// With -json, it prints a JSON object that also identifies the platform, to
+// compare devices of a fleet.
+//
+// The ct command tests statistically whether the timing of Equal, of a tag
+// verification and of the MAC finalization depends on their input. It exits
+// with a non-zero status when a leakage is detected, so that it can be run
+// regularly.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chmike/cmac-go"
+)
+
+// benchSizes are the message sizes of the bench command.
+var benchSizes = []int{64, 256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20}
+
+// BenchResult is the measure for one message size.
+type BenchResult struct {
+	Size    int     `json:"size"`
+	NsPerOp float64 `json:"ns_per_op"`
+	MBPerS  float64 `json:"mb_per_s"`
+}
+
+// BenchReport is the JSON output of the bench command.
+type BenchReport struct {
+	GoVersion string        `json:"go_version"`
+	GOOS      string        `json:"goos"`
+	GOARCH    string        `json:"goarch"`
+	NumCPU    int           `json:"num_cpu"`
+	KeySize   int           `json:"key_size"`
+	Results   []BenchResult `json:"results"`
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "cmac:", err)
+		os.Exit(2)
+	}
+}
+
+func run(args []string, w io.Writer) error {
+	if len(args) > 0 && args[0] == "ct" {
+		return runCT(args[1:], w)
+	}
+	if len(args) == 0 || args[0] != "bench" {
+		return errors.New("usage: cmac bench|ct [flags]")
+	}
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the results in JSON")
+	d := fs.Duration("time", time.Second, "minimum measure time per message size")
+	keySize := fs.Int("keysize", 16, "AES key size in bytes (16, 24 or 32)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	rep, err := bench(*keySize, *d)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rep)
+	}
+	fmt.Fprintf(w, "AES-%d-CMAC, %s %s/%s\n", 8*rep.KeySize, rep.GoVersion, rep.GOOS, rep.GOARCH)
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "size\tns/op\tMB/s\t")
+	for _, r := range rep.Results {
+		fmt.Fprintf(tw, "%d\t%.0f\t%.2f\t\n", r.Size, r.NsPerOp, r.MBPerS)
+	}
+	return tw.Flush()
+}
+
+// bench measures the MAC computation time with a key of keySize bytes for
+// each of benchSizes, during at least d each.
+func bench(keySize int, d time.Duration) (*BenchReport, error) {
+	h, err := cmac.NewAES(make([]byte, keySize))
+	if err != nil {
+		return nil, err
+	}
+	rep := &BenchReport{
+		GoVersion: runtime.Version(),
+		GOOS:      runtime.GOOS,
+		GOARCH:    runtime.GOARCH,
+		NumCPU:    runtime.NumCPU(),
+		KeySize:   keySize,
+	}
+	tag := make([]byte, 0, h.Size())
+	for _, size := range benchSizes {
+		msg := make([]byte, size)
+		var elapsed time.Duration
+		n := 1
+		for {
+			start := time.Now()
+			for i := 0; i < n; i++ {
+				h.Reset()
+				h.Write(msg)
+				tag = h.Sum(tag[:0])
+			}
+			elapsed = time.Since(start)
+			if elapsed >= d || n >= 1<<30 {
+				break
+			}
+			n *= 2
+		}
+		ns := float64(elapsed.Nanoseconds()) / float64(n)
+		rep.Results = append(rep.Results, BenchResult{
+			Size:    size,
+			NsPerOp: ns,
+			MBPerS:  float64(size) * 1e3 / ns,
+		})
+	}
+	return rep, nil
+}
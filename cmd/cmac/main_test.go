@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBench(t *testing.T) {
+	var buf bytes.Buffer
+	if err := run([]string{"bench", "-json", "-time", "1ms", "-keysize", "32"}, &buf); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	var rep BenchReport
+	if err := json.Unmarshal(buf.Bytes(), &rep); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if rep.KeySize != 32 || len(rep.Results) != len(benchSizes) {
+		t.Fatalf("got key size %d and %d results, expected 32 and %d", rep.KeySize, len(rep.Results), len(benchSizes))
+	}
+	for i, r := range rep.Results {
+		if r.Size != benchSizes[i] || r.NsPerOp <= 0 || r.MBPerS <= 0 {
+			t.Errorf("%d: got invalid result %+v", i, r)
+		}
+	}
+
+	buf.Reset()
+	if err := run([]string{"bench", "-time", "1ms"}, &buf); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if !strings.HasPrefix(buf.String(), "AES-128-CMAC") || strings.Count(buf.String(), "\n") != 2+len(benchSizes) {
+		t.Errorf("got unexpected output %q", buf.String())
+	}
+
+	for _, args := range [][]string{nil, {"sum"}, {"bench", "-keysize", "7"}} {
+		if err := run(args, &buf); err == nil {
+			t.Errorf("%q: unexpected nil error", args)
+		}
+	}
+}
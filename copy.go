@@ -0,0 +1,53 @@
+package cmac
+
+import (
+	"context"
+	"hash"
+	"io"
+)
+
+const copyBufferSize = 32 * 1024
+
+// CopyAndSum copies src to dst until EOF on src or an error, and computes the
+// CMAC of the data copied with h, which is reset. The context is checked
+// between buffers. The tag is only returned when the copy succeeded, in which
+// case err is nil. written is the number of bytes written to dst. The
+// WithProgress option reports the bytes written to dst.
+func CopyAndSum(ctx context.Context, dst io.Writer, src io.Reader, h hash.Hash, opts ...FileOption) (written int64, tag []byte, err error) {
+	p := newFileOptions(opts).progress
+	h.Reset()
+	buf := make([]byte, copyBufferSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return written, nil, err
+		}
+		nr, er := src.Read(buf)
+		if nr > 0 {
+			nw, ew := dst.Write(buf[:nr])
+			if nw < 0 || nw > nr {
+				nw = 0
+				if ew == nil {
+					ew = io.ErrShortWrite
+				}
+			}
+			written += int64(nw)
+			if _, err := h.Write(buf[:nw]); err != nil {
+				return written, nil, err
+			}
+			p.add(nw)
+			if ew == nil && nw != nr {
+				ew = io.ErrShortWrite
+			}
+			if ew != nil {
+				return written, nil, wrapError(ErrIO, ew)
+			}
+		}
+		if er == io.EOF {
+			p.done()
+			return written, h.Sum(nil), nil
+		}
+		if er != nil {
+			return written, nil, wrapError(ErrIO, er)
+		}
+	}
+}
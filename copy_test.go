@@ -0,0 +1,80 @@
+package cmac
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// cancelingReader cancels the context after the first read.
+type cancelingReader struct {
+	r      io.Reader
+	cancel func()
+}
+
+func (c *cancelingReader) Read(p []byte) (int, error) {
+	defer c.cancel()
+	return c.r.Read(p)
+}
+
+type shortWriter struct{}
+
+func (shortWriter) Write(p []byte) (int, error) { return len(p) / 2, nil }
+
+func TestCopyAndSum(t *testing.T) {
+	h, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+	h.Write(data)
+	exp := h.Sum(nil)
+
+	var buf bytes.Buffer
+	n, tag, err := CopyAndSum(context.Background(), &buf, bytes.NewReader(data), h)
+	if err != nil || n != int64(len(data)) || !bytes.Equal(tag, exp) || !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("got %d, %x, %v", n, tag, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n, tag, err = CopyAndSum(ctx, io.Discard, &cancelingReader{r: bytes.NewReader(data), cancel: cancel}, h)
+	if err != context.Canceled || tag != nil || n != copyBufferSize {
+		t.Errorf("got %d, %x, %v", n, tag, err)
+	}
+
+	n, tag, err = CopyAndSum(context.Background(), shortWriter{}, bytes.NewReader(data), h)
+	if !errors.Is(err, io.ErrShortWrite) || !errors.Is(err, ErrIO) || tag != nil || n != copyBufferSize/2 {
+		t.Errorf("got %d, %x, %v", n, tag, err)
+	}
+
+	limited, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	limited.(interface{ SetMaxLen(uint64) }).SetMaxLen(copyBufferSize)
+	n, tag, err = CopyAndSum(context.Background(), io.Discard, bytes.NewReader(data), limited)
+	if err != ErrTooLong || tag != nil || n != 2*copyBufferSize {
+		t.Errorf("got %d, %x, %v, expected %v", n, tag, err, ErrTooLong)
+	}
+
+	readErr := errors.New("read error")
+	_, tag, err = CopyAndSum(context.Background(), io.Discard, io.MultiReader(bytes.NewReader(data), &errReader{readErr}), h)
+	if !errors.Is(err, readErr) || !errors.Is(err, ErrIO) || tag != nil {
+		t.Errorf("got %x, %v", tag, err)
+	}
+}
+
+type errReader struct{ err error }
+
+func (e *errReader) Read(p []byte) (int, error) { return 0, e.err }
+
+func TestCopyAndSumProgress(t *testing.T) {
+	h, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	var got []int64
+	src := io.LimitReader(bytes.NewReader(make([]byte, 100000)), 100000)
+	n, _, err := CopyAndSum(context.Background(), io.Discard, src, h,
+		WithProgress(10000, func(n int64) { got = append(got, n) }))
+	if err != nil || n != 100000 {
+		t.Fatalf("got %d, %v", n, err)
+	}
+	if len(got) < 2 || got[len(got)-1] != 100000 {
+		t.Errorf("got progress %v, expected several calls ending with 100000", got)
+	}
+}
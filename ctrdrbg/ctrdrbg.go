@@ -0,0 +1,254 @@
+/*
+Package ctrdrbg implements the CTR_DRBG deterministic random bit generator
+of NIST special publication 800-90A rev1 with AES-128 and AES-256, with or
+without derivation function.
+
+The generator is deterministic: its output only depends on the entropy,
+nonce, personalization string and additional inputs provided by the caller.
+The entropy must come from an approved entropy source.
+*/
+package ctrdrbg
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	blockSize = aes.BlockSize
+
+	// MaxRequestSize is the maximum number of bytes returned by a Generate call.
+	MaxRequestSize = 1 << 16
+
+	// ReseedInterval is the maximum number of Generate calls between reseeds.
+	ReseedInterval = 1 << 48
+)
+
+var (
+	// ErrReseedRequired is returned by Generate and Read when the reseed
+	// interval is reached.
+	ErrReseedRequired = errors.New("ctrdrbg: reseed required")
+
+	// ErrInvalidInput is returned when an input has an invalid length.
+	ErrInvalidInput = errors.New("ctrdrbg: invalid input length")
+)
+
+// DRBG is a CTR_DRBG instance. It is not safe for concurrent use.
+type DRBG struct {
+	block         cipher.Block
+	keySize       int
+	df            bool
+	v             [blockSize]byte
+	reseedCounter uint64
+}
+
+// New returns a CTR_DRBG with the derivation function, instantiated with the
+// entropy, nonce and personalization string. keySize is 16 for AES-128 or
+// 32 for AES-256. The entropy must be at least keySize bytes long and the
+// nonce at least keySize/2 bytes long.
+func New(keySize int, entropy, nonce, personalization []byte) (*DRBG, error) {
+	d, err := newDRBG(keySize, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(entropy) < keySize || len(nonce) < keySize/2 {
+		return nil, ErrInvalidInput
+	}
+	seed := make([]byte, 0, len(entropy)+len(nonce)+len(personalization))
+	seed = append(seed, entropy...)
+	seed = append(seed, nonce...)
+	seed = append(seed, personalization...)
+	d.update(d.derive(seed))
+	d.reseedCounter = 1
+	return d, nil
+}
+
+// NewNoDF returns a CTR_DRBG without derivation function, instantiated with
+// the entropy and personalization string. keySize is 16 for AES-128 or 32
+// for AES-256. The entropy must be exactly keySize+16 bytes long and the
+// personalization string may not be longer.
+func NewNoDF(keySize int, entropy, personalization []byte) (*DRBG, error) {
+	d, err := newDRBG(keySize, false)
+	if err != nil {
+		return nil, err
+	}
+	seed, err := d.seedMaterial(entropy, personalization)
+	if err != nil {
+		return nil, err
+	}
+	d.update(seed)
+	d.reseedCounter = 1
+	return d, nil
+}
+
+// newDRBG returns an uninstantiated DRBG with a zero key and V.
+func newDRBG(keySize int, df bool) (*DRBG, error) {
+	if keySize != 16 && keySize != 32 {
+		return nil, errors.New("ctrdrbg: invalid key size")
+	}
+	block, _ := aes.NewCipher(make([]byte, keySize))
+	return &DRBG{block: block, keySize: keySize, df: df}, nil
+}
+
+// Reseed reseeds the DRBG with the entropy and optional additional input,
+// which have the same length constraints as the instantiation entropy and
+// personalization string.
+func (d *DRBG) Reseed(entropy, additional []byte) error {
+	var seed []byte
+	if d.df {
+		if len(entropy) < d.keySize {
+			return ErrInvalidInput
+		}
+		seed = d.derive(append(append([]byte(nil), entropy...), additional...))
+	} else {
+		var err error
+		if seed, err = d.seedMaterial(entropy, additional); err != nil {
+			return err
+		}
+	}
+	d.update(seed)
+	d.reseedCounter = 1
+	return nil
+}
+
+// Generate fills out with random bytes. The optional additional input may
+// not be longer than keySize+16 bytes without derivation function. out may
+// not be longer than MaxRequestSize.
+func (d *DRBG) Generate(out, additional []byte) error {
+	if len(out) > MaxRequestSize {
+		return ErrInvalidInput
+	}
+	if d.reseedCounter > ReseedInterval {
+		return ErrReseedRequired
+	}
+	seedLen := d.keySize + blockSize
+	var add []byte
+	if len(additional) > 0 {
+		if d.df {
+			add = d.derive(additional)
+		} else {
+			if len(additional) > seedLen {
+				return ErrInvalidInput
+			}
+			add = make([]byte, seedLen)
+			copy(add, additional)
+		}
+		d.update(add)
+	}
+	var tmp [blockSize]byte
+	for n := 0; n < len(out); n += blockSize {
+		d.incV()
+		d.block.Encrypt(tmp[:], d.v[:])
+		copy(out[n:], tmp[:])
+	}
+	if add == nil {
+		add = make([]byte, seedLen)
+	}
+	d.update(add)
+	d.reseedCounter++
+	return nil
+}
+
+// Read fills p with random bytes, generating at most MaxRequestSize bytes
+// per Generate call. It implements io.Reader.
+func (d *DRBG) Read(p []byte) (n int, err error) {
+	for n < len(p) {
+		m := len(p) - n
+		if m > MaxRequestSize {
+			m = MaxRequestSize
+		}
+		if err = d.Generate(p[n:n+m], nil); err != nil {
+			return
+		}
+		n += m
+	}
+	return
+}
+
+// seedMaterial returns the entropy xored with the zero padded input, as
+// specified without derivation function.
+func (d *DRBG) seedMaterial(entropy, input []byte) ([]byte, error) {
+	seedLen := d.keySize + blockSize
+	if len(entropy) != seedLen || len(input) > seedLen {
+		return nil, ErrInvalidInput
+	}
+	seed := append([]byte(nil), entropy...)
+	for i, v := range input {
+		seed[i] ^= v
+	}
+	return seed, nil
+}
+
+// incV increments V as a 128 bit big endian integer.
+func (d *DRBG) incV() {
+	for i := blockSize - 1; i >= 0; i-- {
+		d.v[i]++
+		if d.v[i] != 0 {
+			return
+		}
+	}
+}
+
+// update implements CTR_DRBG_Update with provided data of seed length.
+func (d *DRBG) update(data []byte) {
+	tmp := make([]byte, len(data))
+	for n := 0; n < len(tmp); n += blockSize {
+		d.incV()
+		d.block.Encrypt(tmp[n:], d.v[:])
+	}
+	for i, v := range data {
+		tmp[i] ^= v
+	}
+	d.block, _ = aes.NewCipher(tmp[:d.keySize])
+	copy(d.v[:], tmp[d.keySize:])
+}
+
+// derive implements Block_Cipher_df returning seed length bytes.
+func (d *DRBG) derive(input []byte) []byte {
+	seedLen := d.keySize + blockSize
+	s := make([]byte, 8, 8+len(input)+1+blockSize)
+	binary.BigEndian.PutUint32(s, uint32(len(input)))
+	binary.BigEndian.PutUint32(s[4:], uint32(seedLen))
+	s = append(s, input...)
+	s = append(s, 0x80)
+	for len(s)%blockSize != 0 {
+		s = append(s, 0)
+	}
+
+	k := make([]byte, d.keySize)
+	for i := range k {
+		k[i] = byte(i)
+	}
+	block, _ := aes.NewCipher(k)
+	tmp := make([]byte, 0, seedLen)
+	var iv [blockSize]byte
+	for i := uint32(0); len(tmp) < seedLen; i++ {
+		binary.BigEndian.PutUint32(iv[:], i)
+		tmp = append(tmp, bcc(block, iv[:], s)...)
+	}
+
+	block, _ = aes.NewCipher(tmp[:d.keySize])
+	x := tmp[d.keySize:seedLen]
+	out := make([]byte, 0, seedLen+blockSize)
+	for len(out) < seedLen {
+		block.Encrypt(x, x)
+		out = append(out, x...)
+	}
+	return out[:seedLen]
+}
+
+// bcc returns the CBC-MAC of iv || data, whose length is a multiple of the
+// block size.
+func bcc(block cipher.Block, iv, data []byte) []byte {
+	c := make([]byte, blockSize)
+	block.Encrypt(c, iv)
+	for n := 0; n < len(data); n += blockSize {
+		for i := range c {
+			c[i] ^= data[n+i]
+		}
+		block.Encrypt(c, c)
+	}
+	return c
+}
@@ -0,0 +1,124 @@
+package ctrdrbg
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+)
+
+func decodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestNoDF(t *testing.T) {
+	// ACVP ctrDRBG-1.0 AES-256 without derivation function
+	entropy := decodeHex(t, "9FCBB4CCC0135C484BDED061DA9FD70748682FE84166B97FF53F9AA1909B2E95D3D529C0F453B3AC575D12AA441CC5CD")
+	perso := decodeHex(t, "2C9FED0B39556CDBE699EBCA2A0EC7EECB287E8744475050C572FA8AE9ED0A4A7D6F1CABF1C4278532FB20AF7D64BD32")
+	reseedEntropy := decodeHex(t, "913C0DA19B010EDDD55A7A4F3F713EEF5B1534D34360A7EC376AE71A6B340043CC7726F762CB853453F399B3A645062A")
+	reseedAdditional := decodeHex(t, "2D9D4EC141A22E6CD2F6EE4F6719CF6BDF95CFE50B8D5EA6C87D38B4B872706FFF80B0380BB90E9C42D11D6526E56C29")
+	additional1 := decodeHex(t, "A642F06D327828F3E84564A3E37D60C157073B95864CA07981B0189668A0D978CD5DC68F06801CEFF0DC839A312B028E")
+	additional2 := decodeHex(t, "9DB14BABFA9107C88BA92073C0B4A65E89147EA06D74B894142979482F452915B35B5636F9B8A951759735ADE7C8D5D1")
+	returnedBits := decodeHex(t, "F10C645683FF0131254052ED4C698122B46B563654C29D728AC191CA4AAEFE649EEFE4C6FC33B25BB739294DD5CF578099F856C98D98000CBF971F1E6EA900822FF8C110118F6520471744D3F8A3F5C7D568494240E57F5488AF9C9F9F4E7322F56CCD843C0DBFCE9170C02E205389420527F23EDB3369D9FCC5E34901B5BA4EB71B973FC7982FFE0899FF7FE53EE0C4F51A3EF93EF9C6D4D279DD7536F8776BE94AAA05E89EF6E6AEE8832B4B42FFCA5FB91EC0273F9EF945865512889B0C5EE141D1B38DF827D2A694835561628C6F9B093A01A835F07ADBB9E03FEBF93389E8F3B86E1E0ABF1F9958FA286AD995289C2F606D1A9043A166C1AFE8D00769C712650819C9068A4BD22717C98338395A7BA6E95B5178BFBF4EFB0F05A91713BA8BF2127A6BA1EDFA6D1CAB05C03EE0D2AFE1DA4EB8F2C579EC872FF4B602027EF4BDCF2F4B01423F8E600A13D7CACB6AB83263BA58F907694AF614A6724FD0E4C627A0D91DDC6716C697FACE6F4808A4F37B731DE4E0CD4766CEADAAAF47992505299C72AC1A6E9A8335B8D7E501B3841188D0DA4DE5267674444DC2B0CF9F010756FA865A25CA3F1B24C34E845B2259926B6A867A7684DE68A6137C4FB0F47A2E54AE9E6455BEBA0B0A9629644FE9E378EE95386443BA977124FFD1192E9F460684C7B09FA99F5F93F04F56FD7955E042187887CE696F1934017E458B16B5C9")
+
+	d, err := NewNoDF(32, entropy, perso)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if err := d.Reseed(reseedEntropy, reseedAdditional); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	out := make([]byte, len(returnedBits))
+	if err := d.Generate(out, additional1); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if err := d.Generate(out, additional2); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if !bytes.Equal(out, returnedBits) {
+		t.Errorf("got %x, expected %x", out, returnedBits)
+	}
+
+	if _, err := NewNoDF(32, entropy[:47], nil); err != ErrInvalidInput {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidInput)
+	}
+	if _, err := NewNoDF(32, entropy, make([]byte, 49)); err != ErrInvalidInput {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidInput)
+	}
+	if err := d.Generate(out[:16], make([]byte, 49)); err != ErrInvalidInput {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidInput)
+	}
+	if _, err := NewNoDF(24, entropy[:40], nil); err == nil {
+		t.Errorf("unexpected nil error for invalid key size")
+	}
+}
+
+func TestDF(t *testing.T) {
+	entropy := bytes.Repeat([]byte{1}, 32)
+	nonce := bytes.Repeat([]byte{2}, 16)
+	for _, keySize := range []int{16, 32} {
+		d1, err := New(keySize, entropy[:keySize], nonce[:keySize/2], []byte("perso"))
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		d2, _ := New(keySize, entropy[:keySize], nonce[:keySize/2], []byte("perso"))
+		d3, _ := New(keySize, entropy[:keySize], nonce[:keySize/2], []byte("other"))
+		b1, b2, b3 := make([]byte, 100), make([]byte, 100), make([]byte, 100)
+		d1.Generate(b1, []byte("additional input longer than the seed length of the DRBG"))
+		d2.Generate(b2, []byte("additional input longer than the seed length of the DRBG"))
+		d3.Generate(b3, []byte("additional input longer than the seed length of the DRBG"))
+		if !bytes.Equal(b1, b2) {
+			t.Errorf("%d: output is not deterministic", keySize)
+		}
+		if bytes.Equal(b1, b3) {
+			t.Errorf("%d: personalization string is ignored", keySize)
+		}
+		d1.Reseed(entropy, nil)
+		d1.Generate(b1, nil)
+		d2.Generate(b2, nil)
+		if bytes.Equal(b1, b2) {
+			t.Errorf("%d: reseed is ignored", keySize)
+		}
+		if _, err := New(keySize, entropy[:keySize-1], nonce, nil); err != ErrInvalidInput {
+			t.Errorf("%d: got error %v, expected %v", keySize, err, ErrInvalidInput)
+		}
+		if err := d1.Reseed(entropy[:keySize-1], nil); err != ErrInvalidInput {
+			t.Errorf("%d: got error %v, expected %v", keySize, err, ErrInvalidInput)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	entropy := bytes.Repeat([]byte{1}, 32)
+	d1, _ := New(16, entropy, entropy, nil)
+	d2, _ := New(16, entropy, entropy, nil)
+	b := make([]byte, MaxRequestSize+10)
+	if _, err := io.ReadFull(d1, b); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	exp := make([]byte, MaxRequestSize)
+	d2.Generate(exp, nil)
+	if !bytes.Equal(b[:MaxRequestSize], exp) {
+		t.Errorf("Read output differs from Generate")
+	}
+	d2.Generate(exp[:10], nil)
+	if !bytes.Equal(b[MaxRequestSize:], exp[:10]) {
+		t.Errorf("Read output differs from Generate")
+	}
+	if err := d1.Generate(b, nil); err != ErrInvalidInput {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidInput)
+	}
+
+	d1.reseedCounter = ReseedInterval + 1
+	if _, err := d1.Read(b[:1]); err != ErrReseedRequired {
+		t.Errorf("got error %v, expected %v", err, ErrReseedRequired)
+	}
+	d1.Reseed(entropy, nil)
+	if _, err := d1.Read(b[:1]); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
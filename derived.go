@@ -0,0 +1,84 @@
+package cmac
+
+import (
+	"crypto/rand"
+	"io"
+)
+
+/* A DerivedMAC computes the CMAC of each message with a key derived from the
+master key and a nonce:
+
+   K   = DeriveKey(master, "cmac-go derived mac", nonce, len(master))
+   tag = CMAC(K, message)
+
+The output of Sign is nonce || tag, with a random nonce of NonceSize bytes.
+*/
+
+// NonceSize is the byte size of the random nonces generated by DerivedMAC.Sign.
+const NonceSize = 16
+
+const derivedMACLabel = "cmac-go derived mac"
+
+// DerivedMAC computes MACs with a key derived per message from a master key
+// and a nonce. It extends the number of messages that may be authenticated
+// with the master key. The nonce must be unique for each message.
+type DerivedMAC struct {
+	newCipher NewCipherFunc
+	master    []byte
+}
+
+// NewDerivedMAC returns a DerivedMAC with the given master key.
+func NewDerivedMAC(newCipher NewCipherFunc, master []byte) (*DerivedMAC, error) {
+	if _, err := newCipher(master); err != nil {
+		return nil, wrapError(ErrInvalidKey, err)
+	}
+	return &DerivedMAC{newCipher: newCipher, master: append([]byte(nil), master...)}, nil
+}
+
+// SumNonce returns the CMAC of msg with the key derived from the nonce.
+func (d *DerivedMAC) SumNonce(nonce, msg []byte) ([]byte, error) {
+	if len(nonce) == 0 {
+		return nil, newError(ErrInvalidArgument, "cmac: empty nonce")
+	}
+	k, err := DeriveKey(d.newCipher, d.master, []byte(derivedMACLabel), nonce, len(d.master))
+	if err != nil {
+		return nil, err
+	}
+	h, err := New(d.newCipher, k)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(msg)
+	return h.Sum(nil), nil
+}
+
+// Sign returns a random nonce of NonceSize bytes followed by the CMAC of msg
+// with the key derived from the nonce.
+func (d *DerivedMAC) Sign(msg []byte) ([]byte, error) {
+	nonce := make([]byte, NonceSize)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	tag, err := d.SumNonce(nonce, msg)
+	if err != nil {
+		return nil, err
+	}
+	return append(nonce, tag...), nil
+}
+
+// Verify returns nil if sig is a signature of msg returned by Sign, and
+// ErrMismatch otherwise.
+func (d *DerivedMAC) Verify(msg, sig []byte) error {
+	if len(sig) <= NonceSize {
+		return ErrMismatch
+	}
+	tag, err := d.SumNonce(sig[:NonceSize], msg)
+	if err != nil {
+		return err
+	}
+	if !Equal(tag, sig[NonceSize:]) {
+		audit("DerivedMAC.Verify", "", int64(len(msg)))
+		return ErrMismatch
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestDerivedMAC(t *testing.T) {
+	master, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	msg := []byte("message")
+	d, err := NewDerivedMAC(aes.NewCipher, master)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	nonce := []byte("nonce")
+	k, _ := DeriveKey(aes.NewCipher, master, []byte("cmac-go derived mac"), nonce, 16)
+	h, _ := New(aes.NewCipher, k)
+	h.Write(msg)
+	exp := h.Sum(nil)
+	tag, err := d.SumNonce(nonce, msg)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if !bytes.Equal(tag, exp) {
+		t.Errorf("got %x, expected %x", tag, exp)
+	}
+	if _, err := d.SumNonce(nil, msg); err == nil {
+		t.Errorf("unexpected nil error for empty nonce")
+	}
+
+	sig, err := d.Sign(msg)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if len(sig) != NonceSize+16 {
+		t.Errorf("got signature length %d, expected %d", len(sig), NonceSize+16)
+	}
+	if err := d.Verify(msg, sig); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	sig2, _ := d.Sign(msg)
+	if bytes.Equal(sig, sig2) {
+		t.Errorf("signatures must use distinct nonces")
+	}
+	sig[0] ^= 1
+	if err := d.Verify(msg, sig); err != ErrMismatch {
+		t.Errorf("got error %v, expected %v", err, ErrMismatch)
+	}
+	if err := d.Verify(msg, sig[:NonceSize]); err != ErrMismatch {
+		t.Errorf("got error %v, expected %v", err, ErrMismatch)
+	}
+
+	if _, err := NewDerivedMAC(aes.NewCipher, master[:3]); err == nil {
+		t.Errorf("unexpected nil error for invalid key")
+	}
+}
@@ -0,0 +1,135 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"runtime"
+	"sync"
+)
+
+/* The AN10922 diversification of the AES-128 master key K with the
+diversification input M of 1 to 31 bytes is
+
+   D = 0x01 || M, padded with 0x80 0x00 ... to 32 bytes if shorter
+   diversified key = CMAC(K, D)
+
+where the last block of D is xored with K2 instead of K1 when D is padded,
+as specified by NXP AN10922 section 2.2. The padding to two blocks makes it
+differ from the standard CMAC of 0x01 || M.
+*/
+
+// Diversifier derives the diversified keys of a master key. It is safe for
+// concurrent use.
+type Diversifier struct {
+	base   *cmac
+	size   int
+	derive func(c *cmac, dst, input []byte) ([]byte, error)
+}
+
+// NewAN10922Diversifier returns the diversifier of the 16 byte AES-128
+// master key specified by NXP AN10922 for MIFARE cards. Its diversification
+// inputs are 1 to 31 bytes long, and its keys 16 bytes long.
+func NewAN10922Diversifier(master []byte) (*Diversifier, error) {
+	if len(master) != 16 {
+		return nil, newError(ErrInvalidKey, "cmac: invalid AN10922 master key size")
+	}
+	b, err := aes.NewCipher(master)
+	if err != nil {
+		return nil, wrapError(ErrInvalidKey, err)
+	}
+	c, err := newCMAC(b)
+	if err != nil {
+		return nil, err
+	}
+	return &Diversifier{base: c, size: 16, derive: an10922}, nil
+}
+
+// NewKDFDiversifier returns the diversifier deriving keys of length bytes
+// with DeriveKey from the key derivation key kdk and the label. The
+// diversification input is the DeriveKey context.
+func NewKDFDiversifier(newCipher NewCipherFunc, kdk, label []byte, length int) (*Diversifier, error) {
+	if length <= 0 || uint64(length) > 0x1fffffff {
+		return nil, newError(ErrInvalidArgument, "cmac: invalid derived key length")
+	}
+	b, err := newCipher(kdk)
+	if err != nil {
+		return nil, wrapError(ErrInvalidKey, err)
+	}
+	label = append([]byte(nil), label...)
+	derive := func(c *cmac, dst, input []byte) ([]byte, error) {
+		return deriveKey(c, dst, label, input, length), nil
+	}
+	c, err := newCMAC(b)
+	if err != nil {
+		return nil, err
+	}
+	return &Diversifier{base: c, size: length, derive: derive}, nil
+}
+
+// Key returns the key diversified with the input.
+func (d *Diversifier) Key(input []byte) ([]byte, error) {
+	return d.derive(d.base.clone(), make([]byte, 0, d.size+d.base.blockSize), input)
+}
+
+// Keys returns the keys diversified with the inputs, derived in parallel.
+// keys[i] is the key of inputs[i]. When derivations fail, the error of the
+// first failing input is returned.
+func (d *Diversifier) Keys(inputs [][]byte) (keys [][]byte, err error) {
+	keys = make([][]byte, len(inputs))
+	errs := make([]error, len(inputs))
+	buf := make([]byte, len(inputs)*d.size)
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(inputs) {
+		workers = len(inputs)
+	}
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := d.base.clone()
+			tmp := make([]byte, 0, d.size+d.base.blockSize)
+			for i := range next {
+				k, err := d.derive(c, tmp, inputs[i])
+				if err != nil {
+					errs[i] = err
+					continue
+				}
+				keys[i] = buf[i*d.size : (i+1)*d.size : (i+1)*d.size]
+				copy(keys[i], k)
+			}
+		}()
+	}
+	for i := range inputs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// an10922 appends the AN10922 AES-128 diversified key of the input to dst.
+func an10922(c *cmac, dst, input []byte) ([]byte, error) {
+	if len(input) == 0 || len(input) > 31 {
+		return nil, newError(ErrInvalidArgument, "cmac: invalid AN10922 diversification input size")
+	}
+	var d [32]byte
+	d[0] = 1
+	n := 1 + copy(d[1:], input)
+	k := c.k1
+	if n < len(d) {
+		d[n] = 0x80
+		k = c.k2
+	}
+	c.Reset()
+	c.Write(d[:])
+	copy(c.mac, k)
+	xor(c.mac, c.x)
+	c.cipher.Encrypt(c.mac, c.mac)
+	return append(dst, c.mac...), nil
+}
@@ -0,0 +1,80 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestAN10922(t *testing.T) {
+	// NXP AN10922 section 2.2.1 AES-128 example
+	master, _ := hex.DecodeString("00112233445566778899aabbccddeeff")
+	m, _ := hex.DecodeString("04782e21801d803042f54e585020416275")
+	d, err := NewAN10922Diversifier(master)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	k, err := d.Key(m)
+	if err != nil || hex.EncodeToString(k) != "a8dd63a3b89d54b37ca802473fda9175" {
+		t.Errorf("got %x, %v", k, err)
+	}
+
+	// an unpadded 31 byte input is the standard CMAC of 0x01 || M
+	m = bytes.Repeat([]byte{0x42}, 31)
+	h, _ := New(aes.NewCipher, master)
+	h.Write([]byte{1})
+	h.Write(m)
+	if k, err := d.Key(m); err != nil || !bytes.Equal(k, h.Sum(nil)) {
+		t.Errorf("got %x, %v, expected %x", k, err, h.Sum(nil))
+	}
+
+	for _, m := range [][]byte{nil, make([]byte, 32)} {
+		if _, err := d.Key(m); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%d: got error %v, expected %v", len(m), err, ErrInvalidArgument)
+		}
+	}
+	if _, err := NewAN10922Diversifier(make([]byte, 32)); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidKey)
+	}
+}
+
+func TestDiversifierKeys(t *testing.T) {
+	master := []byte("0123456789abcdef")
+	an, _ := NewAN10922Diversifier(master)
+	kdf, err := NewKDFDiversifier(aes.NewCipher, master, []byte("card"), 24)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	inputs := make([][]byte, 1000)
+	for i := range inputs {
+		inputs[i] = []byte{byte(i >> 8), byte(i), 'u', 'i', 'd'}
+	}
+	for _, d := range []*Diversifier{an, kdf} {
+		keys, err := d.Keys(inputs)
+		if err != nil || len(keys) != len(inputs) {
+			t.Fatalf("got %d keys, %v", len(keys), err)
+		}
+		for i, k := range keys {
+			if expected, _ := d.Key(inputs[i]); !bytes.Equal(k, expected) {
+				t.Fatalf("%d: got %x, expected %x", i, k, expected)
+			}
+		}
+	}
+	if k, _ := kdf.Key(inputs[3]); len(k) != 24 {
+		t.Errorf("got key length %d, expected 24", len(k))
+	}
+	expected, _ := DeriveKey(aes.NewCipher, master, []byte("card"), inputs[3], 24)
+	if k, _ := kdf.Key(inputs[3]); !bytes.Equal(k, expected) {
+		t.Errorf("got %x, expected %x", k, expected)
+	}
+
+	inputs[500] = nil
+	if _, err := an.Keys(inputs); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidArgument)
+	}
+	if _, err := NewKDFDiversifier(aes.NewCipher, master, nil, 0); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidArgument)
+	}
+}
@@ -0,0 +1,62 @@
+package cmac
+
+import "errors"
+
+/* An envelope is a self-describing binary encoding of a tag:
+
+   +---------+-----------+-----+--------+-----+-----+
+   | version | algorithm | len | key ID | len | tag |
+   +---------+-----------+-----+--------+-----+-----+
+
+version is the byte 1, algorithm is the Algorithm identifier byte, each len
+is the byte length of the following field. The length of the tag field is
+the truncation length of the tag.
+*/
+
+// EnvelopeVersion is the version of the envelope encoding built by BuildEnvelope.
+const EnvelopeVersion = 1
+
+// ErrInvalidEnvelope is returned when an envelope is malformed.
+var ErrInvalidEnvelope = errors.New("cmac: invalid envelope")
+
+// Envelope is a tag with its algorithm and the ID of the key used to compute it.
+type Envelope struct {
+	Algorithm Algorithm
+	KeyID     string
+	Tag       []byte
+}
+
+// BuildEnvelope returns the binary encoding of e. The key ID and tag may not
+// be longer than 255 bytes, and the tag may not be empty.
+func BuildEnvelope(e Envelope) ([]byte, error) {
+	if !e.Algorithm.Valid() || len(e.KeyID) > 255 || len(e.Tag) == 0 || len(e.Tag) > 255 {
+		return nil, ErrInvalidEnvelope
+	}
+	b := make([]byte, 0, 4+len(e.KeyID)+len(e.Tag))
+	b = append(b, EnvelopeVersion, byte(e.Algorithm), byte(len(e.KeyID)))
+	b = append(b, e.KeyID...)
+	b = append(b, byte(len(e.Tag)))
+	return append(b, e.Tag...), nil
+}
+
+// ParseEnvelope decodes the envelope b. The returned tag references b.
+func ParseEnvelope(b []byte) (Envelope, error) {
+	var e Envelope
+	if len(b) < 4 || b[0] != EnvelopeVersion {
+		return e, ErrInvalidEnvelope
+	}
+	e.Algorithm = Algorithm(b[1])
+	if !e.Algorithm.Valid() {
+		return e, ErrUnknownAlgorithm
+	}
+	n := 3 + int(b[2])
+	if len(b) < n+1 {
+		return e, ErrInvalidEnvelope
+	}
+	e.KeyID = string(b[3:n])
+	if len(b) != n+1+int(b[n]) || b[n] == 0 {
+		return e, ErrInvalidEnvelope
+	}
+	e.Tag = b[n+1:]
+	return e, nil
+}
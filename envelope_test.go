@@ -0,0 +1,54 @@
+package cmac
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestEnvelope(t *testing.T) {
+	tag, _ := hex.DecodeString("070a16b46b4d4144f79bdd9d")
+	b, err := BuildEnvelope(Envelope{Algorithm: AES128, KeyID: "k1", Tag: tag})
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if exp := "0101026b310c070a16b46b4d4144f79bdd9d"; hex.EncodeToString(b) != exp {
+		t.Errorf("got %x, expected %s", b, exp)
+	}
+	e, err := ParseEnvelope(b)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if e.Algorithm != AES128 || e.KeyID != "k1" || !bytes.Equal(e.Tag, tag) {
+		t.Errorf("envelope mismatch, got %+v", e)
+	}
+
+	for i, e := range []Envelope{
+		{Algorithm: 0, Tag: tag},
+		{Algorithm: AES128},
+		{Algorithm: AES128, KeyID: string(make([]byte, 256)), Tag: tag},
+		{Algorithm: AES128, Tag: make([]byte, 256)},
+	} {
+		if _, err := BuildEnvelope(e); err != ErrInvalidEnvelope {
+			t.Errorf("%2d: got error %v, expected %v", i, err, ErrInvalidEnvelope)
+		}
+	}
+
+	for i, s := range []string{
+		"",
+		"010100",
+		"020100010a",
+		"01010201",
+		"0101026b3100",
+		"0101026b31020a",
+		"0101026b31010a0b",
+	} {
+		b, _ := hex.DecodeString(s)
+		if _, err := ParseEnvelope(b); err != ErrInvalidEnvelope {
+			t.Errorf("%2d: got error %v, expected %v", i, err, ErrInvalidEnvelope)
+		}
+	}
+	if _, err := ParseEnvelope([]byte{1, 0xff, 0, 1, 0}); err != ErrUnknownAlgorithm {
+		t.Errorf("got error %v, expected %v", err, ErrUnknownAlgorithm)
+	}
+}
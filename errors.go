@@ -0,0 +1,51 @@
+package cmac
+
+import "errors"
+
+// Error categories. The errors returned by the package that are not one of
+// its other sentinel errors match one of these categories with errors.Is.
+var (
+	// ErrInvalidKey is the category of invalid keys.
+	ErrInvalidKey = errors.New("cmac: invalid key")
+
+	// ErrInvalidArgument is the category of invalid arguments and
+	// parameters other than keys.
+	ErrInvalidArgument = errors.New("cmac: invalid argument")
+
+	// ErrIO is the category of errors returned by the readers, writers and
+	// files used by the package.
+	ErrIO = errors.New("cmac: I/O error")
+)
+
+// Error is an error of a category with its cause. errors.Is matches the
+// category, and errors.Unwrap returns the cause.
+type Error struct {
+	Kind error // category error, e.g. ErrInvalidKey
+	Err  error // cause
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+
+// Unwrap returns the cause.
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is reports whether target is the category of e.
+func (e *Error) Is(target error) bool { return target == e.Kind }
+
+// newError returns an error of the category with the message.
+func newError(kind error, msg string) error {
+	return &Error{Kind: kind, Err: errors.New(msg)}
+}
+
+// wrapError returns err with the category, or err when it is nil or already
+// has a category.
+func wrapError(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	return &Error{Kind: kind, Err: err}
+}
@@ -0,0 +1,101 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestErrorCategories(t *testing.T) {
+	_, errNew := New(aes.NewCipher, []byte("short"))
+	_, errAlg := AES256.New(make([]byte, 16))
+	_, errSIV := NewSIV(make([]byte, 20))
+	_, errTDEA := NewTDEACipher(make([]byte, 8))
+	_, errKDF := DeriveKey(aes.NewCipher, make([]byte, 16), nil, nil, 0)
+	_, errPBKDF2 := PBKDF2(nil, nil, 0, 16)
+	_, errFile := SumFile(MustNew(aes.NewCipher, make([]byte, 16)), filepath.Join(t.TempDir(), "missing"))
+	_, errSidecar := NewSidecar(AES128, make([]byte, 16), "", io.MultiReader(strings.NewReader("x"), &errReader{io.ErrClosedPipe}), 0)
+
+	tests := []struct {
+		err, kind error
+	}{
+		{errNew, ErrInvalidKey},
+		{errAlg, ErrInvalidKey},
+		{errSIV, ErrInvalidKey},
+		{errTDEA, ErrInvalidKey},
+		{errKDF, ErrInvalidArgument},
+		{errPBKDF2, ErrInvalidArgument},
+		{errFile, ErrIO},
+		{errSidecar, ErrIO},
+	}
+	for i, test := range tests {
+		if !errors.Is(test.err, test.kind) {
+			t.Errorf("%d: got error %v, expected category %v", i, test.err, test.kind)
+		}
+		var e *Error
+		if !errors.As(test.err, &e) || e.Err == nil {
+			t.Errorf("%d: got error %v, expected an *Error with a cause", i, test.err)
+		}
+	}
+
+	// the causes remain accessible
+	var ks aes.KeySizeError
+	if !errors.As(errNew, &ks) || ks != 5 {
+		t.Errorf("got error %v, expected aes.KeySizeError(5)", errNew)
+	}
+	if !errors.Is(errFile, os.ErrNotExist) {
+		t.Errorf("got error %v, expected to match %v", errFile, os.ErrNotExist)
+	}
+	if !errors.Is(errSidecar, io.ErrClosedPipe) {
+		t.Errorf("got error %v, expected to match %v", errSidecar, io.ErrClosedPipe)
+	}
+	if errSIV != ErrInvalidSIVKey || errTDEA != ErrInvalidTDEAKey {
+		t.Errorf("sentinel errors aren't returned as is")
+	}
+	if errors.Is(errNew, ErrIO) {
+		t.Errorf("error %v matches an unrelated category", errNew)
+	}
+	if err := wrapError(ErrIO, errNew); err != errNew {
+		t.Errorf("got error %v, expected the category to be kept", err)
+	}
+}
+
+func TestErrorSentinelCategories(t *testing.T) {
+	tests := []struct {
+		err, kind error
+	}{
+		{ErrUnknownAlgorithm, ErrInvalidArgument},
+		{ErrOpen, ErrInvalidArgument},
+		{ErrInvalidPEM, ErrInvalidArgument},
+		{ErrInvalidCheckpoint, ErrInvalidArgument},
+		{ErrLockedOut, ErrInvalidArgument},
+		{ErrInvalidSignedURL, ErrInvalidArgument},
+		{ErrExpiredURL, ErrInvalidArgument},
+		{ErrTooLong, ErrInvalidArgument},
+		{ErrInvalidSIVKey, ErrInvalidKey},
+		{ErrInvalidTDEAKey, ErrInvalidKey},
+	}
+	for i, test := range tests {
+		if !errors.Is(test.err, test.kind) {
+			t.Errorf("%d: got error %v, expected category %v", i, test.err, test.kind)
+		}
+	}
+
+	h := MustNew(aes.NewCipher, make([]byte, 16))
+	_, errPart := SumPart(h, MultipartState{}, 1, &errReader{io.ErrClosedPipe})
+	if !errors.Is(errPart, ErrIO) || !errors.Is(errPart, io.ErrClosedPipe) {
+		t.Errorf("got error %v, expected category %v and cause %v", errPart, ErrIO, io.ErrClosedPipe)
+	}
+
+	defer func(r io.Reader) { rand.Reader = r }(rand.Reader)
+	rand.Reader = &errReader{io.ErrUnexpectedEOF}
+	_, errSeal := Seal(make([]byte, 16), []byte("plaintext"), nil)
+	if !errors.Is(errSeal, ErrIO) || !errors.Is(errSeal, io.ErrUnexpectedEOF) {
+		t.Errorf("got error %v, expected category %v and cause %v", errSeal, ErrIO, io.ErrUnexpectedEOF)
+	}
+}
@@ -0,0 +1,60 @@
+package cmac
+
+import (
+	"encoding/binary"
+)
+
+/* ExpandLabel mirrors HKDF-Expand-Label of TLS 1.3 (RFC 8446 section 7.1)
+with CMAC in place of HMAC. The info is the encoding of the structure
+
+   struct {
+       uint16 length = Length;
+       opaque label<7..255> = "cmac " + Label;
+       opaque context<0..255> = Context;
+   } CmacLabel;
+
+and the output is the first Length bytes of T(1) || T(2) || ... where
+
+   T(0) = empty string
+   T(i) = CMAC(Secret, T(i-1) || info || byte(i))
+
+as in HKDF-Expand of RFC 5869.
+*/
+
+const expandLabelPrefix = "cmac "
+
+// ExpandLabel derives length bytes from the secret, which must be a valid
+// key of newCipher, with the label and context. The label may not be longer
+// than 250 bytes, the context longer than 255 bytes, and length larger than
+// 255 times the cipher block size.
+func ExpandLabel(newCipher NewCipherFunc, secret []byte, label string, context []byte, length int) ([]byte, error) {
+	if len(label) > 255-len(expandLabelPrefix) || len(context) > 255 {
+		return nil, newError(ErrInvalidArgument, "cmac: label or context too long")
+	}
+	h, err := New(newCipher, secret)
+	if err != nil {
+		return nil, err
+	}
+	if length <= 0 || length > 255*h.Size() {
+		return nil, newError(ErrInvalidArgument, "cmac: invalid derived key length")
+	}
+	info := make([]byte, 2, 4+len(expandLabelPrefix)+len(label)+len(context))
+	binary.BigEndian.PutUint16(info, uint16(length))
+	info = append(info, byte(len(expandLabelPrefix)+len(label)))
+	info = append(info, expandLabelPrefix...)
+	info = append(info, label...)
+	info = append(info, byte(len(context)))
+	info = append(info, context...)
+
+	out := make([]byte, 0, length+h.Size())
+	var prev []byte
+	for i := 1; len(out) < length; i++ {
+		h.Reset()
+		h.Write(prev)
+		h.Write(info)
+		h.Write([]byte{byte(i)})
+		out = h.Sum(out)
+		prev = out[len(out)-h.Size():]
+	}
+	return out[:length], nil
+}
@@ -0,0 +1,56 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestExpandLabel(t *testing.T) {
+	secret, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	info, _ := hex.DecodeString("0018" + "08" + "636d6163206b6579" + "03" + "637478")
+
+	h, _ := New(aes.NewCipher, secret)
+	h.Write(info)
+	h.Write([]byte{1})
+	t1 := h.Sum(nil)
+	h.Reset()
+	h.Write(t1)
+	h.Write(info)
+	h.Write([]byte{2})
+	exp := h.Sum(append([]byte(nil), t1...))[:24]
+
+	k, err := ExpandLabel(aes.NewCipher, secret, "key", []byte("ctx"), 24)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if !bytes.Equal(k, exp) {
+		t.Errorf("got %x, expected %x", k, exp)
+	}
+	k2, _ := ExpandLabel(aes.NewCipher, secret, "key", []byte("ctx"), 16)
+	if bytes.Equal(k2, k[:16]) {
+		t.Errorf("keys of different length must be independent")
+	}
+
+	for i, tc := range []struct {
+		secret  []byte
+		label   string
+		context []byte
+		length  int
+	}{
+		{secret[:3], "key", nil, 16},
+		{secret, strings.Repeat("x", 251), nil, 16},
+		{secret, "key", make([]byte, 256), 16},
+		{secret, "key", nil, 0},
+		{secret, "key", nil, 255*16 + 1},
+	} {
+		if _, err := ExpandLabel(aes.NewCipher, tc.secret, tc.label, tc.context, tc.length); err == nil {
+			t.Errorf("%d: unexpected nil error", i)
+		}
+	}
+	if _, err := ExpandLabel(aes.NewCipher, secret, strings.Repeat("x", 250), nil, 255*16); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package cmac
+
+import "sync"
+
+// Factory computes and verifies CMACs with a fixed key. Its methods are safe
+// for concurrent use, unlike a hash.
+type Factory struct {
+	base *cmac
+	pool sync.Pool
+}
+
+// NewFactory returns a factory of CMACs with the cipher and key.
+func NewFactory(newCipher NewCipherFunc, key []byte) (*Factory, error) {
+	h, err := New(newCipher, key)
+	if err != nil {
+		return nil, err
+	}
+	f := &Factory{base: h.(*cmac)}
+	f.pool.New = func() interface{} { return f.base.clone() }
+	return f, nil
+}
+
+// Size returns the size of the CMACs in bytes.
+func (f *Factory) Size() int { return f.base.blockSize }
+
+// Sum appends the CMAC of msg to dst and returns the resulting slice.
+func (f *Factory) Sum(dst, msg []byte) []byte {
+	c := f.pool.Get().(*cmac)
+	c.Reset()
+	c.Write(msg)
+	dst = c.Sum(dst)
+	f.pool.Put(c)
+	return dst
+}
+
+// Verify returns nil if tag is the CMAC of msg, and ErrMismatch otherwise.
+// The tag may be truncated to no less than 8 bytes.
+func (f *Factory) Verify(msg, tag []byte) error {
+	var buf [32]byte
+	mac := f.Sum(buf[:0], msg)
+	if len(tag) < minTagSize || len(tag) > len(mac) || !Equal(mac[:len(tag)], tag) {
+		audit("Factory.Verify", "", int64(len(msg)))
+		return ErrMismatch
+	}
+	return nil
+}
+
+// Iterator computes the CMACs of a sequence of messages with the key of a
+// factory, reusing its state and tag buffer. It must not be used
+// concurrently.
+type Iterator struct {
+	c *cmac
+}
+
+// Iterator returns a new iterator with the key of the factory.
+func (f *Factory) Iterator() *Iterator {
+	return &Iterator{c: f.base.clone()}
+}
+
+// Next returns the CMAC of msg. The returned slice is overwritten by the
+// next call.
+func (it *Iterator) Next(msg []byte) []byte {
+	c := it.c
+	bs := c.blockSize
+	for i := range c.x {
+		c.x[i] = 0
+	}
+	for len(msg) > bs {
+		xor(c.x, msg[:bs])
+		c.cipher.Encrypt(c.x, c.x)
+		msg = msg[bs:]
+	}
+	xor(c.x, msg)
+	if len(msg) == bs {
+		xor(c.x, c.k1)
+	} else {
+		c.x[len(msg)] ^= 0x80
+		xor(c.x, c.k2)
+	}
+	c.cipher.Encrypt(c.mac, c.x)
+	return c.mac
+}
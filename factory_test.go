@@ -0,0 +1,79 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestFactory(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	f, err := NewFactory(aes.NewCipher, key)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if f.Size() != 16 {
+		t.Errorf("got size %d, expected 16", f.Size())
+	}
+	if _, err := NewFactory(aes.NewCipher, key[:5]); err == nil {
+		t.Errorf("unexpected nil error for invalid key")
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				msg := []byte(strconv.Itoa(g*1000 + i))
+				tag := f.Sum([]byte("prefix"), msg)
+				h, _ := New(aes.NewCipher, key)
+				h.Write(msg)
+				if exp := h.Sum([]byte("prefix")); !bytes.Equal(tag, exp) {
+					t.Errorf("got %x, expected %x", tag, exp)
+					return
+				}
+				if err := f.Verify(msg, tag[6:]); err != nil {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	tag := f.Sum(nil, []byte("msg"))
+	tests := []struct {
+		msg, tag []byte
+		err      error
+	}{
+		{[]byte("msg"), tag[:8], nil},
+		{[]byte("msg"), tag[:7], ErrMismatch},
+		{[]byte("msh"), tag, ErrMismatch},
+		{[]byte("msg"), append(tag, 0), ErrMismatch},
+	}
+	for i, test := range tests {
+		if err := f.Verify(test.msg, test.tag); err != test.err {
+			t.Errorf("%d: got error %v, expected %v", i, err, test.err)
+		}
+	}
+}
+
+func TestIterator(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	f, _ := NewFactory(aes.NewCipher, key)
+	it := f.Iterator()
+	msg := make([]byte, 70)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	for n := 0; n <= len(msg); n++ {
+		h, _ := New(aes.NewCipher, key)
+		h.Write(msg[:n])
+		if tag, exp := it.Next(msg[:n]), h.Sum(nil); !bytes.Equal(tag, exp) {
+			t.Errorf("%d: got %x, expected %x", n, tag, exp)
+		}
+	}
+}
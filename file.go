@@ -0,0 +1,132 @@
+package cmac
+
+import (
+	"hash"
+	"io"
+	"os"
+)
+
+type fileOptions struct {
+	mmap     bool
+	progress *progress
+}
+
+// FileOption is an option of SumFile, CopyAndSum and the chunked SIV
+// streams. Options that don't apply to a function are ignored.
+type FileOption func(*fileOptions)
+
+func newFileOptions(opts []FileOption) fileOptions {
+	var o fileOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithProgress makes the operation call fn with the number of bytes
+// processed so far, each time at least interval more bytes were processed
+// and once when it ends successfully. The calls are made by the goroutine
+// doing the processing and should return quickly.
+func WithProgress(interval int64, fn func(n int64)) FileOption {
+	if interval <= 0 {
+		interval = 1
+	}
+	return func(o *fileOptions) { o.progress = &progress{fn: fn, interval: interval, next: interval} }
+}
+
+// progress reports the number of bytes processed. A nil progress does
+// nothing.
+type progress struct {
+	fn            func(n int64)
+	interval      int64
+	n, next, last int64
+}
+
+// add records n more bytes processed.
+func (p *progress) add(n int) {
+	if p == nil {
+		return
+	}
+	p.n += int64(n)
+	if p.n >= p.next {
+		p.fn(p.n)
+		p.last = p.n
+		p.next = p.n + p.interval
+	}
+}
+
+// done reports the final number of bytes processed, if not yet reported.
+func (p *progress) done() {
+	if p != nil && (p.last != p.n || p.n == 0) {
+		p.fn(p.n)
+		p.last = p.n
+	}
+}
+
+// progressWriter writes to w and reports the bytes written to p, in
+// slices of at most the progress interval.
+type progressWriter struct {
+	w io.Writer
+	p *progress
+}
+
+func (w progressWriter) Write(b []byte) (n int, err error) {
+	for len(b) > 0 {
+		m := len(b)
+		if int64(m) > w.p.interval {
+			m = int(w.p.interval)
+		}
+		m, err = w.w.Write(b[:m])
+		n += m
+		w.p.add(m)
+		if err != nil {
+			return n, err
+		}
+		b = b[m:]
+	}
+	return n, nil
+}
+
+// WithMmap makes SumFile map the file in memory, with a sequential access
+// advice, instead of reading it into a buffer. This reduces the system call
+// overhead with very large files. SumFile falls back to buffered reads when
+// memory mapping is not supported by the system or the file.
+func WithMmap() FileOption {
+	return func(o *fileOptions) { o.mmap = true }
+}
+
+// SumFile returns the CMAC computed with h of the content of the named file.
+// h is reset.
+func SumFile(h hash.Hash, name string, opts ...FileOption) ([]byte, error) {
+	o := newFileOptions(opts)
+	var w io.Writer = h
+	if o.progress != nil {
+		w = progressWriter{w: h, p: o.progress}
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, wrapError(ErrIO, err)
+	}
+	defer f.Close()
+	h.Reset()
+	mapped := false
+	if o.mmap {
+		if mapped, err = sumMmap(w, f); err != nil {
+			return nil, err
+		}
+	}
+	if !mapped {
+		h.Reset()
+		if o.progress != nil {
+			o.progress.n, o.progress.next = 0, o.progress.interval
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, wrapError(ErrIO, err)
+		}
+		if _, err := io.Copy(w, f); err != nil {
+			return nil, wrapError(ErrIO, err)
+		}
+	}
+	o.progress.done()
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,36 @@
+package cmac
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+// mmapWindow is the size of the file regions mapped at once.
+const mmapWindow = 1 << 28
+
+// sumMmap writes the content of f to h by mapping it in memory. It returns
+// false if the file could not be mapped, and the error of h.Write.
+func sumMmap(h io.Writer, f *os.File) (bool, error) {
+	fi, err := f.Stat()
+	if err != nil || !fi.Mode().IsRegular() {
+		return false, nil
+	}
+	for off := int64(0); off < fi.Size(); off += mmapWindow {
+		n := fi.Size() - off
+		if n > mmapWindow {
+			n = mmapWindow
+		}
+		b, err := syscall.Mmap(int(f.Fd()), off, int(n), syscall.PROT_READ, syscall.MAP_SHARED)
+		if err != nil {
+			return false, nil
+		}
+		syscall.Madvise(b, syscall.MADV_SEQUENTIAL)
+		_, err = h.Write(b)
+		syscall.Munmap(b)
+		if err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
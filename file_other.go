@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package cmac
+
+import (
+	"io"
+	"os"
+)
+
+// sumMmap returns false because memory mapping is not supported.
+func sumMmap(h io.Writer, f *os.File) (bool, error) {
+	return false, nil
+}
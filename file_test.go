@@ -0,0 +1,67 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSumFile(t *testing.T) {
+	h, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	dir := t.TempDir()
+	for i, size := range []int{0, 1, 16, 100000} {
+		data := bytes.Repeat([]byte{byte(i)}, size)
+		name := filepath.Join(dir, "file")
+		if err := os.WriteFile(name, data, 0600); err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		h.Reset()
+		h.Write(data)
+		exp := h.Sum(nil)
+		for _, opts := range [][]FileOption{nil, {WithMmap()}} {
+			h.Write([]byte("garbage"))
+			tag, err := SumFile(h, name, opts...)
+			if err != nil || !bytes.Equal(tag, exp) {
+				t.Errorf("%d: got %x, %v, expected %x", size, tag, err, exp)
+			}
+		}
+	}
+	if _, err := SumFile(h, filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("unexpected nil error for missing file")
+	}
+
+	// the maximum message length applies to both paths
+	limited, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	limited.(interface{ SetMaxLen(uint64) }).SetMaxLen(99999)
+	for _, opts := range [][]FileOption{nil, {WithMmap()}} {
+		if tag, err := SumFile(limited, filepath.Join(dir, "file"), opts...); err != ErrTooLong || tag != nil {
+			t.Errorf("%d options: got %x, %v, expected %v", len(opts), tag, err, ErrTooLong)
+		}
+	}
+}
+
+func TestSumFileProgress(t *testing.T) {
+	h, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	name := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(name, make([]byte, 100000), 0600); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	for _, opts := range [][]FileOption{nil, {WithMmap()}} {
+		var got []int64
+		opts = append(opts, WithProgress(30000, func(n int64) { got = append(got, n) }))
+		if _, err := SumFile(h, name, opts...); err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		if len(got) < 4 || got[len(got)-1] != 100000 {
+			t.Errorf("got progress %v, expected at least 4 calls ending with 100000", got)
+		}
+		for i := 1; i < len(got); i++ {
+			if got[i] <= got[i-1] {
+				t.Errorf("got progress %v, expected increasing values", got)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package cmac
+
+import "crypto/aes"
+
+/* A Fixture derives its values from the seed with the AES-CMAC-PRF-128 of
+RFC 4615 and the SP 800-108 counter mode KDF of DeriveKey:
+
+   KDK   = AES-CMAC-PRF-128(seed, "")
+   value = DeriveKey(AES-128, KDK, "cmac-go fixture " + kind, name, size)
+
+where kind is "key", "nonce" or "message". The values are reproducible in
+any language from the seed, kind, name and size.
+*/
+
+// Fixture expands a short seed into reproducible keys, nonces and messages
+// for deterministic test fixtures. It must not be used to generate secrets.
+type Fixture struct {
+	kdk []byte
+}
+
+// NewFixture returns a fixture expanding the seed, which may have any length.
+func NewFixture(seed []byte) *Fixture {
+	h, _ := newPRF128(seed)
+	return &Fixture{kdk: h.Sum(nil)}
+}
+
+// Key returns the key of size bytes with the given name.
+func (f *Fixture) Key(name string, size int) []byte {
+	return f.expand("key", name, size)
+}
+
+// Nonce returns the nonce of size bytes with the given name.
+func (f *Fixture) Nonce(name string, size int) []byte {
+	return f.expand("nonce", name, size)
+}
+
+// Message returns the message of size bytes with the given name.
+func (f *Fixture) Message(name string, size int) []byte {
+	return f.expand("message", name, size)
+}
+
+// expand returns the value of size bytes of the kind with the given name.
+// It panics if size is not positive.
+func (f *Fixture) expand(kind, name string, size int) []byte {
+	if size <= 0 {
+		panic("cmac: invalid fixture size")
+	}
+	b, err := DeriveKey(aes.NewCipher, f.kdk, []byte("cmac-go fixture "+kind), []byte(name), size)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,41 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"testing"
+)
+
+func TestFixture(t *testing.T) {
+	seed := []byte("test seed")
+	h, _ := newPRF128(seed)
+	kdk := h.Sum(nil)
+	exp, _ := DeriveKey(aes.NewCipher, kdk, []byte("cmac-go fixture key"), []byte("alice"), 32)
+
+	f := NewFixture(seed)
+	if k := f.Key("alice", 32); !bytes.Equal(k, exp) {
+		t.Errorf("got %x, expected %x", k, exp)
+	}
+	if k := NewFixture(seed).Key("alice", 32); !bytes.Equal(k, exp) {
+		t.Errorf("fixture is not reproducible")
+	}
+	if k := NewFixture([]byte("other seed")).Key("alice", 32); bytes.Equal(k, exp) {
+		t.Errorf("seed is ignored")
+	}
+	if n := f.Nonce("alice", 32); bytes.Equal(n, exp) {
+		t.Errorf("nonce and key of same name must differ")
+	}
+	if m := f.Message("alice", 32); bytes.Equal(m, exp) {
+		t.Errorf("message and key of same name must differ")
+	}
+	if m := f.Message("bob", 1000); len(m) != 1000 {
+		t.Errorf("got message length %d, expected 1000", len(m))
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic for zero size")
+		}
+	}()
+	f.Key("alice", 0)
+}
@@ -0,0 +1,179 @@
+package cmac
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash"
+)
+
+/* An authenticated frame is the payload followed by a trailer:
+
+   +---------+---------+-----+
+   | payload | counter | tag |
+   +---------+---------+-----+
+
+counter is the big endian uint64 sequence number of the frame, starting at 0,
+and tag is the truncated CMAC of counter || payload. Each direction of a
+connection must use a distinct key so that frames can't be reflected.
+
+The most significant bit of counter is set in a rekey frame. Its payload is
+the ID of the key authenticating the following frames, and its tag is
+computed with the previous key. Sequence numbers continue across rekeys.
+*/
+
+// minTagSize is the minimum truncated tag size in bytes.
+const minTagSize = 8
+
+// rekeyFlag is the counter bit set in rekey frames.
+const rekeyFlag = 1 << 63
+
+// replayWindowSize is the number of frame counters tracked below the highest
+// counter received.
+const replayWindowSize = 64
+
+var (
+	// ErrInvalidFrame is returned when a frame is too short or its tag is invalid.
+	ErrInvalidFrame = errors.New("cmac: invalid frame")
+
+	// ErrReplayedFrame is returned when a frame was already received or is
+	// too old to be checked.
+	ErrReplayedFrame = errors.New("cmac: replayed frame")
+)
+
+// FrameAuthenticator authenticates the frames of a message oriented
+// connection, like a websocket, and rejects replayed frames. It must not be
+// used concurrently.
+type FrameAuthenticator struct {
+	send, recv hash.Hash
+	tagSize    int
+	sendCount  uint64
+	window     replayWindow
+	rekey      KeyFunc
+}
+
+// NewFrameAuthenticator returns a frame authenticator sealing frames with send
+// and opening frames with recv. The two hashes must use distinct keys. The
+// tags are truncated to tagSize bytes.
+func NewFrameAuthenticator(send, recv hash.Hash, tagSize int) (*FrameAuthenticator, error) {
+	if tagSize < minTagSize || tagSize > send.Size() || tagSize > recv.Size() {
+		return nil, newError(ErrInvalidArgument, "cmac: invalid tag size")
+	}
+	return &FrameAuthenticator{send: send, recv: recv, tagSize: tagSize}, nil
+}
+
+// Overhead returns the number of bytes appended to a payload by Seal.
+func (f *FrameAuthenticator) Overhead() int {
+	return 8 + f.tagSize
+}
+
+// SetRekeyFunc sets the function returning the hash opening the frames
+// following a received rekey frame, given its key ID. Rekey frames are
+// rejected as invalid when no function is set.
+func (f *FrameAuthenticator) SetRekeyFunc(keys KeyFunc) {
+	f.rekey = keys
+}
+
+// SealRekey appends to dst a rekey frame announcing the key ID, and seals
+// the following frames with send. The receiver must obtain the hash of the
+// key ID with its rekey function. The key ID may not be longer than 255 bytes.
+func (f *FrameAuthenticator) SealRekey(dst []byte, keyID string, send hash.Hash) ([]byte, error) {
+	if len(keyID) > 255 {
+		return dst, newError(ErrInvalidArgument, "cmac: key ID too long")
+	}
+	if f.tagSize > send.Size() {
+		return dst, newError(ErrInvalidArgument, "cmac: invalid tag size")
+	}
+	dst = f.seal(dst, []byte(keyID), rekeyFlag)
+	f.send = send
+	return dst, nil
+}
+
+// Seal appends the authenticated frame of payload to dst and returns the
+// resulting slice.
+func (f *FrameAuthenticator) Seal(dst, payload []byte) []byte {
+	return f.seal(dst, payload, 0)
+}
+
+// seal appends the frame of payload with the counter flags to dst.
+func (f *FrameAuthenticator) seal(dst, payload []byte, flags uint64) []byte {
+	var ctr [8]byte
+	binary.BigEndian.PutUint64(ctr[:], f.sendCount|flags)
+	f.sendCount++
+	f.send.Reset()
+	f.send.Write(ctr[:])
+	f.send.Write(payload)
+	dst = append(dst, payload...)
+	dst = append(dst, ctr[:]...)
+	return append(dst, f.send.Sum(nil)[:f.tagSize]...)
+}
+
+// Open verifies the authenticated frame and appends its payload to dst. A
+// valid rekey frame switches the hash opening the following frames, and
+// leaves dst unchanged.
+func (f *FrameAuthenticator) Open(dst, frame []byte) ([]byte, error) {
+	n := len(frame) - f.Overhead()
+	if n < 0 {
+		return dst, ErrInvalidFrame
+	}
+	ctr := binary.BigEndian.Uint64(frame[n:])
+	flags := ctr & rekeyFlag
+	ctr &^= rekeyFlag
+	if !f.window.check(ctr) {
+		return dst, ErrReplayedFrame
+	}
+	f.recv.Reset()
+	f.recv.Write(frame[n : n+8])
+	f.recv.Write(frame[:n])
+	if !Equal(f.recv.Sum(nil)[:f.tagSize], frame[n+8:]) {
+		audit("FrameAuthenticator.Open", "", int64(n))
+		return dst, ErrInvalidFrame
+	}
+	if flags != 0 {
+		if f.rekey == nil {
+			return dst, ErrInvalidFrame
+		}
+		h, err := f.rekey(string(frame[:n]))
+		if err != nil {
+			return dst, err
+		}
+		if f.tagSize > h.Size() {
+			return dst, newError(ErrInvalidArgument, "cmac: invalid tag size")
+		}
+		f.recv = h
+		f.window.update(ctr)
+		return dst, nil
+	}
+	f.window.update(ctr)
+	return append(dst, frame[:n]...), nil
+}
+
+// replayWindow is a sliding window over received counters as in RFC 4303.
+type replayWindow struct {
+	top    uint64 // highest counter received plus one, 0 when none
+	bitmap uint64 // bit i is set when counter top-1-i was received
+}
+
+// check returns true if ctr was not yet received and is in the window.
+func (w *replayWindow) check(ctr uint64) bool {
+	if ctr >= w.top {
+		return true
+	}
+	d := w.top - 1 - ctr
+	return d < replayWindowSize && w.bitmap&(1<<d) == 0
+}
+
+// update records the reception of ctr which must have been checked.
+func (w *replayWindow) update(ctr uint64) {
+	if ctr >= w.top {
+		s := ctr + 1 - w.top
+		if s >= replayWindowSize {
+			w.bitmap = 0
+		} else {
+			w.bitmap <<= s
+		}
+		w.bitmap |= 1
+		w.top = ctr + 1
+		return
+	}
+	w.bitmap |= 1 << (w.top - 1 - ctr)
+}
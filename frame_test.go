@@ -0,0 +1,131 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"errors"
+	"hash"
+	"testing"
+)
+
+func newFramePair(t *testing.T, tagSize int) (*FrameAuthenticator, *FrameAuthenticator) {
+	k1, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	k2, _ := New(aes.NewCipher, []byte("fedcba9876543210"))
+	k3, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	k4, _ := New(aes.NewCipher, []byte("fedcba9876543210"))
+	a, err := NewFrameAuthenticator(k1, k2, tagSize)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	b, err := NewFrameAuthenticator(k4, k3, tagSize)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	return a, b
+}
+
+func TestFrameAuthenticator(t *testing.T) {
+	a, b := newFramePair(t, 12)
+	frames := make([][]byte, 100)
+	for i := range frames {
+		frames[i] = a.Seal(nil, []byte{byte(i), 'x'})
+		if len(frames[i]) != 2+a.Overhead() {
+			t.Fatalf("got frame length %d, expected %d", len(frames[i]), 2+a.Overhead())
+		}
+	}
+	p, err := b.Open(nil, frames[0])
+	if err != nil || string(p) != "\x00x" {
+		t.Fatalf("got %q, %v", p, err)
+	}
+	if _, err := b.Open(nil, frames[0]); err != ErrReplayedFrame {
+		t.Errorf("got error %v, expected %v", err, ErrReplayedFrame)
+	}
+	// out of order frames in the window are accepted once
+	for _, i := range []int{5, 3, 4, 1, 2, 80, 20, 17} {
+		if p, err := b.Open(nil, frames[i]); err != nil || p[0] != byte(i) {
+			t.Errorf("%2d: got %q, %v", i, p, err)
+		}
+	}
+	for _, i := range []int{3, 80, 16, 10} {
+		if _, err := b.Open(nil, frames[i]); err != ErrReplayedFrame {
+			t.Errorf("%2d: got error %v, expected %v", i, err, ErrReplayedFrame)
+		}
+	}
+	if _, err := b.Open(nil, frames[17]); err != ErrReplayedFrame {
+		t.Errorf("got error %v, expected %v", err, ErrReplayedFrame)
+	}
+
+	// reflected, tampered and short frames are rejected
+	if _, err := a.Open(nil, frames[90]); err != ErrInvalidFrame {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidFrame)
+	}
+	frames[91][0] ^= 1
+	if _, err := b.Open(nil, frames[91]); err != ErrInvalidFrame {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidFrame)
+	}
+	if _, err := b.Open(nil, frames[92][:10]); err != ErrInvalidFrame {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidFrame)
+	}
+	if _, err := b.Open(nil, frames[92]); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// reply direction
+	if p, err := a.Open(nil, b.Seal(nil, []byte("pong"))); err != nil || string(p) != "pong" {
+		t.Errorf("got %q, %v", p, err)
+	}
+
+	h, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	for _, n := range []int{0, 7, 17} {
+		if _, err := NewFrameAuthenticator(h, h, n); err == nil {
+			t.Errorf("%d: unexpected nil error for invalid tag size", n)
+		}
+	}
+}
+
+func TestFrameRekey(t *testing.T) {
+	a, b := newFramePair(t, 12)
+	k5, _ := New(aes.NewCipher, []byte("new key 01234567"))
+	k6, _ := New(aes.NewCipher, []byte("new key 01234567"))
+	keys := func(keyID string) (hash.Hash, error) {
+		if keyID != "k5" {
+			return nil, errors.New("unknown key")
+		}
+		return k6, nil
+	}
+
+	f0 := a.Seal(nil, []byte("old"))
+	r, err := a.SealRekey(nil, "k5", k5)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	f1 := a.Seal(nil, []byte("new"))
+
+	if _, err := b.Open(nil, r); err != ErrInvalidFrame {
+		t.Errorf("got error %v, expected %v without rekey function", err, ErrInvalidFrame)
+	}
+	b.SetRekeyFunc(keys)
+	if p, err := b.Open(nil, f0); err != nil || string(p) != "old" {
+		t.Errorf("got %q %v, expected old", p, err)
+	}
+	if _, err := b.Open(nil, f1); err != ErrInvalidFrame {
+		t.Errorf("got error %v, expected %v before rekey", err, ErrInvalidFrame)
+	}
+	if p, err := b.Open([]byte{}, r); err != nil || len(p) != 0 {
+		t.Errorf("got %q %v for rekey frame", p, err)
+	}
+	if _, err := b.Open(nil, r); err != ErrReplayedFrame {
+		t.Errorf("got error %v, expected %v", err, ErrReplayedFrame)
+	}
+	if p, err := b.Open(nil, f1); err != nil || string(p) != "new" {
+		t.Errorf("got %q %v, expected new", p, err)
+	}
+
+	// a rekey frame to an unknown key is rejected
+	r, _ = a.SealRekey(nil, "k7", k5)
+	if _, err := b.Open(nil, r); err == nil {
+		t.Errorf("unexpected nil error for unknown key ID")
+	}
+	if _, err := a.SealRekey(nil, string(make([]byte, 256)), k5); err == nil {
+		t.Errorf("unexpected nil error for too long key ID")
+	}
+}
@@ -0,0 +1,148 @@
+package cmac
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+	"io/fs"
+)
+
+// ErrNotInManifest is returned when opening a file that is not in the manifest.
+var ErrNotInManifest = errors.New("cmac: file not in manifest")
+
+type verifyingFS struct {
+	fsys fs.FS
+	m    *Manifest
+	key  []byte
+}
+
+// VerifyFS returns a file system whose regular files are verified against
+// the manifest m with the key as they are read. Opening a regular file not
+// in the manifest fails with ErrNotInManifest. A read returns ErrMismatch
+// when the file content doesn't match its tags. VerifyFS returns ErrMismatch
+// when the manifest tag is invalid, so that the entries can't be modified,
+// swapped or taken from another manifest.
+//
+// When the manifest is not chunked, the data read is unverified until the
+// file has been read up to io.EOF. When it is chunked, the data is verified
+// chunk by chunk before being returned.
+func VerifyFS(fsys fs.FS, m *Manifest, key []byte) (fs.FS, error) {
+	h, err := m.Algorithm.New(key)
+	if err != nil {
+		return nil, err
+	}
+	if !Equal(m.sum(h), m.Tag) {
+		audit("VerifyFS", m.KeyID, -1)
+		return nil, ErrMismatch
+	}
+	return &verifyingFS{fsys: fsys, m: m, key: key}, nil
+}
+
+func (v *verifyingFS) Open(name string) (fs.File, error) {
+	f, err := v.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	e := v.m.Lookup(name)
+	if e == nil {
+		if fi, err := f.Stat(); err == nil && fi.IsDir() {
+			return f, nil
+		}
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrNotInManifest}
+	}
+	h, _ := v.m.Algorithm.New(v.key)
+	vf := &verifyingFile{File: f, e: e}
+	if v.m.ChunkSize <= 0 {
+		vf.r = NewVerifyingReader(io.LimitReader(f, e.Size+1), h, e.Tag)
+		return vf, nil
+	}
+	if !Equal(sumChunkTags(h, uint64(e.Size), e.Chunks), e.Tag) {
+		f.Close()
+		audit("VerifyFS", v.m.KeyID, e.Size)
+		return nil, &fs.PathError{Op: "open", Path: name, Err: ErrMismatch}
+	}
+	vf.r = &chunkVerifier{r: f, h: h, keyID: v.m.KeyID, chunks: e.Chunks, size: v.m.ChunkSize}
+	return vf, nil
+}
+
+type verifyingFile struct {
+	fs.File
+	e *ManifestEntry
+	r io.Reader
+	n int64
+}
+
+func (f *verifyingFile) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	f.n += int64(n)
+	if f.n > f.e.Size || (err == io.EOF && f.n != f.e.Size) {
+		return 0, &fs.PathError{Op: "read", Path: f.e.Path, Err: ErrMismatch}
+	}
+	if err != nil && err != io.EOF {
+		err = &fs.PathError{Op: "read", Path: f.e.Path, Err: err}
+	}
+	return n, err
+}
+
+// chunkVerifier reads and verifies a chunk at a time.
+type chunkVerifier struct {
+	r      io.Reader
+	h      hash.Hash
+	keyID  string
+	chunks [][]byte
+	size   int64
+	idx    int
+	buf    []byte
+	off    int
+	err    error
+}
+
+func (c *chunkVerifier) Read(p []byte) (int, error) {
+	if c.off == len(c.buf) && c.err == nil {
+		c.err = c.next()
+	}
+	if c.off == len(c.buf) {
+		return 0, c.err
+	}
+	n := copy(p, c.buf[c.off:])
+	c.off += n
+	return n, nil
+}
+
+// next reads and verifies the next chunk.
+func (c *chunkVerifier) next() error {
+	if c.buf == nil {
+		c.buf = make([]byte, c.size)
+	}
+	n, err := io.ReadFull(c.r, c.buf[:c.size])
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	c.buf, c.off = c.buf[:n], 0
+	if n == 0 {
+		if c.idx != len(c.chunks) {
+			audit("VerifyFS", c.keyID, -1)
+			return ErrMismatch
+		}
+		return io.EOF
+	}
+	if c.idx >= len(c.chunks) {
+		c.buf = c.buf[:0]
+		audit("VerifyFS", c.keyID, int64(n))
+		return ErrMismatch
+	}
+	var hdr [8]byte
+	binary.BigEndian.PutUint64(hdr[:], uint64(c.idx))
+	c.h.Reset()
+	c.h.Write(hdr[:])
+	c.h.Write(c.buf)
+	if !Equal(c.h.Sum(nil), c.chunks[c.idx]) {
+		c.buf = c.buf[:0]
+		audit("VerifyFS", c.keyID, int64(n))
+		return ErrMismatch
+	}
+	c.idx++
+	return nil
+}
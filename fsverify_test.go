@@ -0,0 +1,108 @@
+package cmac
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func testManifest(t *testing.T, fsys fstest.MapFS, key []byte, chunkSize int64, paths ...string) *Manifest {
+	m := &Manifest{Algorithm: AES128, ChunkSize: chunkSize}
+	for _, p := range paths {
+		data := fsys[p].Data
+		s, err := NewSidecar(AES128, key, "", bytes.NewReader(data), chunkSize)
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		m.Files = append(m.Files, ManifestEntry{Path: p, Size: int64(len(data)), Chunks: s.Chunks, Tag: s.Tag})
+	}
+	h, _ := AES128.New(key)
+	m.Tag = m.sum(h)
+	return m
+}
+
+func TestVerifyFS(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	for _, chunkSize := range []int64{0, 7, 100} {
+		fsys := fstest.MapFS{
+			"a.txt":     {Data: bytes.Repeat([]byte("a"), 50)},
+			"dir/b.txt": {Data: []byte("bbbbbbbbbbbbbbbb")},
+			"dir/c.txt": {Data: []byte("")},
+			"extra.txt": {Data: []byte("not in manifest")},
+		}
+		m := testManifest(t, fsys, key, chunkSize, "a.txt", "dir/b.txt", "dir/c.txt")
+		vfs, err := VerifyFS(fsys, m, key)
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		for _, p := range []string{"a.txt", "dir/b.txt", "dir/c.txt"} {
+			b, err := fs.ReadFile(vfs, p)
+			if err != nil || !bytes.Equal(b, fsys[p].Data) {
+				t.Errorf("%d %s: got %q, %v", chunkSize, p, b, err)
+			}
+		}
+		if _, err := vfs.Open("extra.txt"); !errors.Is(err, ErrNotInManifest) {
+			t.Errorf("%d: got error %v, expected %v", chunkSize, err, ErrNotInManifest)
+		}
+		if _, err := fs.ReadDir(vfs, "dir"); err != nil {
+			t.Errorf("%d: unexpected error: %v", chunkSize, err)
+		}
+
+		fsys["a.txt"].Data[20] = 'x'
+		if _, err := fs.ReadFile(vfs, "a.txt"); !errors.Is(err, ErrMismatch) {
+			t.Errorf("%d: got error %v, expected %v", chunkSize, err, ErrMismatch)
+		}
+		fsys["dir/b.txt"].Data = fsys["dir/b.txt"].Data[:10]
+		if _, err := fs.ReadFile(vfs, "dir/b.txt"); !errors.Is(err, ErrMismatch) {
+			t.Errorf("%d: got error %v, expected %v", chunkSize, err, ErrMismatch)
+		}
+		fsys["dir/c.txt"].Data = []byte("c")
+		if _, err := fs.ReadFile(vfs, "dir/c.txt"); !errors.Is(err, ErrMismatch) {
+			t.Errorf("%d: got error %v, expected %v", chunkSize, err, ErrMismatch)
+		}
+	}
+
+	// chunks are verified before being returned
+	fsys := fstest.MapFS{"a.txt": {Data: bytes.Repeat([]byte("a"), 50)}}
+	m := testManifest(t, fsys, key, 10, "a.txt")
+	vfs, _ := VerifyFS(fsys, m, key)
+	fsys["a.txt"].Data[25] = 'x'
+	f, _ := vfs.Open("a.txt")
+	b, err := io.ReadAll(f)
+	if len(b) != 20 || !errors.Is(err, ErrMismatch) {
+		t.Errorf("got %d bytes and error %v, expected 20 bytes and %v", len(b), err, ErrMismatch)
+	}
+	m.Files[0].Chunks[0][0] ^= 1
+	if _, err := vfs.Open("a.txt"); !errors.Is(err, ErrMismatch) {
+		t.Errorf("got error %v, expected %v", err, ErrMismatch)
+	}
+	if _, err := VerifyFS(fsys, m, key[:5]); err == nil {
+		t.Errorf("unexpected nil error for invalid key size")
+	}
+}
+
+func TestVerifyFSManifestTag(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	for _, chunkSize := range []int64{0, 10} {
+		fsys := fstest.MapFS{
+			"a.txt": {Data: []byte("content of a")},
+			"b.txt": {Data: []byte("content of b")},
+		}
+		m := testManifest(t, fsys, key, chunkSize, "a.txt", "b.txt")
+		// swap the entries of a.txt and b.txt and serve b.txt as a.txt
+		a, b := m.Files[0], m.Files[1]
+		m.Files[0].Size, m.Files[0].Chunks, m.Files[0].Tag = b.Size, b.Chunks, b.Tag
+		m.Files[1].Size, m.Files[1].Chunks, m.Files[1].Tag = a.Size, a.Chunks, a.Tag
+		fsys["a.txt"].Data, fsys["b.txt"].Data = fsys["b.txt"].Data, fsys["a.txt"].Data
+		if _, err := VerifyFS(fsys, m, key); err != ErrMismatch {
+			t.Errorf("%d: got error %v, expected %v", chunkSize, err, ErrMismatch)
+		}
+		m.Tag = nil
+		if _, err := VerifyFS(fsys, m, key); err != ErrMismatch {
+			t.Errorf("%d: got error %v, expected %v for missing tag", chunkSize, err, ErrMismatch)
+		}
+	}
+}
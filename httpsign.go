@@ -0,0 +1,229 @@
+//go:build !cmac_small
+// +build !cmac_small
+
+package cmac
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* A signed HTTP request carries the key ID, timestamp and signature headers.
+The signature is the base64url encoded CMAC, without padding, of the
+canonical request made of the following lines separated by '\n':
+
+   method
+   escaped URL path
+   raw URL query
+   timestamp in seconds since the Unix epoch
+   key ID
+   lowercase header name ':' header values joined by ','   (one per selected header)
+   hex encoded SHA-256 of the body
+
+The selected headers are configured identically on both sides. The Host
+header value is the request host.
+*/
+
+// HTTP header names used for request signing.
+const (
+	HeaderKeyID     = "X-Cmac-Key-Id"
+	HeaderTimestamp = "X-Cmac-Timestamp"
+	HeaderSignature = "X-Cmac-Signature"
+)
+
+// ErrInvalidSignature is returned when a request signature is missing,
+// invalid or out of the accepted time window.
+var ErrInvalidSignature = errors.New("cmac: invalid request signature")
+
+// ErrBodyTooLarge is returned when a request body to verify is larger than
+// the maximum body size.
+var ErrBodyTooLarge = newError(ErrInvalidArgument, "cmac: request body too large")
+
+// DefaultMaxBodySize is the maximum byte size of the request bodies verified
+// by VerifyHandler.
+const DefaultMaxBodySize = 10 << 20
+
+// SignRequest adds the key ID, timestamp and signature headers to req. The
+// signature covers the given header names. h is the hash of the key with ID
+// keyID and is reset. The request body is read and replaced.
+func SignRequest(req *http.Request, h hash.Hash, keyID string, headers []string, now time.Time) error {
+	body, err := readBody(req, -1)
+	if err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set(HeaderKeyID, keyID)
+	req.Header.Set(HeaderTimestamp, ts)
+	h.Reset()
+	writeCanonicalRequest(h, req, ts, keyID, headers, body)
+	req.Header.Set(HeaderSignature, base64.RawURLEncoding.EncodeToString(h.Sum(nil)))
+	return nil
+}
+
+// VerifyRequest verifies the signature of req with the hash returned by keys
+// for the request key ID. The timestamp may not differ from now by more than
+// maxSkew. The request body is read and replaced. The body is read in memory
+// before the signature can be verified, so the body of an untrusted request
+// should be limited, e.g. with http.MaxBytesReader, as VerifyHandler does.
+func VerifyRequest(req *http.Request, keys KeyFunc, headers []string, now time.Time, maxSkew time.Duration) error {
+	return verifyRequest(req, keys, headers, now, maxSkew, -1)
+}
+
+// verifyRequest is VerifyRequest returning ErrBodyTooLarge when the body is
+// larger than maxBodySize bytes, unless it is negative.
+func verifyRequest(req *http.Request, keys KeyFunc, headers []string, now time.Time, maxSkew time.Duration, maxBodySize int64) error {
+	keyID := req.Header.Get(HeaderKeyID)
+	ts := req.Header.Get(HeaderTimestamp)
+	sig, err := base64.RawURLEncoding.DecodeString(req.Header.Get(HeaderSignature))
+	if err != nil || len(sig) == 0 {
+		return ErrInvalidSignature
+	}
+	secs, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if d := now.Sub(time.Unix(secs, 0)); d > maxSkew || d < -maxSkew {
+		return ErrInvalidSignature
+	}
+	h, err := keys(keyID)
+	if err != nil {
+		return err
+	}
+	body, err := readBody(req, maxBodySize)
+	if err != nil {
+		return err
+	}
+	h.Reset()
+	writeCanonicalRequest(h, req, ts, keyID, headers, body)
+	if !Equal(h.Sum(nil), sig) {
+		audit("VerifyRequest", keyID, int64(len(body)))
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Transport is an http.RoundTripper that signs requests before sending
+// them with Base, or http.DefaultTransport when Base is nil. Keys must return
+// a distinct hash at each call when the transport is used concurrently.
+type Transport struct {
+	Base    http.RoundTripper
+	KeyID   string
+	Keys    KeyFunc
+	Headers []string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	h, err := t.Keys(t.KeyID)
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	if err := SignRequest(req, h, t.KeyID, t.Headers, time.Now()); err != nil {
+		return nil, err
+	}
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+// VerifyHandler returns a handler that verifies request signatures before
+// calling next, and replies with 401 Unauthorized when the verification fails.
+// Keys must return a distinct hash at each call. Request bodies larger than
+// DefaultMaxBodySize are rejected with 413 Request Entity Too Large.
+func VerifyHandler(next http.Handler, keys KeyFunc, headers []string, maxSkew time.Duration) http.Handler {
+	return VerifyHandlerMaxBody(next, keys, headers, maxSkew, DefaultMaxBodySize)
+}
+
+// VerifyHandlerMaxBody is like VerifyHandler, with request bodies limited to
+// maxBodySize bytes. The body is buffered to verify the signature, so the
+// limit bounds the memory used by unauthenticated requests.
+func VerifyHandlerMaxBody(next http.Handler, keys KeyFunc, headers []string, maxSkew time.Duration, maxBodySize int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > maxBodySize {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+		err := verifyRequest(r, keys, headers, time.Now(), maxSkew, maxBodySize)
+		if err == ErrBodyTooLarge {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// readBody reads the request body and replaces it with a reader of the bytes
+// read. It returns ErrBodyTooLarge when the body is larger than max bytes,
+// unless max is negative.
+func readBody(req *http.Request, max int64) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	var r io.Reader = req.Body
+	if max >= 0 {
+		r = io.LimitReader(r, max+1)
+	}
+	body, err := io.ReadAll(r)
+	req.Body.Close()
+	if err != nil {
+		return nil, wrapError(ErrIO, err)
+	}
+	if max >= 0 && int64(len(body)) > max {
+		return nil, ErrBodyTooLarge
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, nil
+}
+
+func writeCanonicalRequest(w io.Writer, req *http.Request, ts, keyID string, headers []string, body []byte) {
+	var b strings.Builder
+	b.WriteString(req.Method)
+	b.WriteByte('\n')
+	b.WriteString(req.URL.EscapedPath())
+	b.WriteByte('\n')
+	b.WriteString(req.URL.RawQuery)
+	b.WriteByte('\n')
+	b.WriteString(ts)
+	b.WriteByte('\n')
+	b.WriteString(keyID)
+	b.WriteByte('\n')
+	for _, name := range headers {
+		b.WriteString(strings.ToLower(name))
+		b.WriteByte(':')
+		if strings.EqualFold(name, "Host") {
+			host := req.Host
+			if host == "" {
+				host = req.URL.Host
+			}
+			b.WriteString(host)
+		} else {
+			b.WriteString(strings.Join(req.Header.Values(name), ","))
+		}
+		b.WriteByte('\n')
+	}
+	sum := sha256.Sum256(body)
+	b.WriteString(hex.EncodeToString(sum[:]))
+	io.WriteString(w, b.String())
+}
@@ -0,0 +1,142 @@
+//go:build !cmac_small
+// +build !cmac_small
+
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPSign(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	keys := func(keyID string) (hash.Hash, error) {
+		if keyID != "k1" {
+			return nil, errors.New("unknown key")
+		}
+		return New(aes.NewCipher, key)
+	}
+	headers := []string{"Host", "Content-Type"}
+	now := time.Unix(1600000000, 0)
+
+	req := httptest.NewRequest("POST", "http://example.com/a%20b?x=1", strings.NewReader("payload"))
+	req.Header.Set("Content-Type", "text/plain")
+	h, _ := keys("k1")
+	if err := SignRequest(req, h, "k1", headers, now); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if err := VerifyRequest(req, keys, headers, now.Add(time.Second), time.Minute); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if body, _ := io.ReadAll(req.Body); string(body) != "payload" {
+		t.Errorf("got body %q, expected %q", body, "payload")
+	}
+	if err := VerifyRequest(req, keys, headers, now.Add(2*time.Minute), time.Minute); err != ErrInvalidSignature {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidSignature)
+	}
+	req.Body = io.NopCloser(strings.NewReader("tampered"))
+	if err := VerifyRequest(req, keys, headers, now, time.Minute); err != ErrInvalidSignature {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidSignature)
+	}
+	req.Body = io.NopCloser(strings.NewReader("payload"))
+	req.Header.Set("Content-Type", "text/html")
+	if err := VerifyRequest(req, keys, headers, now, time.Minute); err != ErrInvalidSignature {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidSignature)
+	}
+	req.Header.Set(HeaderKeyID, "k2")
+	if err := VerifyRequest(req, keys, headers, now, time.Minute); err == nil {
+		t.Errorf("unexpected nil error for unknown key ID")
+	}
+	req.Header.Set(HeaderKeyID, "k1")
+	req.Body = io.NopCloser(&errReader{io.ErrUnexpectedEOF})
+	if err := VerifyRequest(req, keys, headers, now, time.Minute); !errors.Is(err, ErrIO) {
+		t.Errorf("got error %v, expected category %v", err, ErrIO)
+	}
+}
+
+func TestHTTPSignMiddleware(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	keys := func(keyID string) (hash.Hash, error) {
+		return New(aes.NewCipher, key)
+	}
+	headers := []string{"Host"}
+	srv := httptest.NewServer(VerifyHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}), keys, headers, time.Minute))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &Transport{KeyID: "k1", Keys: keys, Headers: headers}}
+	resp, err := client.Post(srv.URL+"/path?q=1", "text/plain", bytes.NewReader([]byte("hello")))
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "hello" {
+		t.Errorf("got status %d and body %q", resp.StatusCode, body)
+	}
+
+	resp, err = http.Post(srv.URL+"/path", "text/plain", bytes.NewReader([]byte("hello")))
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("got status %d, expected %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	// the body is closed when the key is unknown
+	rc := &closeRecorder{Reader: strings.NewReader("hello")}
+	req, _ := http.NewRequest("POST", srv.URL+"/path", rc)
+	failing := &Transport{KeyID: "k1", Keys: func(string) (hash.Hash, error) { return nil, ErrUnknownKey }}
+	if _, err := failing.RoundTrip(req); err != ErrUnknownKey || !rc.closed {
+		t.Errorf("got error %v and closed %v, expected %v and a closed body", err, rc.closed, ErrUnknownKey)
+	}
+
+	// bodies larger than the maximum are rejected before being buffered
+	limited := httptest.NewServer(VerifyHandlerMaxBody(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		keys, headers, time.Minute, 8))
+	defer limited.Close()
+	for _, tc := range []struct {
+		body   string
+		length int64
+		status int
+	}{
+		{"12345678", 8, http.StatusOK},
+		{"123456789", 9, http.StatusRequestEntityTooLarge},
+		{"123456789", -1, http.StatusRequestEntityTooLarge}, // chunked
+	} {
+		req, _ := http.NewRequest("POST", limited.URL+"/path", strings.NewReader(tc.body))
+		req.ContentLength = tc.length
+		if tc.length < 0 {
+			req.Body = io.NopCloser(strings.NewReader(tc.body))
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.status {
+			t.Errorf("%q %d: got status %d, expected %d", tc.body, tc.length, resp.StatusCode, tc.status)
+		}
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
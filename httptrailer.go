@@ -0,0 +1,126 @@
+//go:build !cmac_small
+// +build !cmac_small
+
+package cmac
+
+import (
+	"encoding/base64"
+	"hash"
+	"io"
+	"net/http"
+)
+
+/* A response authenticated by a trailer carries the key ID in the
+X-Cmac-Key-Id header, and the base64url encoded CMAC of the body, without
+padding, in the X-Content-Cmac trailer. The body is sent with the chunked
+transfer encoding, so that it is streamed without buffering.
+*/
+
+// HeaderContentCMAC is the name of the trailer holding the CMAC of a
+// response body.
+const HeaderContentCMAC = "X-Content-Cmac"
+
+// TrailerHandler returns a handler that streams the response body of next
+// and sends its CMAC, computed with the hash of the key ID, in a trailer.
+// Keys must return a distinct hash at each call.
+func TrailerHandler(next http.Handler, keyID string, keys KeyFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, err := keys(keyID)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		h.Reset()
+		tw := &trailerWriter{ResponseWriter: w, h: h, keyID: keyID}
+		next.ServeHTTP(tw, r)
+		if !tw.wroteHeader {
+			tw.WriteHeader(http.StatusOK)
+		}
+		w.Header().Set(HeaderContentCMAC, base64.RawURLEncoding.EncodeToString(h.Sum(nil)))
+	})
+}
+
+type trailerWriter struct {
+	http.ResponseWriter
+	h           hash.Hash
+	keyID       string
+	wroteHeader bool
+}
+
+func (t *trailerWriter) WriteHeader(code int) {
+	if t.wroteHeader {
+		return
+	}
+	t.wroteHeader = true
+	hdr := t.Header()
+	hdr.Del("Content-Length")
+	hdr.Set(HeaderKeyID, t.keyID)
+	hdr.Add("Trailer", HeaderContentCMAC)
+	t.ResponseWriter.WriteHeader(code)
+}
+
+func (t *trailerWriter) Write(p []byte) (int, error) {
+	if !t.wroteHeader {
+		t.WriteHeader(http.StatusOK)
+	}
+	n, err := t.ResponseWriter.Write(p)
+	t.h.Write(p[:n])
+	return n, err
+}
+
+// Flush implements http.Flusher when the underlying writer does.
+func (t *trailerWriter) Flush() {
+	if !t.wroteHeader {
+		t.WriteHeader(http.StatusOK)
+	}
+	if f, ok := t.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// VerifyResponseTrailer replaces the body of resp with a reader verifying
+// the CMAC of the body, sent in a trailer by a TrailerHandler, with the hash
+// returned by keys for the response key ID. The reader returns io.EOF when
+// the CMAC is valid, and ErrMismatch when it is invalid or missing. The data
+// read is thus unverified until io.EOF is returned.
+func VerifyResponseTrailer(resp *http.Response, keys KeyFunc) error {
+	keyID := resp.Header.Get(HeaderKeyID)
+	h, err := keys(keyID)
+	if err != nil {
+		return err
+	}
+	h.Reset()
+	resp.Body = &trailerReader{body: resp.Body, resp: resp, h: h, keyID: keyID}
+	return nil
+}
+
+type trailerReader struct {
+	body  io.ReadCloser
+	resp  *http.Response
+	h     hash.Hash
+	keyID string
+	n     int64
+	err   error
+}
+
+func (t *trailerReader) Read(p []byte) (int, error) {
+	if t.err != nil {
+		return 0, t.err
+	}
+	n, err := t.body.Read(p)
+	t.h.Write(p[:n])
+	t.n += int64(n)
+	if err == io.EOF {
+		tag, derr := base64.RawURLEncoding.DecodeString(t.resp.Trailer.Get(HeaderContentCMAC))
+		if derr != nil || !Equal(t.h.Sum(nil), tag) {
+			audit("VerifyResponseTrailer", t.keyID, t.n)
+			err = ErrMismatch
+		}
+	}
+	t.err = err
+	return n, err
+}
+
+func (t *trailerReader) Close() error {
+	return t.body.Close()
+}
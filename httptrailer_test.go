@@ -0,0 +1,84 @@
+//go:build !cmac_small
+// +build !cmac_small
+
+package cmac
+
+import (
+	"crypto/aes"
+	"hash"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestResponseTrailer(t *testing.T) {
+	keys := func(keyID string) (hash.Hash, error) {
+		switch keyID {
+		case "k1":
+			return New(aes.NewCipher, []byte("0123456789abcdef"))
+		case "k2":
+			return New(aes.NewCipher, []byte("fedcba9876543210"))
+		}
+		return nil, ErrUnknownKey
+	}
+	content := strings.Repeat("large download ", 10000)
+	body := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		for i := 0; i < len(content); i += 4096 {
+			end := i + 4096
+			if end > len(content) {
+				end = len(content)
+			}
+			io.WriteString(w, content[i:end])
+			w.(http.Flusher).Flush()
+		}
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/signed", TrailerHandler(body, "k1", keys))
+	mux.Handle("/unsigned", body)
+	mux.Handle("/wrongkey", TrailerHandler(body, "k1", func(string) (hash.Hash, error) { return keys("k2") }))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		path string
+		err  error
+	}{
+		{"/signed", nil},
+		{"/unsigned", ErrUnknownKey},
+		{"/wrongkey", ErrMismatch},
+	}
+	for _, test := range tests {
+		resp, err := http.Get(srv.URL + test.path)
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		err = VerifyResponseTrailer(resp, keys)
+		if err == nil {
+			var b []byte
+			b, err = io.ReadAll(resp.Body)
+			if err == nil && string(b) != content {
+				t.Errorf("%s: got body of %d bytes, expected %d", test.path, len(b), len(content))
+			}
+		}
+		resp.Body.Close()
+		if err != test.err {
+			t.Errorf("%s: got error %v, expected %v", test.path, err, test.err)
+		}
+	}
+
+	// a missing trailer is rejected
+	resp, err := http.Get(srv.URL + "/unsigned")
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	resp.Header.Set(HeaderKeyID, "k1")
+	VerifyResponseTrailer(resp, keys)
+	if _, err := io.ReadAll(resp.Body); err != ErrMismatch {
+		t.Errorf("got error %v, expected %v", err, ErrMismatch)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,70 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"hash"
+)
+
+/* The IANA code points of the algorithms implemented by the package are
+
+   IKEv2 transform type 2 (PRF)        ID 8  PRF_AES128_CMAC   RFC 4615
+   IKEv2 transform type 3 (integrity)  ID 8  AUTH_AES_CMAC_96  RFC 4494
+
+The COSE AES-MAC algorithms are CBC-MAC, not CMAC, and TLS has no CMAC
+code point, so they have no mapping.
+*/
+
+// IKEv2 transform types of the IANA IKEv2 registry.
+const (
+	IKEv2TransformPRF       = 2
+	IKEv2TransformIntegrity = 3
+)
+
+// IANAAlgorithm is the parameter set of an algorithm identified by an IANA
+// code point.
+type IANAAlgorithm struct {
+	Name    string // IANA name, e.g. "AUTH_AES_CMAC_96"
+	KeySize int    // key byte size, or 0 when any size is accepted
+	TagSize int    // byte size of the output, which is the truncated tag
+	// New returns the hash of the algorithm with the key. Its Sum is
+	// truncated to TagSize.
+	New func(key []byte) (hash.Hash, error)
+}
+
+var ikev2Algorithms = []struct {
+	transformType, id uint16
+	alg               IANAAlgorithm
+}{
+	{IKEv2TransformPRF, 8, IANAAlgorithm{"PRF_AES128_CMAC", 0, aes.BlockSize, newPRF128}},
+	{IKEv2TransformIntegrity, 8, IANAAlgorithm{"AUTH_AES_CMAC_96", 16, 12, newAESCMAC96}},
+}
+
+func newAESCMAC96(key []byte) (hash.Hash, error) {
+	if len(key) != 16 {
+		return nil, newError(ErrInvalidKey, "cmac: invalid AES-CMAC-96 key size")
+	}
+	return NewWithTagSize(aes.NewCipher, key, 12)
+}
+
+// LookupIKEv2 returns the algorithm of the IKEv2 transform type and ID. It
+// returns ErrUnknownAlgorithm when the package doesn't implement it.
+func LookupIKEv2(transformType, id uint16) (IANAAlgorithm, error) {
+	for _, a := range ikev2Algorithms {
+		if a.transformType == transformType && a.id == id {
+			return a.alg, nil
+		}
+	}
+	return IANAAlgorithm{}, ErrUnknownAlgorithm
+}
+
+// IKEv2ID returns the IKEv2 transform type and ID of the algorithm with the
+// IANA name, e.g. "PRF_AES128_CMAC". It returns ErrUnknownAlgorithm when the
+// package doesn't implement it.
+func IKEv2ID(name string) (transformType, id uint16, err error) {
+	for _, a := range ikev2Algorithms {
+		if a.alg.Name == name {
+			return a.transformType, a.id, nil
+		}
+	}
+	return 0, 0, ErrUnknownAlgorithm
+}
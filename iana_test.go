@@ -0,0 +1,55 @@
+package cmac
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestIKEv2(t *testing.T) {
+	// RFC 4615 test vector with an 18 byte key
+	a, err := LookupIKEv2(IKEv2TransformPRF, 8)
+	if err != nil || a.Name != "PRF_AES128_CMAC" || a.KeySize != 0 || a.TagSize != 16 {
+		t.Fatalf("got %+v, %v", a, err)
+	}
+	key, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0fedcb")
+	msg, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f10111213")
+	h, err := a.New(key)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	h.Write(msg)
+	if tag := hex.EncodeToString(h.Sum(nil)); tag != "84a348a4a45d235babfffc0d2b4da09a" {
+		t.Errorf("got %s", tag)
+	}
+
+	// RFC 4494 test vector
+	a, err = LookupIKEv2(IKEv2TransformIntegrity, 8)
+	if err != nil || a.Name != "AUTH_AES_CMAC_96" || a.KeySize != 16 || a.TagSize != 12 {
+		t.Fatalf("got %+v, %v", a, err)
+	}
+	key, _ = hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	msg, _ = hex.DecodeString("6bc1bee22e409f96e93d7e117393172a")
+	h, _ = a.New(key)
+	h.Write(msg)
+	if tag := hex.EncodeToString(h.Sum(nil)); tag != "070a16b46b4d4144f79bdd9d" || h.Size() != a.TagSize {
+		t.Errorf("got %s", tag)
+	}
+
+	if _, err := a.New(make([]byte, 32)); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidKey)
+	}
+
+	for _, name := range []string{"PRF_AES128_CMAC", "AUTH_AES_CMAC_96"} {
+		typ, id, err := IKEv2ID(name)
+		if a, _ := LookupIKEv2(typ, id); err != nil || a.Name != name {
+			t.Errorf("%s: got %d %d, %v", name, typ, id, err)
+		}
+	}
+	if _, err := LookupIKEv2(IKEv2TransformIntegrity, 5); err != ErrUnknownAlgorithm {
+		t.Errorf("got error %v, expected %v", err, ErrUnknownAlgorithm)
+	}
+	if _, _, err := IKEv2ID("AUTH_AES_XCBC_96"); err != ErrUnknownAlgorithm {
+		t.Errorf("got error %v, expected %v", err, ErrUnknownAlgorithm)
+	}
+}
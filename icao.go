@@ -0,0 +1,101 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+)
+
+/* ICAO Doc 9303 part 11 secure messaging authenticates each APDU with a MAC
+over the padded concatenation of the send sequence counter SSC and the
+protected data M:
+
+   N   = Pad(SSC || M)
+   MAC = RetailMAC(KSmac, N)              with 3DES (BAC), SSC is 8 bytes
+   MAC = first 8 bytes of CMAC(KSmac, N)  with AES (PACE, Chip Authentication), SSC is 16 bytes
+
+The SSC is incremented before each command and each response.
+*/
+
+// RetailMAC returns the ISO/IEC 9797-1 MAC algorithm 3 with DES of the data,
+// whose length must be a multiple of 8. The 16 byte key is Ka || Kb. The
+// data is not padded.
+func RetailMAC(key, data []byte) ([]byte, error) {
+	if len(key) != 16 || len(data) == 0 || len(data)%des.BlockSize != 0 {
+		return nil, newError(ErrInvalidArgument, "cmac: invalid retail MAC input length")
+	}
+	ka, _ := des.NewCipher(key[:8])
+	kb, _ := des.NewCipher(key[8:])
+	h := make([]byte, des.BlockSize)
+	for i := 0; i < len(data); i += des.BlockSize {
+		xor(h, data[i:i+des.BlockSize])
+		ka.Encrypt(h, h)
+	}
+	kb.Decrypt(h, h)
+	ka.Encrypt(h, h)
+	return h, nil
+}
+
+// SecureMessaging computes the ICAO 9303 secure messaging MACs of a session.
+type SecureMessaging struct {
+	key []byte
+	ssc []byte
+	mac cipher.Block
+}
+
+// NewBACMessaging returns the secure messaging of a BAC session with the 16
+// byte 3DES key KSmac and the 8 byte initial SSC.
+func NewBACMessaging(ksMac, ssc []byte) (*SecureMessaging, error) {
+	if len(ksMac) != 16 || len(ssc) != des.BlockSize {
+		return nil, newError(ErrInvalidKey, "cmac: invalid BAC key or SSC size")
+	}
+	return &SecureMessaging{key: append([]byte(nil), ksMac...), ssc: append([]byte(nil), ssc...)}, nil
+}
+
+// NewAESMessaging returns the secure messaging of a PACE or Chip
+// Authentication session with the AES key KSmac and the 16 byte initial SSC.
+func NewAESMessaging(ksMac, ssc []byte) (*SecureMessaging, error) {
+	if len(ssc) != aes.BlockSize {
+		return nil, newError(ErrInvalidArgument, "cmac: invalid SSC size")
+	}
+	c, err := aes.NewCipher(ksMac)
+	if err != nil {
+		return nil, wrapError(ErrInvalidKey, err)
+	}
+	return &SecureMessaging{mac: c, ssc: append([]byte(nil), ssc...)}, nil
+}
+
+// SSC returns a copy of the current send sequence counter.
+func (s *SecureMessaging) SSC() []byte {
+	return append([]byte(nil), s.ssc...)
+}
+
+// MAC increments the SSC and returns the 8 byte MAC of the protected data m,
+// i.e. the padded command header and data objects of a command, or the data
+// objects of a response.
+func (s *SecureMessaging) MAC(m []byte) []byte {
+	for i := len(s.ssc) - 1; i >= 0; i-- {
+		s.ssc[i]++
+		if s.ssc[i] != 0 {
+			break
+		}
+	}
+	n := Pad(append(s.SSC(), m...), len(s.ssc))
+	if s.mac == nil {
+		mac, _ := RetailMAC(s.key, n)
+		return mac
+	}
+	h, _ := New(func([]byte) (cipher.Block, error) { return s.mac, nil }, nil)
+	h.Write(n)
+	return h.Sum(nil)[:8]
+}
+
+// Verify increments the SSC and returns nil if mac is the MAC of the
+// protected data m, and ErrMismatch otherwise.
+func (s *SecureMessaging) Verify(m, mac []byte) error {
+	if !Equal(s.MAC(m), mac) {
+		audit("SecureMessaging.Verify", "", int64(len(m)))
+		return ErrMismatch
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestBACMessaging(t *testing.T) {
+	// ICAO Doc 9303 part 11 appendix D.4, secure messaging worked example
+	key, _ := hex.DecodeString("F1CB1F1FB5ADF208806B89DC579DC1F8")
+	ssc, _ := hex.DecodeString("887022120C06C226")
+	sm, err := NewBACMessaging(key, ssc)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	// SELECT EF.COM: padded header and DO'87'
+	m, _ := hex.DecodeString("0CA4020C80000000" + "8709016375432908C044F6")
+	if got := hex.EncodeToString(sm.MAC(m)); got != "bf8b92d635ff24f8" {
+		t.Errorf("got command MAC %s, expected bf8b92d635ff24f8", got)
+	}
+	// response DO'99'
+	m, _ = hex.DecodeString("99029000")
+	mac, _ := hex.DecodeString("FA855A5D4C50A8ED")
+	if err := sm.Verify(m, mac); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if exp, _ := hex.DecodeString("887022120C06C228"); !bytes.Equal(sm.SSC(), exp) {
+		t.Errorf("got SSC %x, expected %x", sm.SSC(), exp)
+	}
+	if err := sm.Verify(m, mac); err != ErrMismatch {
+		t.Errorf("got error %v, expected %v with a stale SSC", err, ErrMismatch)
+	}
+	if _, err := NewBACMessaging(key[:8], ssc); err == nil {
+		t.Errorf("unexpected nil error for invalid key")
+	}
+	if _, err := RetailMAC(key, m); err == nil {
+		t.Errorf("unexpected nil error for unpadded data")
+	}
+}
+
+func TestAESMessaging(t *testing.T) {
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	ssc := make([]byte, 16)
+	ssc[15] = 0xff
+	sm, err := NewAESMessaging(key, ssc)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	m := []byte("data objects")
+	n := make([]byte, 14, 32)
+	n = append(n, 1, 0)
+	n = append(n, m...)
+	n = append(n, 0x80, 0, 0, 0)
+	h, _ := New(aes.NewCipher, key)
+	h.Write(n)
+	exp := h.Sum(nil)[:8]
+	if got := sm.MAC(m); !bytes.Equal(got, exp) {
+		t.Errorf("got %x, expected %x", got, exp)
+	}
+	if _, err := NewAESMessaging(key, ssc[:8]); err == nil {
+		t.Errorf("unexpected nil error for invalid SSC")
+	}
+	if _, err := NewAESMessaging(key[:5], ssc); err == nil {
+		t.Errorf("unexpected nil error for invalid key")
+	}
+}
@@ -0,0 +1,210 @@
+//go:build !cmac_small
+// +build !cmac_small
+
+package cmac
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"hash"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ErrInvalidJSON is returned when a JSON document can't be canonicalized.
+var ErrInvalidJSON = errors.New("cmac: invalid JSON document")
+
+// CanonicalJSON returns the canonical form of the JSON document doc as
+// defined by the JSON Canonicalization Scheme (RFC 8785). Documents with
+// duplicate object member names or numbers out of the IEEE 754 double
+// precision range are rejected.
+func CanonicalJSON(doc []byte) ([]byte, error) {
+	if !utf8.Valid(doc) {
+		return nil, ErrInvalidJSON
+	}
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	var b bytes.Buffer
+	if err := canonicalizeValue(&b, dec); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, ErrInvalidJSON
+	}
+	return b.Bytes(), nil
+}
+
+// SumJSON returns the CMAC computed with h, which is reset, of the canonical
+// form of the JSON document doc.
+func SumJSON(h hash.Hash, doc []byte) ([]byte, error) {
+	c, err := CanonicalJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	h.Reset()
+	h.Write(c)
+	return h.Sum(nil), nil
+}
+
+// VerifyJSON returns nil if tag is the CMAC computed with h, which is reset,
+// of the canonical form of the JSON document doc, and ErrMismatch otherwise.
+func VerifyJSON(h hash.Hash, doc, tag []byte) error {
+	mac, err := SumJSON(h, doc)
+	if err != nil {
+		return err
+	}
+	if !Equal(mac, tag) {
+		audit("VerifyJSON", "", int64(len(doc)))
+		return ErrMismatch
+	}
+	return nil
+}
+
+func canonicalizeValue(b *bytes.Buffer, dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return ErrInvalidJSON
+	}
+	switch v := tok.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case string:
+		writeJSONString(b, v)
+	case json.Number:
+		s, err := formatJSONNumber(string(v))
+		if err != nil {
+			return err
+		}
+		b.WriteString(s)
+	case json.Delim:
+		if v == '[' {
+			return canonicalizeArray(b, dec)
+		}
+		return canonicalizeObject(b, dec)
+	}
+	return nil
+}
+
+func canonicalizeArray(b *bytes.Buffer, dec *json.Decoder) error {
+	b.WriteByte('[')
+	for i := 0; dec.More(); i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if err := canonicalizeValue(b, dec); err != nil {
+			return err
+		}
+	}
+	dec.Token()
+	b.WriteByte(']')
+	return nil
+}
+
+func canonicalizeObject(b *bytes.Buffer, dec *json.Decoder) error {
+	type member struct {
+		name  string
+		key   []uint16
+		value []byte
+	}
+	var members []member
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return ErrInvalidJSON
+		}
+		name := tok.(string)
+		var v bytes.Buffer
+		if err := canonicalizeValue(&v, dec); err != nil {
+			return err
+		}
+		members = append(members, member{name, utf16.Encode([]rune(name)), v.Bytes()})
+	}
+	dec.Token()
+	// member names are sorted by their UTF-16 code units
+	sort.Slice(members, func(i, j int) bool {
+		a, c := members[i].key, members[j].key
+		for k := 0; k < len(a) && k < len(c); k++ {
+			if a[k] != c[k] {
+				return a[k] < c[k]
+			}
+		}
+		return len(a) < len(c)
+	})
+	b.WriteByte('{')
+	for i, m := range members {
+		if i > 0 {
+			if m.name == members[i-1].name {
+				return ErrInvalidJSON
+			}
+			b.WriteByte(',')
+		}
+		writeJSONString(b, m.name)
+		b.WriteByte(':')
+		b.Write(m.value)
+	}
+	b.WriteByte('}')
+	return nil
+}
+
+// writeJSONString writes s as serialized by ECMAScript JSON.stringify.
+func writeJSONString(b *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				b.WriteString(`\u00`)
+				b.WriteByte(hex[r>>4])
+				b.WriteByte(hex[r&0xf])
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+}
+
+// formatJSONNumber returns the number formatted as by ECMAScript
+// Number.prototype.toString.
+func formatJSONNumber(s string) (string, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return "", ErrInvalidJSON
+	}
+	if f == 0 {
+		return "0", nil
+	}
+	if abs := math.Abs(f); abs >= 1e-6 && abs < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	}
+	s = strconv.FormatFloat(f, 'e', -1, 64)
+	i := strings.IndexByte(s, 'e')
+	exp, _ := strconv.Atoi(s[i+1:])
+	if exp < 0 {
+		return s[:i] + "e-" + strconv.Itoa(-exp), nil
+	}
+	return s[:i] + "e+" + strconv.Itoa(exp), nil
+}
@@ -0,0 +1,66 @@
+//go:build !cmac_small
+// +build !cmac_small
+
+package cmac
+
+import (
+	"crypto/aes"
+	"testing"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		// RFC 8785 section 3.2.2
+		{
+			in: `{
+  "numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+  "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+  "literals": [null, true, false]
+}`,
+			out: `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`,
+		},
+		// RFC 8785 section 3.2.3
+		{
+			in:  `{"\u20ac":"Euro Sign","\r":"Carriage Return","\ufb33":"Hebrew Letter Dalet With Dagesh","1":"One","\ud83d\ude00":"Emoji: Grinning Face","\u0080":"Control","\u00f6":"Latin Small Letter O With Diaeresis"}`,
+			out: "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"\u00f6\":\"Latin Small Letter O With Diaeresis\",\"\u20ac\":\"Euro Sign\",\"\U0001f600\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}",
+		},
+		{in: `[0, -0, 1e21, 1e-6, 1e-7, 123456789012345680000, -1.5, 5e-324]`, out: `[0,0,1e+21,0.000001,1e-7,123456789012345680000,-1.5,5e-324]`},
+		{in: ` "a\tb" `, out: `"a\tb"`},
+		{in: `{"a":{"c":1,"b":[]},"":{}}`, out: `{"":{},"a":{"b":[],"c":1}}`},
+	}
+	for i, test := range tests {
+		out, err := CanonicalJSON([]byte(test.in))
+		if err != nil {
+			t.Errorf("%d: unexpected error: %v", i, err)
+			continue
+		}
+		if string(out) != test.out {
+			t.Errorf("%d: got\n   %s\nexpected\n   %s", i, out, test.out)
+		}
+	}
+
+	for i, bad := range []string{``, `{`, `[1,]`, `{"a":1,"a":2}`, `1e400`, `{} {}`, "\"\xff\""} {
+		if _, err := CanonicalJSON([]byte(bad)); err != ErrInvalidJSON {
+			t.Errorf("%d: got error %v, expected %v", i, err, ErrInvalidJSON)
+		}
+	}
+}
+
+func TestSumJSON(t *testing.T) {
+	h, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	tag, err := SumJSON(h, []byte(`{"b": 1.0, "a": "x"}`))
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if err := VerifyJSON(h, []byte(`{"a":"x","b":1}`), tag); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := VerifyJSON(h, []byte(`{"a":"x","b":2}`), tag); err != ErrMismatch {
+		t.Errorf("got error %v, expected %v", err, ErrMismatch)
+	}
+	if err := VerifyJSON(h, []byte(`{"a":`), tag); err != ErrInvalidJSON {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidJSON)
+	}
+}
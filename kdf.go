@@ -0,0 +1,46 @@
+package cmac
+
+import (
+	"encoding/binary"
+	"hash"
+)
+
+/* DeriveKey implements the KDF in counter mode of NIST SP 800-108 with CMAC
+as PRF. With big endian 32 bit unsigned integers, the derived key is the
+first length bytes of K(1) || K(2) || ... where
+
+   K(i) = CMAC(KI, uint32(i) || label || 0x00 || context || uint32(8*length))
+*/
+
+// DeriveKey returns length bytes derived from the key derivation key kdk
+// with the label and context, as specified by NIST SP 800-108 in counter
+// mode with CMAC as PRF.
+func DeriveKey(newCipher NewCipherFunc, kdk, label, context []byte, length int) ([]byte, error) {
+	if length <= 0 || uint64(length) > 0x1fffffff {
+		return nil, newError(ErrInvalidArgument, "cmac: invalid derived key length")
+	}
+	h, err := New(newCipher, kdk)
+	if err != nil {
+		return nil, err
+	}
+	return deriveKey(h, make([]byte, 0, length+h.Size()), label, context, length), nil
+}
+
+// deriveKey appends the length bytes derived with the CMAC h of the key
+// derivation key to dst.
+func deriveKey(h hash.Hash, dst, label, context []byte, length int) []byte {
+	var b [4]byte
+	out := dst
+	for i := uint32(1); len(out)-len(dst) < length; i++ {
+		h.Reset()
+		binary.BigEndian.PutUint32(b[:], i)
+		h.Write(b[:])
+		h.Write(label)
+		h.Write([]byte{0})
+		h.Write(context)
+		binary.BigEndian.PutUint32(b[:], uint32(8*length))
+		h.Write(b[:])
+		out = h.Sum(out)
+	}
+	return out[:len(dst)+length]
+}
@@ -0,0 +1,83 @@
+package cmac
+
+import (
+	"encoding/binary"
+)
+
+/* NIST SP 800-56C rev2 doesn't define CMAC as auxiliary function of the
+one-step KDF, but its two-step KDF (extraction-then-expansion) supports it:
+
+   K_DK = CMAC(salt, Z)
+   K(i) = CMAC(K_DK, uint32(i) || FixedInfo)
+   DKM  = first L bytes of K(1) || K(2) || ...
+
+The expansion is the SP 800-108 counter mode KDF with FixedInfo as fixed
+input data, and i a big endian 32 bit unsigned integer. The AES key size of
+the extraction is the salt length, which defaults to 16 zero bytes. K_DK
+being 16 bytes long, the expansion uses AES-128.
+
+FixedInfo.Bytes returns the concatenation format of SP 800-56A rev3:
+
+   uint32(len(AlgorithmID)) || AlgorithmID ||
+   uint32(len(PartyUInfo)) || PartyUInfo ||
+   uint32(len(PartyVInfo)) || PartyVInfo ||
+   SuppPubInfo || SuppPrivInfo
+*/
+
+// FixedInfo is the context of a key derived from a shared secret.
+type FixedInfo struct {
+	AlgorithmID  []byte // identifies the use of the derived key
+	PartyUInfo   []byte // information on the initiator, e.g. its ID and nonce
+	PartyVInfo   []byte // information on the responder
+	SuppPubInfo  []byte // optional fixed length public information
+	SuppPrivInfo []byte // optional fixed length private information
+}
+
+// Bytes returns the concatenation format encoding of the fixed info.
+func (f FixedInfo) Bytes() []byte {
+	b := make([]byte, 0, 12+len(f.AlgorithmID)+len(f.PartyUInfo)+len(f.PartyVInfo)+
+		len(f.SuppPubInfo)+len(f.SuppPrivInfo))
+	var l [4]byte
+	for _, v := range [][]byte{f.AlgorithmID, f.PartyUInfo, f.PartyVInfo} {
+		binary.BigEndian.PutUint32(l[:], uint32(len(v)))
+		b = append(b, l[:]...)
+		b = append(b, v...)
+	}
+	b = append(b, f.SuppPubInfo...)
+	return append(b, f.SuppPrivInfo...)
+}
+
+// TwoStepKDF derives length bytes from the shared secret z with the two-step
+// KDF of NIST SP 800-56C rev2 using AES-CMAC for the extraction and the
+// expansion. A nil salt is replaced with 16 zero bytes, otherwise its length
+// must be a valid AES key size.
+func TwoStepKDF(salt, z, fixedInfo []byte, length int) ([]byte, error) {
+	if length <= 0 || uint64(length) > 0x1fffffff {
+		return nil, newError(ErrInvalidArgument, "cmac: invalid derived key length")
+	}
+	if salt == nil {
+		salt = make([]byte, 16)
+	}
+	alg := AES128
+	for ; alg.Valid() && alg.KeySize() != len(salt); alg++ {
+	}
+	h, err := alg.New(salt)
+	if err != nil {
+		return nil, newError(ErrInvalidKey, "cmac: invalid salt size")
+	}
+	h.Write(z)
+	kdk := h.Sum(nil)
+	if h, err = AES128.New(kdk); err != nil {
+		return nil, err
+	}
+	var ctr [4]byte
+	out := make([]byte, 0, length+h.Size())
+	for i := uint32(1); len(out) < length; i++ {
+		h.Reset()
+		binary.BigEndian.PutUint32(ctr[:], i)
+		h.Write(ctr[:])
+		h.Write(fixedInfo)
+		out = h.Sum(out)
+	}
+	return out[:length], nil
+}
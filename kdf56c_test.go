@@ -0,0 +1,62 @@
+package cmac
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestFixedInfo(t *testing.T) {
+	f := FixedInfo{
+		AlgorithmID: []byte("A"),
+		PartyUInfo:  []byte("UU"),
+		SuppPubInfo: []byte{0, 0, 0, 128},
+	}
+	exp := "0000000141" + "000000025555" + "00000000" + "00000080"
+	if got := hex.EncodeToString(f.Bytes()); got != exp {
+		t.Errorf("got %s, expected %s", got, exp)
+	}
+}
+
+func TestTwoStepKDF(t *testing.T) {
+	z, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c6bc1bee22e409f96")
+	salt, _ := hex.DecodeString("603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4")
+	info := FixedInfo{AlgorithmID: []byte("AES-128-GCM")}.Bytes()
+
+	for _, s := range [][]byte{nil, salt[:16], salt[:24], salt} {
+		h, _ := AES128.New(make([]byte, 16))
+		if s != nil {
+			for _, a := range []Algorithm{AES128, AES192, AES256} {
+				if a.KeySize() == len(s) {
+					h, _ = a.New(s)
+				}
+			}
+		}
+		h.Write(z)
+		kdk := h.Sum(nil)
+		var exp []byte
+		h, _ = AES128.New(kdk)
+		for i := byte(1); i <= 2; i++ {
+			h.Reset()
+			h.Write([]byte{0, 0, 0, i})
+			h.Write(info)
+			exp = h.Sum(exp)
+		}
+		exp = exp[:24]
+
+		k, err := TwoStepKDF(s, z, info, 24)
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		if !bytes.Equal(k, exp) {
+			t.Errorf("salt %x: got %x, expected %x", s, k, exp)
+		}
+	}
+
+	if _, err := TwoStepKDF(salt[:10], z, info, 16); err == nil {
+		t.Errorf("unexpected nil error for invalid salt size")
+	}
+	if _, err := TwoStepKDF(nil, z, info, 0); err == nil {
+		t.Errorf("unexpected nil error for zero length")
+	}
+}
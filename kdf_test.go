@@ -0,0 +1,47 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestDeriveKey(t *testing.T) {
+	kdk, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	label, context := []byte("label"), []byte("context")
+
+	var exp []byte
+	h, _ := New(aes.NewCipher, kdk)
+	for i := byte(1); i <= 2; i++ {
+		h.Reset()
+		h.Write([]byte{0, 0, 0, i})
+		h.Write([]byte("label\x00context"))
+		h.Write([]byte{0, 0, 0, 160})
+		exp = h.Sum(exp)
+	}
+	exp = exp[:20]
+
+	k, err := DeriveKey(aes.NewCipher, kdk, label, context, 20)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if !bytes.Equal(k, exp) {
+		t.Errorf("got %x, expected %x", k, exp)
+	}
+	k2, _ := DeriveKey(aes.NewCipher, kdk, label, context, 16)
+	if bytes.Equal(k2, k[:16]) {
+		t.Errorf("keys of different length must be independent")
+	}
+	k2, _ = DeriveKey(aes.NewCipher, kdk, []byte("label\x00"), []byte("ontext"), 20)
+	if bytes.Equal(k2, k) {
+		t.Errorf("label and context must be separated")
+	}
+
+	if _, err := DeriveKey(aes.NewCipher, kdk, label, context, 0); err == nil {
+		t.Errorf("unexpected nil error for zero length")
+	}
+	if _, err := DeriveKey(aes.NewCipher, kdk[:3], label, context, 16); err == nil {
+		t.Errorf("unexpected nil error for invalid key")
+	}
+}
@@ -0,0 +1,102 @@
+package cmac
+
+import (
+	"errors"
+	"hash"
+	"strings"
+	"sync"
+)
+
+/* The key of a path "a/b/c" in a KeyTree is derived from the root key with
+
+   K(a)     = DeriveKey(root, "cmac-go key tree", "a", len(root))
+   K(a/b)   = DeriveKey(K(a), "cmac-go key tree", "b", len(root))
+   K(a/b/c) = DeriveKey(K(a/b), "cmac-go key tree", "c", len(root))
+*/
+
+const keyTreeLabel = "cmac-go key tree"
+
+// ErrInvalidPath is returned for an empty path or a path with an empty element.
+var ErrInvalidPath = errors.New("cmac: invalid key path")
+
+// KeyTree derives keys from a root key along slash separated paths like
+// "app/v1/device/1234". Derived keys are cached, so that keys sharing a
+// path prefix only derive it once. A KeyTree is safe for concurrent use.
+type KeyTree struct {
+	newCipher NewCipherFunc
+	root      []byte
+	mu        sync.Mutex
+	cache     map[string][]byte
+}
+
+// NewKeyTree returns a key tree with the root key.
+func NewKeyTree(newCipher NewCipherFunc, root []byte) (*KeyTree, error) {
+	if _, err := newCipher(root); err != nil {
+		return nil, wrapError(ErrInvalidKey, err)
+	}
+	return &KeyTree{
+		newCipher: newCipher,
+		root:      append([]byte(nil), root...),
+		cache:     make(map[string][]byte),
+	}, nil
+}
+
+// Key returns a copy of the key of the path, which may be exported.
+func (t *KeyTree) Key(path string) ([]byte, error) {
+	return t.derive(path)
+}
+
+// Hash returns a new CMAC hash with the key of the path.
+func (t *KeyTree) Hash(path string) (hash.Hash, error) {
+	k, err := t.derive(path)
+	if err != nil {
+		return nil, err
+	}
+	h, err := New(t.newCipher, k)
+	zero(k)
+	return h, err
+}
+
+// Purge empties the cache of derived keys.
+func (t *KeyTree) Purge() {
+	t.mu.Lock()
+	for p, k := range t.cache {
+		zero(k)
+		delete(t.cache, p)
+	}
+	t.mu.Unlock()
+}
+
+// derive returns a copy of the cached key of the path, deriving it and the
+// missing keys of its prefixes. The copy is made with the lock held, since
+// Purge zeroes the cached keys.
+func (t *KeyTree) derive(path string) ([]byte, error) {
+	elems := strings.Split(path, "/")
+	for _, e := range elems {
+		if e == "" {
+			return nil, ErrInvalidPath
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if k, ok := t.cache[path]; ok {
+		return append([]byte(nil), k...), nil
+	}
+	// find the longest cached prefix
+	i, k := len(elems)-1, t.root
+	for ; i > 0; i-- {
+		if c, ok := t.cache[strings.Join(elems[:i], "/")]; ok {
+			k = c
+			break
+		}
+	}
+	for ; i < len(elems); i++ {
+		c, err := DeriveKey(t.newCipher, k, []byte(keyTreeLabel), []byte(elems[i]), len(t.root))
+		if err != nil {
+			return nil, err
+		}
+		t.cache[strings.Join(elems[:i+1], "/")] = c
+		k = c
+	}
+	return append([]byte(nil), k...), nil
+}
@@ -0,0 +1,110 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"sync"
+	"testing"
+)
+
+func TestKeyTree(t *testing.T) {
+	root, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	label := []byte("cmac-go key tree")
+	ka, _ := DeriveKey(aes.NewCipher, root, label, []byte("app"), 16)
+	kb, _ := DeriveKey(aes.NewCipher, ka, label, []byte("v1"), 16)
+	kc, _ := DeriveKey(aes.NewCipher, kb, label, []byte("1234"), 16)
+
+	kt, err := NewKeyTree(aes.NewCipher, root)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	for i := 0; i < 2; i++ {
+		if k, err := kt.Key("app/v1/1234"); err != nil || !bytes.Equal(k, kc) {
+			t.Errorf("%d: got %x %v, expected %x", i, k, err, kc)
+		}
+		if k, err := kt.Key("app/v1"); err != nil || !bytes.Equal(k, kb) {
+			t.Errorf("%d: got %x %v, expected %x", i, k, err, kb)
+		}
+		if k, err := kt.Key("app"); err != nil || !bytes.Equal(k, ka) {
+			t.Errorf("%d: got %x %v, expected %x", i, k, err, ka)
+		}
+		kt.Purge()
+	}
+	kt.Key("app/v1")
+	if k, _ := kt.Key("app/v1/1234"); !bytes.Equal(k, kc) {
+		t.Errorf("got %x with cached prefix, expected %x", k, kc)
+	}
+	k, _ := kt.Key("app")
+	k[0] ^= 1
+	if k, _ := kt.Key("app"); !bytes.Equal(k, ka) {
+		t.Errorf("cached key modified by caller")
+	}
+
+	h, err := kt.Hash("app/v1")
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	h2, _ := New(aes.NewCipher, kb)
+	if !bytes.Equal(h.Sum(nil), h2.Sum(nil)) {
+		t.Errorf("hash key mismatch")
+	}
+
+	for _, p := range []string{"", "/app", "app/", "app//v1"} {
+		if _, err := kt.Key(p); err != ErrInvalidPath {
+			t.Errorf("%q: got error %v, expected %v", p, err, ErrInvalidPath)
+		}
+	}
+	if _, err := NewKeyTree(aes.NewCipher, root[:3]); err == nil {
+		t.Errorf("unexpected nil error for invalid root key")
+	}
+}
+
+func TestKeyTreeConcurrentPurge(t *testing.T) {
+	root, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	tree, _ := NewKeyTree(aes.NewCipher, root)
+	k, _ := tree.Key("app/v1")
+	h, _ := New(aes.NewCipher, k)
+	h.Write([]byte("message"))
+	expected := h.Sum(nil)
+
+	done := make(chan struct{})
+	purged := make(chan struct{})
+	go func() {
+		defer close(purged)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				tree.Purge()
+			}
+		}
+	}()
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				h, err := tree.Hash("app/v1")
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+				h.Write([]byte("message"))
+				if !bytes.Equal(h.Sum(nil), expected) {
+					t.Errorf("tag mismatch with concurrent Purge")
+					return
+				}
+				if key, _ := tree.Key("app/v1"); !bytes.Equal(key, k) {
+					t.Errorf("key mismatch with concurrent Purge")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	<-purged
+}
@@ -0,0 +1,164 @@
+/*
+Package keywrap implements the AES Key Wrap algorithm of RFC 3394 and the
+AES Key Wrap with Padding algorithm of RFC 5649, also specified by NIST
+special publication 800-38F as KW and KWP.
+*/
+package keywrap
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+
+	"github.com/chmike/cmac-go"
+)
+
+var (
+	// ErrInvalidInput is returned when the key to wrap or the wrapped key
+	// has an invalid length.
+	ErrInvalidInput = errors.New("keywrap: invalid input length")
+
+	// ErrUnwrap is returned when the integrity check of a wrapped key fails.
+	ErrUnwrap = errors.New("keywrap: integrity check failed")
+)
+
+var defaultIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+const paddedIV = 0xa65959a6
+
+// Wrap returns the key wrapped with the key encryption key kek as specified
+// by RFC 3394. The key length must be a multiple of 8 and at least 16.
+func Wrap(newCipher cmac.NewCipherFunc, kek, key []byte) ([]byte, error) {
+	if len(key) < 16 || len(key)%8 != 0 {
+		return nil, ErrInvalidInput
+	}
+	c, err := newBlock(newCipher, kek)
+	if err != nil {
+		return nil, err
+	}
+	return wrap(c, defaultIV, key), nil
+}
+
+// Unwrap returns the key unwrapped with the key encryption key kek as
+// specified by RFC 3394.
+func Unwrap(newCipher cmac.NewCipherFunc, kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < 24 || len(wrapped)%8 != 0 {
+		return nil, ErrInvalidInput
+	}
+	c, err := newBlock(newCipher, kek)
+	if err != nil {
+		return nil, err
+	}
+	iv, key := unwrap(c, wrapped)
+	if subtle.ConstantTimeCompare(iv, defaultIV) != 1 {
+		return nil, ErrUnwrap
+	}
+	return key, nil
+}
+
+// WrapPad returns the key wrapped with the key encryption key kek as
+// specified by RFC 5649. The key may have any non zero length.
+func WrapPad(newCipher cmac.NewCipherFunc, kek, key []byte) ([]byte, error) {
+	if len(key) == 0 || uint64(len(key)) > 0xffffffff {
+		return nil, ErrInvalidInput
+	}
+	c, err := newBlock(newCipher, kek)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, 8)
+	binary.BigEndian.PutUint32(iv, paddedIV)
+	binary.BigEndian.PutUint32(iv[4:], uint32(len(key)))
+	p := make([]byte, (len(key)+7)/8*8)
+	copy(p, key)
+	if len(p) == 8 {
+		b := append(iv, p...)
+		c.Encrypt(b, b)
+		return b, nil
+	}
+	return wrap(c, iv, p), nil
+}
+
+// UnwrapPad returns the key unwrapped with the key encryption key kek as
+// specified by RFC 5649.
+func UnwrapPad(newCipher cmac.NewCipherFunc, kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < 16 || len(wrapped)%8 != 0 {
+		return nil, ErrInvalidInput
+	}
+	c, err := newBlock(newCipher, kek)
+	if err != nil {
+		return nil, err
+	}
+	var iv, p []byte
+	if len(wrapped) == 16 {
+		b := make([]byte, 16)
+		c.Decrypt(b, wrapped)
+		iv, p = b[:8], b[8:]
+	} else {
+		iv, p = unwrap(c, wrapped)
+	}
+	n := int(binary.BigEndian.Uint32(iv[4:]))
+	if binary.BigEndian.Uint32(iv) != paddedIV || n <= len(p)-8 || n > len(p) {
+		return nil, ErrUnwrap
+	}
+	var pad byte
+	for _, v := range p[n:] {
+		pad |= v
+	}
+	if pad != 0 {
+		return nil, ErrUnwrap
+	}
+	return p[:n], nil
+}
+
+// newBlock returns the cipher of kek, which must have a 16 byte block size.
+func newBlock(newCipher cmac.NewCipherFunc, kek []byte) (cipher.Block, error) {
+	c, err := newCipher(kek)
+	if err != nil {
+		return nil, &cmac.Error{Kind: cmac.ErrInvalidKey, Err: err}
+	}
+	if c.BlockSize() != 16 {
+		return nil, &cmac.Error{Kind: cmac.ErrInvalidArgument, Err: errors.New("keywrap: cipher block size must be 16")}
+	}
+	return c, nil
+}
+
+// wrap returns the wrapping of p with the initial value iv.
+func wrap(c cipher.Block, iv, p []byte) []byte {
+	n := len(p) / 8
+	out := make([]byte, 8+len(p))
+	copy(out[8:], p)
+	var b [16]byte
+	copy(b[:8], iv)
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(b[8:], out[8*i:])
+			c.Encrypt(b[:], b[:])
+			t := binary.BigEndian.Uint64(b[:8]) ^ uint64(n*j+i)
+			binary.BigEndian.PutUint64(b[:8], t)
+			copy(out[8*i:], b[8:])
+		}
+	}
+	copy(out, b[:8])
+	return out
+}
+
+// unwrap returns the initial value and the plaintext of the wrapped bytes.
+func unwrap(c cipher.Block, wrapped []byte) (iv, p []byte) {
+	n := len(wrapped)/8 - 1
+	out := make([]byte, len(wrapped))
+	copy(out, wrapped)
+	var b [16]byte
+	copy(b[:8], out[:8])
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := binary.BigEndian.Uint64(b[:8]) ^ uint64(n*j+i)
+			binary.BigEndian.PutUint64(b[:8], t)
+			copy(b[8:], out[8*i:])
+			c.Decrypt(b[:], b[:])
+			copy(out[8*i:], b[8:])
+		}
+	}
+	return b[:8], out[8:]
+}
@@ -0,0 +1,136 @@
+package keywrap
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	// RFC 3394 section 4
+	tests := []struct {
+		kek, key, wrapped string
+	}{
+		{
+			"000102030405060708090a0b0c0d0e0f",
+			"00112233445566778899aabbccddeeff",
+			"1fa68b0a8112b447aef34bd8fb5a7b829d3e862371d2cfe5",
+		},
+		{
+			"000102030405060708090a0b0c0d0e0f1011121314151617",
+			"00112233445566778899aabbccddeeff",
+			"96778b25ae6ca435f92b5b97c050aed2468ab8a17ad84e5d",
+		},
+		{
+			"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+			"00112233445566778899aabbccddeeff",
+			"64e8c3f9ce0f5ba263e9777905818a2a93c8191e7d6e8ae7",
+		},
+		{
+			"000102030405060708090a0b0c0d0e0f1011121314151617",
+			"00112233445566778899aabbccddeeff0001020304050607",
+			"031d33264e15d33268f24ec260743edce1c6c7ddee725a936ba814915c6762d2",
+		},
+		{
+			"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+			"00112233445566778899aabbccddeeff0001020304050607",
+			"a8f9bc1612c68b3ff6e6f4fbe30e71e4769c8b80a32cb8958cd5d17d6b254da1",
+		},
+		{
+			"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+			"00112233445566778899aabbccddeeff000102030405060708090a0b0c0d0e0f",
+			"28c9f404c4b810f4cbccb35cfb87f8263f5786e2d80ed326cbc7f0e71a99f43bfb988b9b7a02dd21",
+		},
+	}
+	for i, tc := range tests {
+		kek, _ := hex.DecodeString(tc.kek)
+		key, _ := hex.DecodeString(tc.key)
+		exp, _ := hex.DecodeString(tc.wrapped)
+		w, err := Wrap(aes.NewCipher, kek, key)
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+		if !bytes.Equal(w, exp) {
+			t.Errorf("%d: got %x, expected %x", i, w, exp)
+		}
+		k, err := Unwrap(aes.NewCipher, kek, w)
+		if err != nil || !bytes.Equal(k, key) {
+			t.Errorf("%d: got %x %v, expected %x", i, k, err, key)
+		}
+		w[3] ^= 1
+		if _, err := Unwrap(aes.NewCipher, kek, w); err != ErrUnwrap {
+			t.Errorf("%d: got error %v, expected %v", i, err, ErrUnwrap)
+		}
+	}
+
+	kek := make([]byte, 16)
+	for _, n := range []int{0, 8, 17} {
+		if _, err := Wrap(aes.NewCipher, kek, make([]byte, n)); err != ErrInvalidInput {
+			t.Errorf("%d: got error %v, expected %v", n, err, ErrInvalidInput)
+		}
+	}
+	for _, n := range []int{16, 25} {
+		if _, err := Unwrap(aes.NewCipher, kek, make([]byte, n)); err != ErrInvalidInput {
+			t.Errorf("%d: got error %v, expected %v", n, err, ErrInvalidInput)
+		}
+	}
+	if _, err := Wrap(aes.NewCipher, kek[:3], make([]byte, 16)); err == nil {
+		t.Errorf("unexpected nil error for invalid kek")
+	}
+}
+
+func TestWrapPad(t *testing.T) {
+	// RFC 5649 section 6
+	kek, _ := hex.DecodeString("5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8")
+	tests := []struct {
+		key, wrapped string
+	}{
+		{
+			"c37b7e6492584340bed12207808941155068f738",
+			"138bdeaa9b8fa7fc61f97742e72248ee5ae6ae5360d1ae6a5f54f373fa543b6a",
+		},
+		{
+			"466f7250617369",
+			"afbeb0f07dfbf5419200f2ccb50bb24f",
+		},
+	}
+	for i, tc := range tests {
+		key, _ := hex.DecodeString(tc.key)
+		exp, _ := hex.DecodeString(tc.wrapped)
+		w, err := WrapPad(aes.NewCipher, kek, key)
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+		if !bytes.Equal(w, exp) {
+			t.Errorf("%d: got %x, expected %x", i, w, exp)
+		}
+		k, err := UnwrapPad(aes.NewCipher, kek, w)
+		if err != nil || !bytes.Equal(k, key) {
+			t.Errorf("%d: got %x %v, expected %x", i, k, err, key)
+		}
+		w[len(w)-1] ^= 1
+		if _, err := UnwrapPad(aes.NewCipher, kek, w); err != ErrUnwrap {
+			t.Errorf("%d: got error %v, expected %v", i, err, ErrUnwrap)
+		}
+	}
+
+	for n := 1; n <= 33; n++ {
+		key := bytes.Repeat([]byte{byte(n)}, n)
+		w, _ := WrapPad(aes.NewCipher, kek, key)
+		if k, err := UnwrapPad(aes.NewCipher, kek, w); err != nil || !bytes.Equal(k, key) {
+			t.Errorf("%d: got %x %v, expected %x", n, k, err, key)
+		}
+	}
+	// a RFC 3394 wrapped key is not a valid padded wrapped key
+	w, _ := Wrap(aes.NewCipher, kek, make([]byte, 16))
+	if _, err := UnwrapPad(aes.NewCipher, kek, w); err != ErrUnwrap {
+		t.Errorf("got error %v, expected %v", err, ErrUnwrap)
+	}
+	if _, err := WrapPad(aes.NewCipher, kek, nil); err != ErrInvalidInput {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidInput)
+	}
+	if _, err := UnwrapPad(aes.NewCipher, kek, make([]byte, 8)); err != ErrInvalidInput {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidInput)
+	}
+}
@@ -0,0 +1,297 @@
+package cmac
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+	"io/fs"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+/* The manifest tag is the CMAC of the following encoding of the manifest,
+where integers are big endian:
+
+   algorithm byte || uint32(len(key ID)) || key ID || uint64(chunk size) || uint64(number of files)
+   for each file:
+      uint32(len(path)) || path || uint64(size) || uint32(number of chunks)
+      for each chunk: uint32(len(chunk tag)) || chunk tag
+      uint32(len(tag)) || tag
+*/
+
+// Manifest holds the tags of a set of files. The file tags are computed as
+// for a Sidecar with the manifest algorithm and chunk size. Tag authenticates
+// the whole manifest.
+type Manifest struct {
+	Algorithm Algorithm       `json:"algorithm"`
+	KeyID     string          `json:"key_id,omitempty"`
+	ChunkSize int64           `json:"chunk_size,omitempty"`
+	Files     []ManifestEntry `json:"files"`
+	Tag       []byte          `json:"tag"`
+}
+
+// ManifestEntry holds the tags of a file. Path is slash separated and
+// relative to the root of the file set, as for io/fs.
+type ManifestEntry struct {
+	Path   string   `json:"path"`
+	Size   int64    `json:"size"`
+	Chunks [][]byte `json:"chunks,omitempty"`
+	Tag    []byte   `json:"tag"`
+}
+
+// ManifestOptions are the options of BuildManifest and Manifest.Verify.
+type ManifestOptions struct {
+	// KeyID is the key ID stored in the built manifest.
+	KeyID string
+
+	// ChunkSize is the chunk size of the built manifest. Files are not
+	// chunked when zero.
+	ChunkSize int64
+
+	// Workers is the number of files processed concurrently. It is
+	// runtime.GOMAXPROCS(0) when zero.
+	Workers int
+
+	// Progress, when not nil, is called after each file is processed with
+	// the error of the file, or nil. The calls are serialized.
+	Progress func(path string, err error)
+}
+
+// ManifestError is the error of a file of a manifest.
+type ManifestError struct {
+	Path string
+	Err  error
+}
+
+func (e *ManifestError) Error() string { return "cmac: " + e.Path + ": " + e.Err.Error() }
+
+// Unwrap returns the error of the file.
+func (e *ManifestError) Unwrap() error { return e.Err }
+
+// Lookup returns the entry with the given path, or nil if there is none.
+// The files must be sorted by path.
+func (m *Manifest) Lookup(path string) *ManifestEntry {
+	i := sort.Search(len(m.Files), func(i int) bool { return m.Files[i].Path >= path })
+	if i < len(m.Files) && m.Files[i].Path == path {
+		return &m.Files[i]
+	}
+	return nil
+}
+
+// BuildManifest returns the manifest of the regular files of fsys with the
+// algorithm and key. The files are sorted by path. Other file types are
+// ignored. The options may be nil.
+func BuildManifest(fsys fs.FS, alg Algorithm, key []byte, opts *ManifestOptions) (*Manifest, error) {
+	if opts == nil {
+		opts = &ManifestOptions{}
+	}
+	h, err := alg.New(key)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{Algorithm: alg, KeyID: opts.KeyID, ChunkSize: opts.ChunkSize}
+	err = fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			m.Files = append(m.Files, ManifestEntry{Path: path})
+		}
+		return err
+	})
+	if err != nil {
+		return nil, wrapError(ErrIO, err)
+	}
+	errs := m.forEach(context.Background(), fsys, opts, func(e *ManifestEntry, r *countingReader) error {
+		s, err := NewSidecar(alg, key, "", r, m.ChunkSize)
+		if err == nil {
+			e.Size, e.Chunks, e.Tag = r.n, s.Chunks, s.Tag
+		}
+		return err
+	})
+	for i, err := range errs {
+		if err != nil {
+			return nil, &ManifestError{Path: m.Files[i].Path, Err: err}
+		}
+	}
+	m.Tag = m.sum(h)
+	return m, nil
+}
+
+// Verify returns nil if the manifest tag is valid and the files of the
+// manifest in fsys match their tags. It returns ErrMismatch when the manifest
+// tag is invalid, or the *ManifestError of the first file in path order that
+// can't be read or doesn't match. The options may be nil. Only the Workers
+// and Progress options are used.
+func (m *Manifest) Verify(fsys fs.FS, key []byte, opts *ManifestOptions) error {
+	r, err := m.VerifyReport(context.Background(), fsys, key, opts)
+	if err != nil {
+		return err
+	}
+	return r.Err()
+}
+
+// ManifestReport is the result of the verification of the files of a
+// manifest.
+type ManifestReport struct {
+	// Files is the number of files verified, successfully or not.
+	Files int
+
+	// Bytes is the number of bytes read.
+	Bytes int64
+
+	// Errors are the errors of the files that can't be read or don't
+	// match, in path order.
+	Errors []*ManifestError
+}
+
+// Err returns the first error of the report, or nil when all files match.
+func (r *ManifestReport) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	return r.Errors[0]
+}
+
+// VerifyReport verifies the files of the manifest in fsys, as Verify, and
+// returns the report of all files instead of stopping at the first error.
+// It returns ErrMismatch when the manifest tag is invalid, and the context
+// error with the report of the files verified so far when ctx is done. The
+// options may be nil. Only the Workers and Progress options are used.
+func (m *Manifest) VerifyReport(ctx context.Context, fsys fs.FS, key []byte, opts *ManifestOptions) (*ManifestReport, error) {
+	if opts == nil {
+		opts = &ManifestOptions{}
+	}
+	h, err := m.Algorithm.New(key)
+	if err != nil {
+		return nil, err
+	}
+	if !Equal(m.sum(h), m.Tag) {
+		audit("Manifest.Verify", m.KeyID, -1)
+		return nil, ErrMismatch
+	}
+	var read int64
+	errs := m.forEach(ctx, fsys, opts, func(e *ManifestEntry, r *countingReader) error {
+		s := Sidecar{Algorithm: m.Algorithm, KeyID: m.KeyID, ChunkSize: m.ChunkSize, Chunks: e.Chunks, Tag: e.Tag}
+		err := s.Verify(key, r)
+		atomic.AddInt64(&read, r.n)
+		if err != nil {
+			return err
+		}
+		if r.n != e.Size {
+			audit("Manifest.Verify", m.KeyID, r.n)
+			return ErrMismatch
+		}
+		return nil
+	})
+	report := &ManifestReport{Bytes: read}
+	for i, err := range errs {
+		if err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+			continue
+		}
+		report.Files++
+		if err != nil {
+			report.Errors = append(report.Errors, &ManifestError{Path: m.Files[i].Path, Err: err})
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// forEach calls fn concurrently with each file entry and a reader of its
+// content, and returns the error of each file. Files are skipped with the
+// context error, and reads fail with it, once ctx is done.
+func (m *Manifest) forEach(ctx context.Context, fsys fs.FS, opts *ManifestOptions, fn func(*ManifestEntry, *countingReader) error) []error {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	errs := make([]error, len(m.Files))
+	idx := make(chan int)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range idx {
+				e := &m.Files[i]
+				err := ctx.Err()
+				if err == nil {
+					err = processManifestFile(ctx, fsys, e, fn)
+				}
+				errs[i] = err
+				if opts.Progress != nil {
+					mu.Lock()
+					opts.Progress(e.Path, err)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range m.Files {
+		idx <- i
+	}
+	close(idx)
+	wg.Wait()
+	return errs
+}
+
+func processManifestFile(ctx context.Context, fsys fs.FS, e *ManifestEntry, fn func(*ManifestEntry, *countingReader) error) error {
+	f, err := fsys.Open(e.Path)
+	if err != nil {
+		return wrapError(ErrIO, err)
+	}
+	defer f.Close()
+	return fn(e, &countingReader{ctx: ctx, r: f})
+}
+
+type countingReader struct {
+	ctx context.Context
+	r   io.Reader
+	n   int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// sum returns the manifest tag computed with h.
+func (m *Manifest) sum(h hash.Hash) []byte {
+	var b [8]byte
+	h.Reset()
+	h.Write([]byte{byte(m.Algorithm)})
+	writeBytes32(h, []byte(m.KeyID))
+	binary.BigEndian.PutUint64(b[:], uint64(m.ChunkSize))
+	h.Write(b[:])
+	binary.BigEndian.PutUint64(b[:], uint64(len(m.Files)))
+	h.Write(b[:])
+	for _, e := range m.Files {
+		writeBytes32(h, []byte(e.Path))
+		binary.BigEndian.PutUint64(b[:], uint64(e.Size))
+		h.Write(b[:])
+		binary.BigEndian.PutUint32(b[:4], uint32(len(e.Chunks)))
+		h.Write(b[:4])
+		for _, c := range e.Chunks {
+			writeBytes32(h, c)
+		}
+		writeBytes32(h, e.Tag)
+	}
+	return h.Sum(nil)
+}
+
+// writeBytes32 writes the big endian uint32 length of p followed by p.
+func writeBytes32(w io.Writer, p []byte) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(len(p)))
+	w.Write(b[:])
+	w.Write(p)
+}
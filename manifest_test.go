@@ -0,0 +1,135 @@
+package cmac
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestManifest(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	fsys := fstest.MapFS{
+		"b.txt":       {Data: bytes.Repeat([]byte("b"), 100)},
+		"a/x.bin":     {Data: []byte("x")},
+		"a/y.bin":     {Data: nil},
+		"a/z/deep.go": {Data: []byte("package z")},
+	}
+	for _, chunkSize := range []int64{0, 16} {
+		opts := &ManifestOptions{KeyID: "k1", ChunkSize: chunkSize, Workers: 3}
+		m, err := BuildManifest(fsys, AES128, key, opts)
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		paths := []string{"a/x.bin", "a/y.bin", "a/z/deep.go", "b.txt"}
+		if len(m.Files) != len(paths) {
+			t.Fatalf("got %d files, expected %d", len(m.Files), len(paths))
+		}
+		for i, p := range paths {
+			if m.Files[i].Path != p || m.Files[i].Size != int64(len(fsys[p].Data)) {
+				t.Errorf("%d: got %s %d, expected %s %d", i, m.Files[i].Path, m.Files[i].Size, p, len(fsys[p].Data))
+			}
+		}
+		if e := m.Lookup("b.txt"); e == nil || e.Path != "b.txt" {
+			t.Errorf("lookup failed")
+		}
+		if m.Lookup("c.txt") != nil {
+			t.Errorf("unexpected entry")
+		}
+
+		js1, _ := json.Marshal(m)
+		m2, _ := BuildManifest(fsys, AES128, key, &ManifestOptions{KeyID: "k1", ChunkSize: chunkSize, Workers: 1})
+		js2, _ := json.Marshal(m2)
+		if !bytes.Equal(js1, js2) {
+			t.Errorf("manifest is not deterministic")
+		}
+
+		var m3 Manifest
+		if err := json.Unmarshal(js1, &m3); err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		var done []string
+		opts.Progress = func(path string, err error) {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", path, err)
+			}
+			done = append(done, path)
+		}
+		if err := m3.Verify(fsys, key, opts); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if len(done) != len(paths) {
+			t.Errorf("got %d progress calls, expected %d", len(done), len(paths))
+		}
+
+		m3.KeyID = "k2"
+		if err := m3.Verify(fsys, key, nil); err != ErrMismatch {
+			t.Errorf("got error %v, expected %v", err, ErrMismatch)
+		}
+		m3.KeyID = "k1"
+
+		fsys["a/x.bin"].Data[0] = 'y'
+		var merr *ManifestError
+		if err := m3.Verify(fsys, key, nil); !errors.As(err, &merr) || merr.Path != "a/x.bin" || !errors.Is(err, ErrMismatch) {
+			t.Errorf("got error %v, expected mismatch of a/x.bin", err)
+		}
+		fsys["a/x.bin"].Data[0] = 'x'
+
+		delete(fsys, "b.txt")
+		if err := m3.Verify(fsys, key, nil); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("got error %v, expected %v", err, fs.ErrNotExist)
+		}
+		fsys["b.txt"] = &fstest.MapFile{Data: bytes.Repeat([]byte("b"), 100)}
+	}
+
+	if _, err := BuildManifest(fsys, AES256, key, nil); err == nil {
+		t.Errorf("unexpected nil error for invalid key size")
+	}
+}
+
+func TestManifestVerifyReport(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	fsys := fstest.MapFS{
+		"a": {Data: []byte("aaaa")},
+		"b": {Data: []byte("bbbbbbbb")},
+		"c": {Data: []byte("cc")},
+		"d": {Data: []byte("dddddd")},
+	}
+	m, err := BuildManifest(fsys, AES128, key, &ManifestOptions{ChunkSize: 3})
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	fsys["b"] = &fstest.MapFile{Data: []byte("bbbbbbbB")}
+	delete(fsys, "d")
+
+	r, err := m.VerifyReport(context.Background(), fsys, key, nil)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if r.Files != 4 || r.Bytes != 14 || len(r.Errors) != 2 {
+		t.Fatalf("got report %+v", r)
+	}
+	if r.Errors[0].Path != "b" || r.Errors[0].Err != ErrMismatch {
+		t.Errorf("got error %v, expected b mismatch", r.Errors[0])
+	}
+	if r.Errors[1].Path != "d" || !errors.Is(r.Errors[1], fs.ErrNotExist) {
+		t.Errorf("got error %v, expected d not found", r.Errors[1])
+	}
+	if err := m.Verify(fsys, key, nil); err == nil || err.Error() != r.Err().Error() {
+		t.Errorf("got error %v, expected %v", err, r.Err())
+	}
+
+	// cancellation stops the verification
+	ctx, cancel := context.WithCancel(context.Background())
+	opts := &ManifestOptions{Workers: 1, Progress: func(string, error) { cancel() }}
+	r, err = m.VerifyReport(ctx, fsys, key, opts)
+	if err != context.Canceled || r == nil || r.Files != 1 || len(r.Errors) != 0 {
+		t.Errorf("got report %+v, error %v", r, err)
+	}
+	if _, err := m.VerifyReport(ctx, fsys, []byte("fedcba9876543210"), nil); err != ErrMismatch {
+		t.Errorf("got error %v, expected %v", err, ErrMismatch)
+	}
+}
@@ -0,0 +1,177 @@
+package cmac
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* A signed bus message carries the following headers:
+
+   cmac-key-id      ID of the key used to compute the tag
+   cmac-timestamp   signing time in milliseconds since the Unix epoch
+   cmac-headers     comma separated sorted names of the signed headers
+   cmac-tag         base64url encoded truncated tag, without padding
+
+The signed headers are all the headers present when the message is signed,
+including cmac-key-id, cmac-timestamp and cmac-headers, but excluding
+cmac-tag. Their names must be HTTP tokens, so that they don't contain
+commas. Headers added afterwards, e.g. by a broker, are ignored, and a
+message missing a signed header is rejected, so that an absent header isn't
+mistaken for an empty one. With
+uint32 and uint64 big endian lengths, the tag is the CMAC of
+
+   for each signed header in cmac-headers order: uint32(len(name)) || name || uint32(len(value)) || value
+   uint64(len(payload)) || payload
+*/
+
+// Message header names used for message signing.
+const (
+	MessageKeyIDHeader     = "cmac-key-id"
+	MessageTimestampHeader = "cmac-timestamp"
+	MessageHeadersHeader   = "cmac-headers"
+	MessageTagHeader       = "cmac-tag"
+)
+
+// ErrInvalidMessage is returned when a message signature is missing, invalid
+// or rejected by the verification policy.
+var ErrInvalidMessage = errors.New("cmac: invalid message signature")
+
+// MessagePolicy is the verification policy of messages.
+type MessagePolicy struct {
+	// MaxAge is the maximum age of a message. There is no limit when zero.
+	MaxAge time.Duration
+
+	// MaxSkew is the maximum time a message timestamp may be in the future.
+	MaxSkew time.Duration
+
+	// KeyIDs is the list of accepted key IDs. All are accepted when empty.
+	KeyIDs []string
+
+	// MinTagSize is the minimum accepted tag size. It is 8 when zero.
+	MinTagSize int
+}
+
+// SignMessage adds the signature headers to headers, which may not be nil.
+// h is the hash of the key with ID keyID and is reset. The tag is truncated
+// to tagSize bytes. It returns an ErrInvalidArgument error when a header name
+// isn't an HTTP token.
+func SignMessage(h hash.Hash, keyID string, tagSize int, headers map[string]string, payload []byte, now time.Time) error {
+	if tagSize < minTagSize || tagSize > h.Size() {
+		return newError(ErrInvalidArgument, "cmac: invalid tag size")
+	}
+	delete(headers, MessageTagHeader)
+	headers[MessageKeyIDHeader] = keyID
+	headers[MessageTimestampHeader] = strconv.FormatInt(now.UnixNano()/int64(time.Millisecond), 10)
+	headers[MessageHeadersHeader] = ""
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		if !isToken(name) {
+			return newError(ErrInvalidArgument, "cmac: invalid message header name")
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	headers[MessageHeadersHeader] = strings.Join(names, ",")
+	h.Reset()
+	writeMessage(h, names, headers, payload)
+	headers[MessageTagHeader] = base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:tagSize])
+	return nil
+}
+
+// VerifyMessage verifies the signature headers of a message with the hash
+// returned by keys for the message key ID, and applies the policy p.
+func VerifyMessage(keys KeyFunc, headers map[string]string, payload []byte, now time.Time, p MessagePolicy) error {
+	keyID := headers[MessageKeyIDHeader]
+	if len(p.KeyIDs) > 0 {
+		found := false
+		for _, id := range p.KeyIDs {
+			found = found || id == keyID
+		}
+		if !found {
+			return ErrInvalidMessage
+		}
+	}
+	ms, err := strconv.ParseInt(headers[MessageTimestampHeader], 10, 64)
+	if err != nil {
+		return ErrInvalidMessage
+	}
+	age := now.Sub(time.Unix(0, ms*int64(time.Millisecond)))
+	if (p.MaxAge > 0 && age > p.MaxAge) || -age > p.MaxSkew {
+		return ErrInvalidMessage
+	}
+	names := strings.Split(headers[MessageHeadersHeader], ",")
+	if !sort.StringsAreSorted(names) || !containsAll(names, MessageKeyIDHeader, MessageTimestampHeader, MessageHeadersHeader) {
+		return ErrInvalidMessage
+	}
+	for _, name := range names {
+		if _, ok := headers[name]; !ok {
+			return ErrInvalidMessage
+		}
+	}
+	tag, err := base64.RawURLEncoding.DecodeString(headers[MessageTagHeader])
+	minSize := p.MinTagSize
+	if minSize == 0 {
+		minSize = minTagSize
+	}
+	if err != nil || len(tag) < minSize {
+		return ErrInvalidMessage
+	}
+	h, err := keys(keyID)
+	if err != nil {
+		return err
+	}
+	if len(tag) > h.Size() {
+		return ErrInvalidMessage
+	}
+	h.Reset()
+	writeMessage(h, names, headers, payload)
+	if !Equal(h.Sum(nil)[:len(tag)], tag) {
+		audit("VerifyMessage", keyID, int64(len(payload)))
+		return ErrInvalidMessage
+	}
+	return nil
+}
+
+func containsAll(names []string, required ...string) bool {
+	for _, r := range required {
+		i := sort.SearchStrings(names, r)
+		if i == len(names) || names[i] != r {
+			return false
+		}
+	}
+	return true
+}
+
+// isToken returns true if s is an HTTP token as defined by RFC 9110.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			continue
+		}
+		if strings.IndexByte("!#$%&'*+-.^_`|~", c) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func writeMessage(h hash.Hash, names []string, headers map[string]string, payload []byte) {
+	var b [8]byte
+	for _, name := range names {
+		writeBytes32(h, []byte(name))
+		writeBytes32(h, []byte(headers[name]))
+	}
+	binary.BigEndian.PutUint64(b[:], uint64(len(payload)))
+	h.Write(b[:])
+	h.Write(payload)
+}
@@ -0,0 +1,94 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"errors"
+	"hash"
+	"testing"
+	"time"
+)
+
+func TestMessage(t *testing.T) {
+	keys := func(keyID string) (hash.Hash, error) {
+		return New(aes.NewCipher, []byte("0123456789abcdef"))
+	}
+	h, _ := keys("k1")
+	now := time.Unix(1600000000, 0)
+	payload := []byte("event payload")
+	headers := map[string]string{"content-type": "application/json", "trace": "abc"}
+	if err := SignMessage(h, "k1", 12, headers, payload, now); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if exp := "cmac-headers,cmac-key-id,cmac-timestamp,content-type,trace"; headers[MessageHeadersHeader] != exp {
+		t.Errorf("got signed headers %q, expected %q", headers[MessageHeadersHeader], exp)
+	}
+
+	p := MessagePolicy{MaxAge: time.Minute, KeyIDs: []string{"k0", "k1"}}
+	if err := VerifyMessage(keys, headers, payload, now.Add(time.Second), p); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	headers["added-by-broker"] = "x"
+	if err := VerifyMessage(keys, headers, payload, now, p); err != nil {
+		t.Errorf("unexpected error with unsigned header: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		payload string
+		policy  func(*MessagePolicy)
+		header  func(map[string]string)
+	}{
+		{name: "too old", now: now.Add(2 * time.Minute)},
+		{name: "future", now: now.Add(-time.Second)},
+		{name: "key ID", policy: func(p *MessagePolicy) { p.KeyIDs = []string{"k2"} }},
+		{name: "tag size", policy: func(p *MessagePolicy) { p.MinTagSize = 16 }},
+		{name: "payload", payload: "other payload"},
+		{name: "header", header: func(h map[string]string) { h["trace"] = "def" }},
+		{name: "removed header", header: func(h map[string]string) {
+			h[MessageHeadersHeader] = "cmac-headers,cmac-key-id,cmac-timestamp,content-type"
+		}},
+		{name: "missing tag", header: func(h map[string]string) { delete(h, MessageTagHeader) }},
+		{name: "timestamp", header: func(h map[string]string) { h[MessageTimestampHeader] = "x" }},
+	}
+	for _, test := range tests {
+		hdr := make(map[string]string)
+		for k, v := range headers {
+			hdr[k] = v
+		}
+		if test.header != nil {
+			test.header(hdr)
+		}
+		pol := p
+		if test.policy != nil {
+			test.policy(&pol)
+		}
+		if test.now.IsZero() {
+			test.now = now
+		}
+		msg := payload
+		if test.payload != "" {
+			msg = []byte(test.payload)
+		}
+		if err := VerifyMessage(keys, hdr, msg, test.now, pol); err != ErrInvalidMessage {
+			t.Errorf("%s: got error %v, expected %v", test.name, err, ErrInvalidMessage)
+		}
+	}
+
+	if err := SignMessage(h, "k1", 4, headers, payload, now); err == nil {
+		t.Errorf("unexpected nil error for invalid tag size")
+	}
+	err := SignMessage(h, "k1", 12, map[string]string{"a,b": "x"}, payload, now)
+	if !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidArgument)
+	}
+
+	empty := map[string]string{"trace": ""}
+	if err := SignMessage(h, "k1", 12, empty, payload, now); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	delete(empty, "trace")
+	if err := VerifyMessage(keys, empty, payload, now, p); err != ErrInvalidMessage {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidMessage)
+	}
+}
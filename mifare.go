@@ -0,0 +1,98 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"encoding/binary"
+	"hash"
+)
+
+/* MIFARE Plus SL3 secure messaging MACs are AES-CMACs truncated to the 8
+bytes at odd indexes (1, 3, ..., 15). With the 4 byte transaction
+identifier TI and the read and write counters R_Ctr and W_Ctr as 16 bit
+little endian integers, a session computes
+
+   command MAC  = MACt(KMac, Cmd || Ctr || TI || data)
+   response MAC = MACt(KMac, SC || Ctr+1 || TI || data)
+
+where Ctr is W_Ctr for write commands and R_Ctr otherwise, and SC is the
+status code. The counter is incremented after the response.
+*/
+
+// TruncateOdd returns the bytes at odd indexes of mac, i.e. the truncation
+// of a 16 byte CMAC to 8 bytes used by MIFARE Plus and DESFire EV2.
+func TruncateOdd(mac []byte) []byte {
+	t := make([]byte, len(mac)/2)
+	for i := range t {
+		t[i] = mac[2*i+1]
+	}
+	return t
+}
+
+// MifarePlusSession computes the MACs of a MIFARE Plus SL3 session.
+type MifarePlusSession struct {
+	mac               hash.Hash
+	ti                [4]byte
+	readCtr, writeCtr uint16
+}
+
+// NewMifarePlusSession returns a session with the 16 byte AES session MAC
+// key and the 4 byte transaction identifier. The counters start at 0.
+func NewMifarePlusSession(kMac, ti []byte) (*MifarePlusSession, error) {
+	if len(kMac) != 16 || len(ti) != 4 {
+		return nil, newError(ErrInvalidKey, "cmac: invalid MIFARE Plus key or TI size")
+	}
+	h, err := New(aes.NewCipher, kMac)
+	if err != nil {
+		return nil, err
+	}
+	s := &MifarePlusSession{mac: h}
+	copy(s.ti[:], ti)
+	return s, nil
+}
+
+// Counters returns the read and write counters.
+func (s *MifarePlusSession) Counters() (readCtr, writeCtr uint16) {
+	return s.readCtr, s.writeCtr
+}
+
+// CommandMAC returns the MAC of the command cmd with its parameters and data.
+func (s *MifarePlusSession) CommandMAC(cmd byte, write bool, data []byte) []byte {
+	return s.sum(cmd, s.counter(write), data)
+}
+
+// VerifyResponse verifies the MAC of the response to a command with the
+// status code and data, and increments the counter of the command. It
+// returns ErrMismatch when the MAC is invalid and the counter is then not
+// incremented.
+func (s *MifarePlusSession) VerifyResponse(status byte, write bool, data, mac []byte) error {
+	ctr := s.counter(write) + 1
+	if !Equal(s.sum(status, ctr, data), mac) {
+		audit("MifarePlusSession.VerifyResponse", "", int64(len(data)))
+		return ErrMismatch
+	}
+	if write {
+		s.writeCtr = ctr
+	} else {
+		s.readCtr = ctr
+	}
+	return nil
+}
+
+func (s *MifarePlusSession) counter(write bool) uint16 {
+	if write {
+		return s.writeCtr
+	}
+	return s.readCtr
+}
+
+// sum returns the truncated MAC of code || ctr || TI || data.
+func (s *MifarePlusSession) sum(code byte, ctr uint16, data []byte) []byte {
+	var hdr [7]byte
+	hdr[0] = code
+	binary.LittleEndian.PutUint16(hdr[1:], ctr)
+	copy(hdr[3:], s.ti[:])
+	s.mac.Reset()
+	s.mac.Write(hdr[:])
+	s.mac.Write(data)
+	return TruncateOdd(s.mac.Sum(nil))
+}
@@ -0,0 +1,56 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestTruncateOdd(t *testing.T) {
+	mac, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	if got := hex.EncodeToString(TruncateOdd(mac)); got != "01030507090b0d0f" {
+		t.Errorf("got %s, expected 01030507090b0d0f", got)
+	}
+}
+
+func TestMifarePlusSession(t *testing.T) {
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	ti, _ := hex.DecodeString("9a1b2c3d")
+	sum := func(b string) []byte {
+		m, _ := hex.DecodeString(b)
+		h, _ := New(aes.NewCipher, key)
+		h.Write(m)
+		return TruncateOdd(h.Sum(nil))
+	}
+
+	s, err := NewMifarePlusSession(key, ti)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	// write command with block number 4 and data
+	if got, exp := s.CommandMAC(0xa1, true, []byte{4, 0, 0xaa}), sum("a100009a1b2c3d0400aa"); !bytes.Equal(got, exp) {
+		t.Errorf("got %x, expected %x", got, exp)
+	}
+	if err := s.VerifyResponse(0x90, true, nil, sum("900100"+"9a1b2c3d")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if r, w := s.Counters(); r != 0 || w != 1 {
+		t.Errorf("got counters %d %d, expected 0 1", r, w)
+	}
+	if got, exp := s.CommandMAC(0xa1, true, nil), sum("a101009a1b2c3d"); !bytes.Equal(got, exp) {
+		t.Errorf("got %x, expected %x", got, exp)
+	}
+	if got, exp := s.CommandMAC(0x33, false, []byte{4}), sum("3300009a1b2c3d04"); !bytes.Equal(got, exp) {
+		t.Errorf("got %x, expected %x", got, exp)
+	}
+	if err := s.VerifyResponse(0x90, false, []byte{1}, sum("900100"+"9a1b2c3d")); err != ErrMismatch {
+		t.Errorf("got error %v, expected %v", err, ErrMismatch)
+	}
+	if r, w := s.Counters(); r != 0 || w != 1 {
+		t.Errorf("got counters %d %d after mismatch, expected 0 1", r, w)
+	}
+	if _, err := NewMifarePlusSession(key, ti[:3]); err == nil {
+		t.Errorf("unexpected nil error for invalid TI")
+	}
+}
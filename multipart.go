@@ -0,0 +1,90 @@
+package cmac
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+)
+
+// ErrPartOrder is returned when a part is not the one following the state.
+var ErrPartOrder = errors.New("cmac: part out of order")
+
+// MultipartState is the state of the CMAC computation of an object uploaded
+// in parts, after its first Parts parts. The CMAC being sequential, the
+// state after a part can only be computed from the state after the previous
+// part. The zero value is the state before the first part.
+type MultipartState struct {
+	Parts int    // number of parts processed
+	Size  int64  // number of bytes processed
+	State []byte // marshaled CMAC state, nil before the first part
+}
+
+// SumPart returns the state following s after processing the part with the
+// given number, starting at 1, read from r. h must implement
+// encoding.BinaryMarshaler and encoding.BinaryUnmarshaler as the hashes
+// returned by New, or an ErrInvalidArgument error is returned. It is reset.
+func SumPart(h hash.Hash, s MultipartState, part int, r io.Reader) (MultipartState, error) {
+	if part != s.Parts+1 {
+		return s, ErrPartOrder
+	}
+	m, ok := h.(encoding.BinaryMarshaler)
+	if !ok {
+		return s, newError(ErrInvalidArgument, "cmac: hash state can't be saved")
+	}
+	if err := s.restore(h); err != nil {
+		return s, err
+	}
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return s, wrapError(ErrIO, err)
+	}
+	state, err := m.MarshalBinary()
+	if err != nil {
+		return s, err
+	}
+	return MultipartState{Parts: part, Size: s.Size + n, State: state}, nil
+}
+
+// Sum returns the CMAC of the parts processed so far. h is reset.
+func (s MultipartState) Sum(h hash.Hash) ([]byte, error) {
+	if err := s.restore(h); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+func (s MultipartState) restore(h hash.Hash) error {
+	h.Reset()
+	if s.State == nil {
+		return nil
+	}
+	u, ok := h.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return newError(ErrInvalidArgument, "cmac: hash state can't be restored")
+	}
+	return u.UnmarshalBinary(s.State)
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (s MultipartState) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 12, 12+len(s.State))
+	binary.BigEndian.PutUint32(b, uint32(s.Parts))
+	binary.BigEndian.PutUint64(b[4:], uint64(s.Size))
+	return append(b, s.State...), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (s *MultipartState) UnmarshalBinary(b []byte) error {
+	if len(b) < 12 {
+		return newError(ErrInvalidArgument, "cmac: invalid multipart state")
+	}
+	s.Parts = int(binary.BigEndian.Uint32(b))
+	s.Size = int64(binary.BigEndian.Uint64(b[4:]))
+	s.State = nil
+	if len(b) > 12 {
+		s.State = append([]byte(nil), b[12:]...)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"errors"
+	"testing"
+)
+
+func TestMultipart(t *testing.T) {
+	h, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	data := bytes.Repeat([]byte("0123456789"), 100)
+	h.Write(data)
+	exp := h.Sum(nil)
+
+	var s MultipartState
+	tag, err := s.Sum(h)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	h.Reset()
+	if !bytes.Equal(tag, h.Sum(nil)) {
+		t.Errorf("empty object tag mismatch")
+	}
+
+	parts := [][]byte{data[:333], data[333:336], data[336:800], data[800:]}
+	for i, p := range parts {
+		s, err = SumPart(h, s, i+1, bytes.NewReader(p))
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %s", i, err)
+		}
+		b, _ := s.MarshalBinary()
+		s = MultipartState{}
+		if err := s.UnmarshalBinary(b); err != nil {
+			t.Fatalf("%d: unexpected error: %s", i, err)
+		}
+	}
+	if s.Parts != len(parts) || s.Size != int64(len(data)) {
+		t.Errorf("got %d parts and %d bytes", s.Parts, s.Size)
+	}
+	tag, err = s.Sum(h)
+	if err != nil || !bytes.Equal(tag, exp) {
+		t.Errorf("got %x, %v, expected %x", tag, err, exp)
+	}
+
+	if _, err := SumPart(h, s, 3, bytes.NewReader(nil)); err != ErrPartOrder {
+		t.Errorf("got error %v, expected %v", err, ErrPartOrder)
+	}
+	if err := s.UnmarshalBinary([]byte{1}); err == nil {
+		t.Errorf("unexpected nil error")
+	}
+
+	// hashes whose state can't be saved are rejected
+	nested, _ := NewNested(aes.NewCipher, []byte("0123456789abcdef"), []byte("fedcba9876543210"))
+	if _, err := SumPart(nested, MultipartState{}, 1, bytes.NewReader(data)); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidArgument)
+	}
+}
@@ -0,0 +1,64 @@
+package cmac
+
+import (
+	"encoding/binary"
+	"hash"
+)
+
+/* The nested CMAC of a message m with the independent keys K1 and K2 is
+
+   CMAC(K2, CMAC(K1, m) || uint64(len(m)))
+
+where len(m) is the byte length of m as a big endian 64 bit unsigned integer.
+*/
+
+type nested struct {
+	inner, outer hash.Hash
+	len          uint64
+	buf          []byte
+}
+
+// NewNested returns a new hash computing the nested CMAC with the keys k1
+// and k2, which must be independent. It returns an error if the keys are
+// equal.
+func NewNested(newCipher NewCipherFunc, k1, k2 []byte) (hash.Hash, error) {
+	if string(k1) == string(k2) {
+		return nil, newError(ErrInvalidKey, "cmac: nested keys must be different")
+	}
+	inner, err := New(newCipher, k1)
+	if err != nil {
+		return nil, err
+	}
+	outer, err := New(newCipher, k2)
+	if err != nil {
+		return nil, err
+	}
+	return &nested{inner: inner, outer: outer, buf: make([]byte, 0, inner.Size()+8)}, nil
+}
+
+func (c *nested) Size() int { return c.outer.Size() }
+
+func (c *nested) BlockSize() int { return c.inner.BlockSize() }
+
+// Write accumulates the bytes in m in the nested CMAC computation.
+func (c *nested) Write(m []byte) (n int, err error) {
+	c.len += uint64(len(m))
+	return c.inner.Write(m)
+}
+
+// Sum returns the nested CMAC appended to m. Write may be called after Sum.
+func (c *nested) Sum(m []byte) []byte {
+	b := c.inner.Sum(c.buf[:0])
+	var l [8]byte
+	binary.BigEndian.PutUint64(l[:], c.len)
+	b = append(b, l[:]...)
+	c.outer.Reset()
+	c.outer.Write(b)
+	return c.outer.Sum(m)
+}
+
+// Reset resets the nested CMAC.
+func (c *nested) Reset() {
+	c.inner.Reset()
+	c.len = 0
+}
@@ -0,0 +1,50 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestNested(t *testing.T) {
+	k1, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	k2, _ := hex.DecodeString("603deb1015ca71be2b73aef0857d7781")
+	msg, _ := hex.DecodeString("6bc1bee22e409f96e93d7e117393172aae2d8a57")
+
+	h1, _ := New(aes.NewCipher, k1)
+	h1.Write(msg)
+	b := h1.Sum(nil)
+	b = append(b, 0, 0, 0, 0, 0, 0, 0, byte(len(msg)))
+	h2, _ := New(aes.NewCipher, k2)
+	h2.Write(b)
+	exp := h2.Sum(nil)
+
+	h, err := NewNested(aes.NewCipher, k1, k2)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	h.Write(msg[:7])
+	h.Write(msg[7:])
+	if got := h.Sum(nil); !bytes.Equal(got, exp) {
+		t.Errorf("got %x, expected %x", got, exp)
+	}
+	if got := h.Sum(nil); !bytes.Equal(got, exp) {
+		t.Errorf("got %x after Sum, expected %x", got, exp)
+	}
+	h.Reset()
+	h.Write(msg)
+	if got := h.Sum(nil); !bytes.Equal(got, exp) {
+		t.Errorf("got %x after Reset, expected %x", got, exp)
+	}
+	if h.Size() != 16 || h.BlockSize() != 16 {
+		t.Errorf("invalid sizes %d and %d", h.Size(), h.BlockSize())
+	}
+
+	if _, err := NewNested(aes.NewCipher, k1, k1); err == nil {
+		t.Errorf("unexpected nil error for equal keys")
+	}
+	if _, err := NewNested(aes.NewCipher, k1, k2[:5]); err == nil {
+		t.Errorf("unexpected nil error for invalid key")
+	}
+}
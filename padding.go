@@ -0,0 +1,59 @@
+package cmac
+
+import "hash"
+
+/* The padding of CMAC, ISO/IEC 7816-4 padding or method 2 of ISO/IEC
+9797-1, is the byte 0x80 followed by as many 0x00 bytes as required to
+reach a multiple of the block size. Unlike CMAC, which doesn't pad complete
+blocks, Pad always appends at least one byte, so that it can be removed.
+*/
+
+// errInvalidPadding is returned by Unpad when the padding is invalid.
+var errInvalidPadding = newError(ErrInvalidArgument, "cmac: invalid padding")
+
+// Pad appends to b the ISO/IEC 7816-4 padding to a multiple of blockSize
+// bytes. A complete padding block is appended when len(b) is a multiple of
+// blockSize. It panics if blockSize isn't positive.
+func Pad(b []byte, blockSize int) []byte {
+	if blockSize <= 0 {
+		panic("cmac: invalid padding block size")
+	}
+	b = append(b, 0x80)
+	for len(b)%blockSize != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// Unpad returns b without its ISO/IEC 7816-4 padding. It returns an
+// ErrInvalidArgument error when the length of b isn't a non-zero multiple of
+// blockSize, or when its last block doesn't end with a valid padding.
+func Unpad(b []byte, blockSize int) ([]byte, error) {
+	if blockSize <= 0 || len(b) == 0 || len(b)%blockSize != 0 {
+		return nil, errInvalidPadding
+	}
+	for i := len(b) - 1; i >= len(b)-blockSize; i-- {
+		switch b[i] {
+		case 0:
+			continue
+		case 0x80:
+			return b[:i], nil
+		}
+		break
+	}
+	return nil, errInvalidPadding
+}
+
+// SumPadded returns the MAC computed with h of the message padded by a
+// device with the ISO/IEC 7816-4 padding to a multiple of the block size of
+// h. The padding is removed, so that the MAC is the one of the message and
+// not of the padded message. h is reset first.
+func SumPadded(h hash.Hash, padded []byte) ([]byte, error) {
+	msg, err := Unpad(padded, h.BlockSize())
+	if err != nil {
+		return nil, err
+	}
+	h.Reset()
+	h.Write(msg)
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,67 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestPad(t *testing.T) {
+	for _, n := range []int{0, 1, 15, 16, 17, 32} {
+		msg := bytes.Repeat([]byte{0x80}, n)
+		p := Pad(msg[:n:n], 16)
+		if len(p)%16 != 0 || len(p) <= n || p[n] != 0x80 || !bytes.Equal(p[:n], msg) {
+			t.Errorf("%d: got %x", n, p)
+		}
+		u, err := Unpad(p, 16)
+		if err != nil || !bytes.Equal(u, msg) {
+			t.Errorf("%d: got %x, %v", n, u, err)
+		}
+	}
+	for _, tc := range []struct{ in, exp string }{
+		{"", "8000000000000000"},
+		{"01", "0180000000000000"},
+		{"01020304050607", "0102030405060780"},
+		{"0102030405060708", "01020304050607088000000000000000"},
+	} {
+		b, _ := hex.DecodeString(tc.in)
+		if got := hex.EncodeToString(Pad(b, 8)); got != tc.exp {
+			t.Errorf("%s: got %s, expected %s", tc.in, got, tc.exp)
+		}
+	}
+	for _, n := range []int{0, -1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%d: expected panic", n)
+				}
+			}()
+			Pad(nil, n)
+		}()
+	}
+	for i, b := range [][]byte{nil, make([]byte, 15), make([]byte, 16), append(make([]byte, 15), 1),
+		append([]byte{0x80}, make([]byte, 16)...)} {
+		if _, err := Unpad(b, 16); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%d: got error %v, expected %v", i, err, ErrInvalidArgument)
+		}
+	}
+}
+
+func TestSumPadded(t *testing.T) {
+	h, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	for _, n := range []int{0, 5, 16, 40} {
+		msg := bytes.Repeat([]byte{'m'}, n)
+		h.Reset()
+		h.Write(msg)
+		expected := h.Sum(nil)
+		tag, err := SumPadded(h, Pad(msg, 16))
+		if err != nil || !bytes.Equal(tag, expected) {
+			t.Errorf("%d: got %x, %v, expected %x", n, tag, err, expected)
+		}
+	}
+	if _, err := SumPadded(h, make([]byte, 16)); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidArgument)
+	}
+}
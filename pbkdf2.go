@@ -0,0 +1,38 @@
+package cmac
+
+import (
+	"encoding/binary"
+)
+
+// PBKDF2 derives a key of keyLen bytes from the password and salt with
+// PBKDF2 as defined in RFC 8018, using AES-CMAC-PRF-128 of RFC 4615 as PRF.
+func PBKDF2(password, salt []byte, iter, keyLen int) ([]byte, error) {
+	if iter < 1 || keyLen <= 0 {
+		return nil, newError(ErrInvalidArgument, "cmac: invalid PBKDF2 parameters")
+	}
+	prf, err := newPRF128(password)
+	if err != nil {
+		return nil, err
+	}
+	size := prf.Size()
+	out := make([]byte, 0, keyLen+size)
+	t := make([]byte, size)
+	u := make([]byte, size)
+	var ctr [4]byte
+	for block := uint32(1); len(out) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(ctr[:], block)
+		prf.Write(ctr[:])
+		u = prf.Sum(u[:0])
+		copy(t, u)
+		for n := 1; n < iter; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			xor(t, u)
+		}
+		out = append(out, t...)
+	}
+	return out[:keyLen], nil
+}
@@ -0,0 +1,49 @@
+package cmac
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPBKDF2(t *testing.T) {
+	password, salt := []byte("password"), []byte("salt")
+
+	// two blocks with 3 iterations computed from the definition
+	prf, _ := newPRF128(password)
+	var exp []byte
+	for i := byte(1); i <= 2; i++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write([]byte{0, 0, 0, i})
+		u := prf.Sum(nil)
+		t := append([]byte(nil), u...)
+		for n := 1; n < 3; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(nil)
+			xor(t, u)
+		}
+		exp = append(exp, t...)
+	}
+
+	k, err := PBKDF2(password, salt, 3, 20)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if !bytes.Equal(k, exp[:20]) {
+		t.Errorf("got %x, expected %x", k, exp[:20])
+	}
+	k, _ = PBKDF2(password, salt, 1, 16)
+	h, _ := newPRF128(password)
+	h.Write([]byte("salt\x00\x00\x00\x01"))
+	if exp := h.Sum(nil); !bytes.Equal(k, exp) {
+		t.Errorf("got %x, expected %x", k, exp)
+	}
+
+	if _, err := PBKDF2(password, salt, 0, 16); err == nil {
+		t.Errorf("unexpected nil error for zero iterations")
+	}
+	if _, err := PBKDF2(password, salt, 1, 0); err == nil {
+		t.Errorf("unexpected nil error for zero key length")
+	}
+}
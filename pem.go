@@ -0,0 +1,114 @@
+package cmac
+
+import (
+	"encoding/pem"
+)
+
+// PEM block types and headers.
+const (
+	PEMKeyType         = "CMAC KEY"
+	PEMTagType         = "CMAC TAG"
+	PEMAlgorithmHeader = "Algorithm"
+	PEMKeyIDHeader     = "Key-ID"
+)
+
+// ErrInvalidPEM is returned when a PEM block is missing or invalid.
+var ErrInvalidPEM = newError(ErrInvalidArgument, "cmac: invalid PEM block")
+
+// EncodeKeyPEM returns the PEM encoding of the key with the algorithm and
+// optional key ID headers.
+func EncodeKeyPEM(alg Algorithm, keyID string, key []byte) ([]byte, error) {
+	if len(key) != alg.KeySize() {
+		return nil, ErrInvalidPEM
+	}
+	return encodePEM(PEMKeyType, alg, keyID, key)
+}
+
+// DecodeKeyPEM decodes the first PEM block of data which must be a valid
+// CMAC KEY block. It returns the rest of data following the block.
+func DecodeKeyPEM(data []byte) (alg Algorithm, keyID string, key, rest []byte, err error) {
+	alg, keyID, key, rest, err = decodePEM(PEMKeyType, data)
+	if err == nil && len(key) != alg.KeySize() {
+		err = ErrInvalidPEM
+	}
+	return
+}
+
+// EncodeTagPEM returns the PEM encoding of the tag with the algorithm and
+// optional key ID headers. The tag may be truncated.
+func EncodeTagPEM(alg Algorithm, keyID string, tag []byte) ([]byte, error) {
+	if !validPEMTag(tag) {
+		return nil, ErrInvalidPEM
+	}
+	return encodePEM(PEMTagType, alg, keyID, tag)
+}
+
+// DecodeTagPEM decodes the first PEM block of data which must be a valid
+// CMAC TAG block. It returns the rest of data following the block.
+func DecodeTagPEM(data []byte) (alg Algorithm, keyID string, tag, rest []byte, err error) {
+	alg, keyID, tag, rest, err = decodePEM(PEMTagType, data)
+	if err == nil && !validPEMTag(tag) {
+		err = ErrInvalidPEM
+	}
+	return
+}
+
+func validPEMTag(tag []byte) bool {
+	return len(tag) > 0 && len(tag) <= 16
+}
+
+// validKeyID returns true if the key ID is at most 255 printable ASCII
+// characters so that it may be stored in a PEM header.
+func validKeyID(keyID string) bool {
+	if len(keyID) > 255 {
+		return false
+	}
+	for i := 0; i < len(keyID); i++ {
+		if keyID[i] < 0x20 || keyID[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func encodePEM(typ string, alg Algorithm, keyID string, b []byte) ([]byte, error) {
+	if !alg.Valid() || !validKeyID(keyID) {
+		return nil, ErrInvalidPEM
+	}
+	block := &pem.Block{
+		Type:    typ,
+		Headers: map[string]string{PEMAlgorithmHeader: alg.String()},
+		Bytes:   b,
+	}
+	if keyID != "" {
+		block.Headers[PEMKeyIDHeader] = keyID
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+func decodePEM(typ string, data []byte) (alg Algorithm, keyID string, b, rest []byte, err error) {
+	block, rest := pem.Decode(data)
+	if block == nil || block.Type != typ {
+		return 0, "", nil, data, ErrInvalidPEM
+	}
+	for k, v := range block.Headers {
+		switch k {
+		case PEMAlgorithmHeader:
+			alg, err = ParseAlgorithm(v)
+		case PEMKeyIDHeader:
+			keyID = v
+			if !validKeyID(v) {
+				err = ErrInvalidPEM
+			}
+		default:
+			err = ErrInvalidPEM
+		}
+		if err != nil {
+			return 0, "", nil, data, err
+		}
+	}
+	if !alg.Valid() {
+		return 0, "", nil, data, ErrInvalidPEM
+	}
+	return alg, keyID, block.Bytes, rest, nil
+}
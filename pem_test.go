@@ -0,0 +1,61 @@
+package cmac
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPEM(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	tag := []byte("0123456789ab")
+	kp, err := EncodeKeyPEM(AES128, "device/42", key)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	tp, err := EncodeTagPEM(AES128, "", tag)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	alg, keyID, k, rest, err := DecodeKeyPEM(append(kp, tp...))
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if alg != AES128 || keyID != "device/42" || !bytes.Equal(k, key) {
+		t.Errorf("key mismatch, got %s %q %x", alg, keyID, k)
+	}
+	alg, keyID, tg, rest, err := DecodeTagPEM(rest)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if alg != AES128 || keyID != "" || !bytes.Equal(tg, tag) || len(rest) != 0 {
+		t.Errorf("tag mismatch, got %s %q %x", alg, keyID, tg)
+	}
+
+	if _, _, _, _, err := DecodeTagPEM(kp); err != ErrInvalidPEM {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidPEM)
+	}
+	if _, err := EncodeKeyPEM(AES256, "", key); err != ErrInvalidPEM {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidPEM)
+	}
+	if _, err := EncodeKeyPEM(AES128, "bad\nid", key); err != ErrInvalidPEM {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidPEM)
+	}
+	if _, err := EncodeTagPEM(AES128, "", make([]byte, 17)); err != ErrInvalidPEM {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidPEM)
+	}
+
+	tests := []string{
+		"",
+		strings.Replace(string(kp), "AES-128-CMAC", "AES-999-CMAC", 1),
+		strings.Replace(string(kp), "Key-ID", "Key-Name", 1),
+		strings.Replace(string(kp), "Algorithm: AES-128-CMAC\n", "", 1),
+		strings.Replace(string(kp), "AES-128-CMAC", "AES-256-CMAC", 1),
+	}
+	for i, test := range tests {
+		if _, _, _, _, err := DecodeKeyPEM([]byte(test)); err == nil {
+			t.Errorf("%2d: unexpected nil error", i)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"hash"
+)
+
+// PRF128 returns the AES-CMAC-PRF-128 of RFC 4615 of msg with the key, which
+// may have any length. It is used by IKEv2 and key derivation schemes
+// requiring a variable-length key.
+func PRF128(key, msg []byte) (out [16]byte) {
+	h, _ := newPRF128(key)
+	h.Write(msg)
+	h.Sum(out[:0])
+	return out
+}
+
+// newPRF128 returns the AES-CMAC-PRF-128 of RFC 4615 keyed with key, which
+// may have any length. A key not 16 bytes long is replaced with its
+// AES-CMAC with the zero key.
+func newPRF128(key []byte) (hash.Hash, error) {
+	if len(key) != aes.BlockSize {
+		h, err := New(aes.NewCipher, make([]byte, aes.BlockSize))
+		if err != nil {
+			return nil, err
+		}
+		h.Write(key)
+		key = h.Sum(nil)
+	}
+	return New(aes.NewCipher, key)
+}
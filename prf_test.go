@@ -0,0 +1,32 @@
+package cmac
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestPRF128(t *testing.T) {
+	// RFC 4615 section 4
+	key, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0fedcb")
+	msg, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f10111213")
+	for _, tc := range []struct {
+		keyLen int
+		exp    string
+	}{
+		{18, "84a348a4a45d235babfffc0d2b4da09a"},
+		{16, "980ae87b5f4c9c5214f5b6a8455e4c2d"},
+		{10, "290d9e112edb09ee141fcf64c0b72f3d"},
+	} {
+		h, err := newPRF128(key[:tc.keyLen])
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		h.Write(msg)
+		if got := hex.EncodeToString(h.Sum(nil)); got != tc.exp {
+			t.Errorf("key length %d: got %s, expected %s", tc.keyLen, got, tc.exp)
+		}
+		if got := PRF128(key[:tc.keyLen], msg); hex.EncodeToString(got[:]) != tc.exp {
+			t.Errorf("key length %d: got %x from PRF128, expected %s", tc.keyLen, got, tc.exp)
+		}
+	}
+}
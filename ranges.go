@@ -0,0 +1,61 @@
+package cmac
+
+import (
+	"io"
+	"runtime"
+	"sync"
+)
+
+// SumRanges returns the CMAC of each byte range of r with the algorithm and
+// key. The ranges are read concurrently and may overlap. It returns an
+// ErrIO error wrapping io.ErrUnexpectedEOF when a range extends beyond the end of r.
+func SumRanges(alg Algorithm, key []byte, r io.ReaderAt, ranges []Range) ([][]byte, error) {
+	h, err := alg.New(key)
+	if err != nil {
+		return nil, err
+	}
+	for _, rg := range ranges {
+		if rg.Off < 0 || rg.Len < 0 {
+			return nil, newError(ErrInvalidArgument, "cmac: invalid range")
+		}
+	}
+	tags := make([][]byte, len(ranges))
+	errs := make([]error, len(ranges))
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(ranges) {
+		workers = len(ranges)
+	}
+	base := h.(*cmac)
+	idx := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := base.clone()
+			for i := range idx {
+				c.Reset()
+				n, err := io.Copy(c, io.NewSectionReader(r, ranges[i].Off, ranges[i].Len))
+				if err == nil && n != ranges[i].Len {
+					err = io.ErrUnexpectedEOF
+				}
+				if err != nil {
+					errs[i] = wrapError(ErrIO, err)
+					continue
+				}
+				tags[i] = c.Sum(nil)
+			}
+		}()
+	}
+	for i := range ranges {
+		idx <- i
+	}
+	close(idx)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tags, nil
+}
@@ -0,0 +1,38 @@
+package cmac
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestSumRanges(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	data := make([]byte, 10000)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	ranges := []Range{{0, 0}, {0, 10000}, {17, 1000}, {500, 33}, {9999, 1}, {17, 1000}}
+	tags, err := SumRanges(AES128, key, bytes.NewReader(data), ranges)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	for i, rg := range ranges {
+		h, _ := AES128.New(key)
+		h.Write(data[rg.Off : rg.Off+rg.Len])
+		if exp := h.Sum(nil); !bytes.Equal(tags[i], exp) {
+			t.Errorf("%d: got %x, expected %x", i, tags[i], exp)
+		}
+	}
+
+	if _, err := SumRanges(AES128, key, bytes.NewReader(data), []Range{{9990, 11}}); !errors.Is(err, io.ErrUnexpectedEOF) || !errors.Is(err, ErrIO) {
+		t.Errorf("got error %v, expected %v", err, io.ErrUnexpectedEOF)
+	}
+	if _, err := SumRanges(AES128, key, bytes.NewReader(data), []Range{{-1, 1}}); err == nil {
+		t.Errorf("unexpected nil error for invalid range")
+	}
+	if _, err := SumRanges(AES256, key, bytes.NewReader(data), nil); err == nil {
+		t.Errorf("unexpected nil error for invalid key")
+	}
+}
@@ -0,0 +1,162 @@
+package cmac
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+/* A ratchet derives a message key and the next chain key from each chain
+key, starting with the root key as chain key 0:
+
+   message_key_i = DeriveKey(chain_key_i, "cmac-go ratchet message", uint64(i), len(root))
+   chain_key_i+1 = DeriveKey(chain_key_i, "cmac-go ratchet chain", uint64(i), len(root))
+
+where uint64(i) is big endian. The marshaled state of a ratchet is
+
+   "cmacrtch" || version || uint64(index) || uint16(message key size) || chain key
+
+where version is the byte 2. The states of version 1 have no message key
+size, which is then the chain key size. The state contains the chain key
+and must be kept secret.
+*/
+
+const (
+	ratchetMagic        = "cmacrtch"
+	ratchetMessageLabel = "cmac-go ratchet message"
+	ratchetChainLabel   = "cmac-go ratchet chain"
+)
+
+// MaxRatchetSkip is the maximum number of message keys skipped by a call to
+// Ratchet.Key, so that a forged index can't make it loop for a long time.
+const MaxRatchetSkip = 1000
+
+// ErrRatchetIndex is returned when the key of an index preceding the
+// ratchet index is requested.
+var ErrRatchetIndex = errors.New("cmac: ratchet index already passed")
+
+// Ratchet is a forward-secure symmetric ratchet. Each message key is derived
+// from a chain key that is erased once the key is returned, so that the
+// compromise of the ratchet state doesn't reveal the previous message keys.
+type Ratchet struct {
+	newCipher NewCipherFunc
+	index     uint64
+	chain     []byte
+	keySize   int
+}
+
+// NewRatchet returns a ratchet at index 0 with the root key.
+func NewRatchet(newCipher NewCipherFunc, root []byte) (*Ratchet, error) {
+	return newRatchet(newCipher, root, len(root))
+}
+
+// newRatchet returns a ratchet at index 0 with the root key, returning
+// message keys of keySize bytes.
+func newRatchet(newCipher NewCipherFunc, root []byte, keySize int) (*Ratchet, error) {
+	if _, err := newCipher(root); err != nil {
+		return nil, wrapError(ErrInvalidKey, err)
+	}
+	return &Ratchet{newCipher: newCipher, chain: append([]byte(nil), root...), keySize: keySize}, nil
+}
+
+// LoadRatchet returns the ratchet with the state returned by MarshalBinary.
+func LoadRatchet(newCipher NewCipherFunc, state []byte) (*Ratchet, error) {
+	errState := newError(ErrInvalidArgument, "cmac: invalid ratchet state")
+	n := len(ratchetMagic) + 1
+	if len(state) < n || string(state[:len(ratchetMagic)]) != ratchetMagic {
+		return nil, errState
+	}
+	version := state[len(ratchetMagic)]
+	if version != 1 && version != 2 || len(state) < n+8 {
+		return nil, errState
+	}
+	index := binary.BigEndian.Uint64(state[n:])
+	n += 8
+	keySize := len(state) - n
+	if version == 2 {
+		if len(state) < n+2 {
+			return nil, errState
+		}
+		keySize = int(binary.BigEndian.Uint16(state[n:]))
+		n += 2
+	}
+	if keySize == 0 || len(state) == n {
+		return nil, errState
+	}
+	r, err := newRatchet(newCipher, state[n:], keySize)
+	if err != nil {
+		return nil, err
+	}
+	r.index = index
+	return r, nil
+}
+
+// Index returns the index of the next message key.
+func (r *Ratchet) Index() uint64 {
+	return r.index
+}
+
+// Next returns the message key of the ratchet index and advances the ratchet.
+func (r *Ratchet) Next() (index uint64, key []byte, err error) {
+	index = r.index
+	key, err = r.Key(index)
+	return
+}
+
+// Key returns the message key of the given index and advances the ratchet
+// to the following index. The keys of the skipped indexes are lost. It
+// returns ErrRatchetIndex when index is smaller than the ratchet index, and
+// an ErrInvalidArgument error when more than MaxRatchetSkip keys would be
+// skipped.
+func (r *Ratchet) Key(index uint64) ([]byte, error) {
+	if index < r.index {
+		return nil, ErrRatchetIndex
+	}
+	if index-r.index > MaxRatchetSkip {
+		return nil, newError(ErrInvalidArgument, "cmac: too many skipped ratchet keys")
+	}
+	for ; r.index < index; r.index++ {
+		if err := r.step(nil); err != nil {
+			return nil, err
+		}
+	}
+	key := make([]byte, r.keySize)
+	if err := r.step(key); err != nil {
+		return nil, err
+	}
+	r.index++
+	return key, nil
+}
+
+// step stores the message key in key when not nil and replaces the chain
+// key with the next one.
+func (r *Ratchet) step(key []byte) error {
+	var ctx [8]byte
+	binary.BigEndian.PutUint64(ctx[:], r.index)
+	if key != nil {
+		k, err := DeriveKey(r.newCipher, r.chain, []byte(ratchetMessageLabel), ctx[:], len(key))
+		if err != nil {
+			return err
+		}
+		copy(key, k)
+		zero(k)
+	}
+	next, err := DeriveKey(r.newCipher, r.chain, []byte(ratchetChainLabel), ctx[:], len(r.chain))
+	if err != nil {
+		return err
+	}
+	copy(r.chain, next)
+	zero(next)
+	return nil
+}
+
+// MarshalBinary returns the state of the ratchet. It implements
+// encoding.BinaryMarshaler.
+func (r *Ratchet) MarshalBinary() ([]byte, error) {
+	n := len(ratchetMagic)
+	b := make([]byte, n+11, n+11+len(r.chain))
+	copy(b, ratchetMagic)
+	b[n] = 2
+	binary.BigEndian.PutUint64(b[n+1:], r.index)
+	binary.BigEndian.PutUint16(b[n+9:], uint16(r.keySize))
+	return append(b, r.chain...), nil
+}
@@ -0,0 +1,102 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestRatchet(t *testing.T) {
+	root, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	r, err := NewRatchet(aes.NewCipher, root)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	ctx := make([]byte, 8)
+	exp0, _ := DeriveKey(aes.NewCipher, root, []byte("cmac-go ratchet message"), ctx, 16)
+	chain1, _ := DeriveKey(aes.NewCipher, root, []byte("cmac-go ratchet chain"), ctx, 16)
+	ctx[7] = 1
+	exp1, _ := DeriveKey(aes.NewCipher, chain1, []byte("cmac-go ratchet message"), ctx, 16)
+
+	i, k, err := r.Next()
+	if err != nil || i != 0 || !bytes.Equal(k, exp0) {
+		t.Errorf("got %d %x %v, expected 0 %x", i, k, err, exp0)
+	}
+	state, _ := r.MarshalBinary()
+	i, k, err = r.Next()
+	if err != nil || i != 1 || !bytes.Equal(k, exp1) {
+		t.Errorf("got %d %x %v, expected 1 %x", i, k, err, exp1)
+	}
+	if _, err := r.Key(1); err != ErrRatchetIndex {
+		t.Errorf("got error %v, expected %v", err, ErrRatchetIndex)
+	}
+
+	k5, err := r.Key(5)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if r.Index() != 6 {
+		t.Errorf("got index %d, expected 6", r.Index())
+	}
+
+	r2, err := LoadRatchet(aes.NewCipher, state)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if k, _ := r2.Key(1); !bytes.Equal(k, exp1) {
+		t.Errorf("got %x after load, expected %x", k, exp1)
+	}
+	if k, _ := r2.Key(5); !bytes.Equal(k, k5) {
+		t.Errorf("got %x after load, expected %x", k, k5)
+	}
+
+	for _, b := range [][]byte{nil, state[:17], append([]byte("x"), state[1:]...)} {
+		if _, err := LoadRatchet(aes.NewCipher, b); err == nil {
+			t.Errorf("unexpected nil error for state %x", b)
+		}
+	}
+	if _, err := NewRatchet(aes.NewCipher, root[:3]); err == nil {
+		t.Errorf("unexpected nil error for invalid root key")
+	}
+
+	// the states of version 1 have no message key size
+	v1 := append([]byte("cmacrtch\x01\x00\x00\x00\x00\x00\x00\x00\x01"), chain1...)
+	r2, err = LoadRatchet(aes.NewCipher, v1)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if k, _ := r2.Key(1); !bytes.Equal(k, exp1) {
+		t.Errorf("got %x after loading a version 1 state, expected %x", k, exp1)
+	}
+
+	// the skipped indexes are bounded
+	idx := r.Index()
+	if _, err := r.Key(idx + MaxRatchetSkip + 1); !errors.Is(err, ErrInvalidArgument) || r.Index() != idx {
+		t.Errorf("got error %v and index %d, expected %v and %d", err, r.Index(), ErrInvalidArgument, idx)
+	}
+	if _, err := r.Key(idx + MaxRatchetSkip); err != nil || r.Index() != idx+MaxRatchetSkip+1 {
+		t.Errorf("got error %v and index %d, expected index %d", err, r.Index(), idx+MaxRatchetSkip+1)
+	}
+}
+
+func TestRatchetKeySize(t *testing.T) {
+	root, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	r, _ := newRatchet(aes.NewCipher, root, 32)
+	r.Next()
+	state, _ := r.MarshalBinary()
+	_, exp, _ := r.Next()
+	r2, err := LoadRatchet(aes.NewCipher, state)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if _, k, err := r2.Next(); err != nil || len(k) != 32 || !bytes.Equal(k, exp) {
+		t.Errorf("got %x %v after load, expected %x", k, err, exp)
+	}
+	state[17], state[18] = 0, 0
+	if _, err := LoadRatchet(aes.NewCipher, state); err == nil {
+		t.Errorf("unexpected nil error for a zero key size")
+	}
+}
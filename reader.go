@@ -0,0 +1,79 @@
+package cmac
+
+import (
+	"hash"
+	"io"
+)
+
+// ErrTooLong is returned when a message exceeds its maximum length, by the
+// limited verifying readers and by Write after SetMaxLen.
+var ErrTooLong = newError(ErrInvalidArgument, "cmac: message too long")
+
+// LimitMode is the behavior of a limited verifying reader when the data
+// exceeds the limit.
+type LimitMode int
+
+// Limit modes.
+const (
+	// LimitError makes the reader return ErrTooLong as soon as the limit
+	// is exceeded.
+	LimitError LimitMode = iota
+
+	// LimitTruncate makes the reader stop at the limit and verify the CMAC
+	// of the truncated data.
+	LimitTruncate
+)
+
+type verifyingReader struct {
+	r        io.Reader
+	h        hash.Hash
+	tag      []byte
+	err      error
+	n, limit int64 // limit is negative when there is none
+}
+
+// NewVerifyingReader returns a reader that reads from r and computes the CMAC
+// of the data read with h, which is reset. When r returns io.EOF, the reader
+// returns io.EOF if the CMAC matches tag, and ErrMismatch otherwise. The data
+// read is thus unverified until io.EOF is returned. The tag may be truncated
+// to no less than 8 bytes.
+func NewVerifyingReader(r io.Reader, h hash.Hash, tag []byte) io.Reader {
+	h.Reset()
+	return &verifyingReader{r: r, h: h, tag: tag, limit: -1}
+}
+
+// NewLimitedVerifyingReader returns a verifying reader, as NewVerifyingReader,
+// that reads at most limit bytes from r. The mode defines the behavior when
+// r has more data.
+func NewLimitedVerifyingReader(r io.Reader, h hash.Hash, tag []byte, limit int64, mode LimitMode) io.Reader {
+	if mode == LimitTruncate {
+		return NewVerifyingReader(io.LimitReader(r, limit), h, tag)
+	}
+	h.Reset()
+	return &verifyingReader{r: r, h: h, tag: tag, limit: limit}
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+	if v.limit >= 0 && int64(len(p)) > v.limit-v.n+1 {
+		p = p[:v.limit-v.n+1]
+	}
+	n, err := v.r.Read(p)
+	if v.limit >= 0 && v.n+int64(n) > v.limit {
+		n, err = int(v.limit-v.n), ErrTooLong
+	}
+	v.n += int64(n)
+	if _, ew := v.h.Write(p[:n]); ew != nil {
+		n, err = 0, ew
+	}
+	if err == io.EOF {
+		if mac := v.h.Sum(nil); len(v.tag) < minTagSize || len(v.tag) > len(mac) || !Equal(mac[:len(v.tag)], v.tag) {
+			err = ErrMismatch
+			audit("NewVerifyingReader", "", v.n)
+		}
+	}
+	v.err = err
+	return n, err
+}
@@ -0,0 +1,73 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"io"
+	"testing"
+)
+
+func TestVerifyingReader(t *testing.T) {
+	h, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	data := bytes.Repeat([]byte("data"), 1000)
+	h.Write(data)
+	tag := h.Sum(nil)
+
+	for _, n := range []int{16, 8} {
+		b, err := io.ReadAll(NewVerifyingReader(bytes.NewReader(data), h, tag[:n]))
+		if err != nil || !bytes.Equal(b, data) {
+			t.Errorf("%d: got %d bytes, %v", n, len(b), err)
+		}
+	}
+	for _, bad := range [][]byte{nil, tag[:7], append(tag, 0)} {
+		if _, err := io.ReadAll(NewVerifyingReader(bytes.NewReader(data), h, bad)); err != ErrMismatch {
+			t.Errorf("got error %v, expected %v", err, ErrMismatch)
+		}
+	}
+	tag[0] ^= 1
+	r := NewVerifyingReader(bytes.NewReader(data), h, tag)
+	if _, err := io.ReadAll(r); err != ErrMismatch {
+		t.Errorf("got error %v, expected %v", err, ErrMismatch)
+	}
+	if _, err := r.Read(make([]byte, 1)); err != ErrMismatch {
+		t.Errorf("got error %v, expected %v", err, ErrMismatch)
+	}
+
+	h.(interface{ SetMaxLen(uint64) }).SetMaxLen(100)
+	if _, err := io.ReadAll(NewVerifyingReader(bytes.NewReader(data), h, tag)); err != ErrTooLong {
+		t.Errorf("got error %v, expected %v", err, ErrTooLong)
+	}
+}
+
+func TestLimitedVerifyingReader(t *testing.T) {
+	h, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	data := bytes.Repeat([]byte("data"), 1000)
+	h.Write(data)
+	tag := h.Sum(nil)
+	h.Reset()
+	h.Write(data[:100])
+	tag100 := h.Sum(nil)
+
+	for _, limit := range []int64{4000, 5000} {
+		b, err := io.ReadAll(NewLimitedVerifyingReader(bytes.NewReader(data), h, tag, limit, LimitError))
+		if err != nil || !bytes.Equal(b, data) {
+			t.Errorf("%d: got %d bytes, %v", limit, len(b), err)
+		}
+	}
+	b, err := io.ReadAll(NewLimitedVerifyingReader(bytes.NewReader(data), h, tag, 3999, LimitError))
+	if err != ErrTooLong || len(b) != 3999 {
+		t.Errorf("got %d bytes, %v, expected 3999 bytes and %v", len(b), err, ErrTooLong)
+	}
+	r := NewLimitedVerifyingReader(bytes.NewReader(data), h, tag, 0, LimitError)
+	if n, err := r.Read(make([]byte, 10)); n != 0 || err != ErrTooLong {
+		t.Errorf("got %d, %v, expected 0 and %v", n, err, ErrTooLong)
+	}
+
+	b, err = io.ReadAll(NewLimitedVerifyingReader(bytes.NewReader(data), h, tag100, 100, LimitTruncate))
+	if err != nil || !bytes.Equal(b, data[:100]) {
+		t.Errorf("got %d bytes, %v", len(b), err)
+	}
+	if _, err = io.ReadAll(NewLimitedVerifyingReader(bytes.NewReader(data), h, tag, 100, LimitTruncate)); err != ErrMismatch {
+		t.Errorf("got error %v, expected %v", err, ErrMismatch)
+	}
+}
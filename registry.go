@@ -0,0 +1,170 @@
+package cmac
+
+import (
+	"errors"
+	"hash"
+	"sync"
+)
+
+// ErrUnknownKey is returned when a key ID is not known.
+var ErrUnknownKey = errors.New("cmac: unknown key ID")
+
+// KeyProvider returns the algorithm and key associated to a key ID. It
+// returns ErrUnknownKey when the key ID is not known.
+type KeyProvider interface {
+	Key(keyID string) (Algorithm, []byte, error)
+}
+
+type registryKey struct {
+	alg Algorithm
+	key []byte
+}
+
+// Registry maps key IDs to keys. The output of Sign is an envelope holding
+// the key ID, so that Verify finds the key by itself. A Registry is safe for
+// concurrent use.
+type Registry struct {
+	mu       sync.RWMutex
+	keys     map[string]registryKey
+	provider KeyProvider
+	stats    *Stats
+}
+
+// NewRegistry returns an empty registry. Key IDs not added to the registry
+// are looked up with p when p is not nil.
+func NewRegistry(p KeyProvider) *Registry {
+	return &Registry{keys: make(map[string]registryKey), provider: p}
+}
+
+// SetStats sets the statistics counting the operations of the registry per
+// key ID, or disables counting when s is nil. Failures are only counted for
+// known key IDs.
+func (r *Registry) SetStats(s *Stats) {
+	r.mu.Lock()
+	r.stats = s
+	r.mu.Unlock()
+}
+
+// record adds a MAC of n bytes, and a failure when failed is true, to the
+// statistics of the key ID, if any.
+func (r *Registry) record(keyID string, n int, failed bool) {
+	r.mu.RLock()
+	s := r.stats
+	r.mu.RUnlock()
+	if s == nil {
+		return
+	}
+	var f uint64
+	if failed {
+		f = 1
+	}
+	s.Add(keyID, 1, uint64(n), f)
+}
+
+// Add associates the algorithm and key to the key ID, replacing any previous
+// association. The key ID may not be longer than 255 bytes.
+func (r *Registry) Add(keyID string, alg Algorithm, key []byte) error {
+	if len(keyID) > 255 {
+		return newError(ErrInvalidArgument, "cmac: key ID too long")
+	}
+	if !alg.Valid() {
+		return ErrUnknownAlgorithm
+	}
+	if len(key) != alg.KeySize() {
+		return newError(ErrInvalidKey, "cmac: invalid key size for "+alg.String())
+	}
+	r.mu.Lock()
+	r.keys[keyID] = registryKey{alg: alg, key: append([]byte(nil), key...)}
+	r.mu.Unlock()
+	return nil
+}
+
+// Remove removes the key ID from the registry. It doesn't affect the provider.
+func (r *Registry) Remove(keyID string) {
+	r.mu.Lock()
+	delete(r.keys, keyID)
+	r.mu.Unlock()
+}
+
+// Key returns the algorithm and a copy of the key associated to the key ID.
+// It implements KeyProvider.
+func (r *Registry) Key(keyID string) (Algorithm, []byte, error) {
+	r.mu.RLock()
+	k, ok := r.keys[keyID]
+	r.mu.RUnlock()
+	if ok {
+		return k.alg, append([]byte(nil), k.key...), nil
+	}
+	if r.provider == nil {
+		return 0, nil, ErrUnknownKey
+	}
+	return r.provider.Key(keyID)
+}
+
+// Hash returns a new CMAC hash with the key associated to the key ID. It may
+// be used as a KeyFunc. When statistics are set, the hash counts its MACs
+// and bytes, and the methods other than those of hash.Hash are hidden.
+func (r *Registry) Hash(keyID string) (hash.Hash, error) {
+	alg, key, err := r.Key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	h, err := alg.New(key)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	s := r.stats
+	r.mu.RUnlock()
+	if s != nil {
+		h = &statsHash{Hash: h, stats: s, keyID: keyID}
+	}
+	return h, nil
+}
+
+// Sign returns the envelope of the CMAC of msg with the key associated to
+// the key ID.
+func (r *Registry) Sign(keyID string, msg []byte) ([]byte, error) {
+	alg, key, err := r.Key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	h, err := alg.New(key)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(msg)
+	r.record(keyID, len(msg), false)
+	return BuildEnvelope(Envelope{Algorithm: alg, KeyID: keyID, Tag: h.Sum(nil)})
+}
+
+// Verify returns nil if envelope holds the CMAC of msg computed with the key
+// associated to its key ID. It returns ErrMismatch when the algorithm or the
+// tag doesn't match, and a truncated tag is rejected.
+func (r *Registry) Verify(msg, envelope []byte) error {
+	e, err := ParseEnvelope(envelope)
+	if err != nil {
+		return err
+	}
+	alg, key, err := r.Key(e.KeyID)
+	if err != nil {
+		return err
+	}
+	if alg != e.Algorithm {
+		r.record(e.KeyID, 0, true)
+		audit("Registry.Verify", e.KeyID, int64(len(msg)))
+		return ErrMismatch
+	}
+	h, err := alg.New(key)
+	if err != nil {
+		return err
+	}
+	h.Write(msg)
+	ok := Equal(h.Sum(nil), e.Tag)
+	r.record(e.KeyID, len(msg), !ok)
+	if !ok {
+		audit("Registry.Verify", e.KeyID, int64(len(msg)))
+		return ErrMismatch
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package cmac
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+type testProvider map[string][]byte
+
+func (p testProvider) Key(keyID string) (Algorithm, []byte, error) {
+	k, ok := p[keyID]
+	if !ok {
+		return 0, nil, ErrUnknownKey
+	}
+	return AES128, k, nil
+}
+
+func TestRegistry(t *testing.T) {
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	msg, _ := hex.DecodeString("6bc1bee22e409f96e93d7e117393172a")
+	r := NewRegistry(testProvider{"p1": key})
+	if err := r.Add("t1", AES128, key); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if err := r.Add("t2", AES256, key); err == nil {
+		t.Errorf("unexpected nil error for invalid key size")
+	}
+	if err := r.Add("t2", 0, key); err != ErrUnknownAlgorithm {
+		t.Errorf("got error %v, expected %v", err, ErrUnknownAlgorithm)
+	}
+
+	env, err := r.Sign("t1", msg)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	// RFC 4493 example 2
+	if exp := "0101027431" + "10" + "070a16b46b4d4144f79bdd9dd04a287c"; hex.EncodeToString(env) != exp {
+		t.Errorf("got %x, expected %s", env, exp)
+	}
+	if err := r.Verify(msg, env); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	_, k, _ := r.Key("t1")
+	k[0] ^= 1 // a copy is returned
+	if err := r.Verify(msg, env); err != nil {
+		t.Errorf("unexpected error after modifying the returned key: %v", err)
+	}
+	if err := r.Verify(msg[1:], env); err != ErrMismatch {
+		t.Errorf("got error %v, expected %v", err, ErrMismatch)
+	}
+	short := append([]byte{}, env[:5]...)
+	short = append(short, 8)
+	short = append(short, env[6:14]...)
+	if err := r.Verify(msg, short); err != ErrMismatch {
+		t.Errorf("got error %v, expected %v for truncated tag", err, ErrMismatch)
+	}
+
+	env, err = r.Sign("p1", msg)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if err := r.Verify(msg, env); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := r.Hash("p1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	r.Remove("t1")
+	if _, err := r.Sign("t1", msg); err != ErrUnknownKey {
+		t.Errorf("got error %v, expected %v", err, ErrUnknownKey)
+	}
+	if _, err := NewRegistry(nil).Hash("t1"); err != ErrUnknownKey {
+		t.Errorf("got error %v, expected %v", err, ErrUnknownKey)
+	}
+}
@@ -0,0 +1,159 @@
+package cmac
+
+import (
+	"hash"
+	"sync"
+	"time"
+)
+
+// FetchFunc returns the algorithm and key of a key ID from a remote source.
+// It returns ErrUnknownKey when the key ID is not known.
+type FetchFunc func(keyID string) (Algorithm, []byte, error)
+
+type remoteKey struct {
+	alg      Algorithm
+	key      []byte
+	fetched  time.Time // time of the last successful fetch
+	lastUsed time.Time
+}
+
+// RemoteProvider is a KeyProvider caching the keys returned by a fetch
+// function. Cached keys are refreshed in the background, and expire when
+// they couldn't be refreshed or weren't used for the TTL. The memory of
+// expired keys is zeroed. It may be used as the provider of a Registry, and
+// its Hash method as a KeyFunc. A RemoteProvider is safe for concurrent use.
+type RemoteProvider struct {
+	fetch FetchFunc
+	ttl   time.Duration
+	now   func() time.Time
+	mu    sync.Mutex
+	keys  map[string]*remoteKey
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// NewRemoteProvider returns a provider caching the keys returned by fetch
+// for the duration ttl, and refreshing them every ttl/2. It returns an
+// ErrInvalidArgument error when ttl is shorter than 2ns, so that the refresh
+// period is positive. Close must be called to stop the refresh.
+func NewRemoteProvider(fetch FetchFunc, ttl time.Duration) (*RemoteProvider, error) {
+	if ttl/2 <= 0 {
+		return nil, newError(ErrInvalidArgument, "cmac: invalid remote key TTL")
+	}
+	p := &RemoteProvider{
+		fetch: fetch,
+		ttl:   ttl,
+		now:   time.Now,
+		keys:  make(map[string]*remoteKey),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go p.refreshLoop()
+	return p, nil
+}
+
+func (p *RemoteProvider) refreshLoop() {
+	defer close(p.done)
+	t := time.NewTicker(p.ttl / 2)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			p.Refresh()
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// Key returns a copy of the algorithm and key of the key ID, fetching it
+// when it isn't cached. Fetch errors are not cached. It implements
+// KeyProvider.
+func (p *RemoteProvider) Key(keyID string) (Algorithm, []byte, error) {
+	now := p.now()
+	p.mu.Lock()
+	if k := p.keys[keyID]; k != nil && now.Sub(k.fetched) < p.ttl {
+		k.lastUsed = now
+		alg, key := k.alg, append([]byte(nil), k.key...)
+		p.mu.Unlock()
+		return alg, key, nil
+	}
+	p.mu.Unlock()
+	alg, key, err := p.fetch(keyID)
+	if err != nil {
+		return 0, nil, err
+	}
+	p.mu.Lock()
+	p.evict(keyID)
+	p.keys[keyID] = &remoteKey{alg: alg, key: append([]byte(nil), key...), fetched: now, lastUsed: now}
+	p.mu.Unlock()
+	return alg, append([]byte(nil), key...), nil
+}
+
+// Hash returns a new CMAC hash with the key of the key ID. It may be used
+// as a KeyFunc.
+func (p *RemoteProvider) Hash(keyID string) (hash.Hash, error) {
+	alg, key, err := p.Key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(key)
+	return alg.New(key)
+}
+
+// Refresh fetches the cached keys again, and removes the keys that expired
+// or weren't used for the TTL. A key that can't be fetched is kept until it
+// expires. Refresh is called periodically in the background.
+func (p *RemoteProvider) Refresh() {
+	now := p.now()
+	p.mu.Lock()
+	var ids []string
+	for id, k := range p.keys {
+		if now.Sub(k.fetched) >= p.ttl || now.Sub(k.lastUsed) >= p.ttl {
+			p.evict(id)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	p.mu.Unlock()
+	for _, id := range ids {
+		alg, key, err := p.fetch(id)
+		if err != nil {
+			continue
+		}
+		p.mu.Lock()
+		if k := p.keys[id]; k != nil {
+			zero(k.key)
+			k.alg, k.key, k.fetched = alg, append([]byte(nil), key...), now
+		}
+		p.mu.Unlock()
+	}
+}
+
+// evict removes the key ID from the cache and zeroes its key. p.mu must be
+// held.
+func (p *RemoteProvider) evict(keyID string) {
+	if k := p.keys[keyID]; k != nil {
+		zero(k.key)
+		delete(p.keys, keyID)
+	}
+}
+
+// Close stops the background refresh and zeroes and removes all cached keys.
+func (p *RemoteProvider) Close() error {
+	close(p.stop)
+	<-p.done
+	p.mu.Lock()
+	for id := range p.keys {
+		p.evict(id)
+	}
+	p.mu.Unlock()
+	return nil
+}
+
+// zero sets all bytes of b to 0.
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
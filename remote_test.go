@@ -0,0 +1,102 @@
+package cmac
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testFetcher struct {
+	mu    sync.Mutex
+	keys  map[string][]byte
+	calls int
+	err   error
+}
+
+func (f *testFetcher) fetch(keyID string) (Algorithm, []byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.err != nil {
+		return 0, nil, f.err
+	}
+	k, ok := f.keys[keyID]
+	if !ok {
+		return 0, nil, ErrUnknownKey
+	}
+	return AES128, k, nil
+}
+
+func TestRemoteProvider(t *testing.T) {
+	f := &testFetcher{keys: map[string][]byte{"k1": []byte("0123456789abcdef")}}
+	p, err := NewRemoteProvider(f.fetch, time.Hour)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	defer p.Close()
+	now := time.Unix(1600000000, 0)
+	p.now = func() time.Time { return now }
+
+	alg, key, err := p.Key("k1")
+	if err != nil || alg != AES128 || string(key) != "0123456789abcdef" {
+		t.Fatalf("got %v %q %v", alg, key, err)
+	}
+	key[0] = 'x' // a copy is returned
+	if _, key, _ = p.Key("k1"); string(key) != "0123456789abcdef" || f.calls != 1 {
+		t.Errorf("got %q after %d fetches, expected cached key", key, f.calls)
+	}
+	if _, _, err := p.Key("k2"); err != ErrUnknownKey {
+		t.Errorf("got error %v, expected %v", err, ErrUnknownKey)
+	}
+
+	// refresh picks up the new key value
+	f.keys["k1"] = []byte("fedcba9876543210")
+	old := p.keys["k1"].key
+	now = now.Add(30 * time.Minute)
+	p.Refresh()
+	if _, key, _ = p.Key("k1"); string(key) != "fedcba9876543210" {
+		t.Errorf("got %q, expected refreshed key", key)
+	}
+	if !bytes.Equal(old, make([]byte, 16)) {
+		t.Errorf("replaced key not zeroed: %q", old)
+	}
+
+	// a failed refresh keeps the key until it expires
+	f.err = errors.New("network error")
+	now = now.Add(40 * time.Minute)
+	p.Refresh()
+	if _, _, err := p.Key("k1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	now = now.Add(30 * time.Minute)
+	old = p.keys["k1"].key
+	p.Refresh()
+	if _, _, err := p.Key("k1"); err != f.err {
+		t.Errorf("got error %v, expected %v", err, f.err)
+	}
+	if !bytes.Equal(old, make([]byte, 16)) {
+		t.Errorf("expired key not zeroed: %q", old)
+	}
+
+	// the provider works with a registry
+	f.err = nil
+	r := NewRegistry(p)
+	env, err := r.Sign("k1", []byte("msg"))
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if err := r.Verify([]byte("msg"), env); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if h, err := p.Hash("k1"); err != nil || h.Size() != 16 {
+		t.Errorf("got %v, %v", h, err)
+	}
+
+	for _, ttl := range []time.Duration{-time.Second, 0, 1} {
+		if _, err := NewRemoteProvider(f.fetch, ttl); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%v: got error %v, expected %v", ttl, err, ErrInvalidArgument)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+//go:build !cmac_small
+// +build !cmac_small
+
+package cmac
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"hash"
+	"math"
+	"sort"
+	"time"
+)
+
+/* A row tag is the encoding version byte followed by the CMAC of the
+version 1 encoding of the columns, sorted by name, where integers are big
+endian:
+
+   version || uint32(number of columns)
+   for each column: uint32(len(name)) || name || type || value
+
+with the following type bytes and value encodings:
+
+   0  nil        no value
+   1  int64      8 bytes
+   2  float64    8 bytes IEEE 754 binary representation
+   3  bool       1 byte, 0 or 1
+   4  []byte     uint32(len(value)) || value
+   5  string     uint32(len(value)) || value
+   6  time.Time  int64(seconds since the Unix epoch) || uint32(nanoseconds)
+
+New encoding versions will be added with a new version byte so that
+existing row tags remain verifiable.
+*/
+
+// RowEncodingVersion is the version of the column encoding used by SumRow.
+const RowEncodingVersion = 1
+
+// ErrInvalidColumn is returned when a column value can't be encoded or the
+// column names are not unique.
+var ErrInvalidColumn = newError(ErrInvalidArgument, "cmac: invalid column")
+
+// Column is a named column value. The value is converted as a database/sql
+// query argument to nil, int64, float64, bool, []byte, string or time.Time.
+type Column struct {
+	Name  string
+	Value interface{}
+}
+
+// SumRow returns the row tag of the columns computed with h, which is reset.
+// The column order doesn't matter.
+func SumRow(h hash.Hash, cols []Column) ([]byte, error) {
+	b, err := encodeRow(cols)
+	if err != nil {
+		return nil, err
+	}
+	h.Reset()
+	h.Write(b)
+	return h.Sum([]byte{RowEncodingVersion}), nil
+}
+
+// VerifyRow returns nil if tag is the valid row tag of the columns computed
+// with h, which is reset, and ErrMismatch otherwise.
+func VerifyRow(h hash.Hash, cols []Column, tag []byte) error {
+	if len(tag) == 0 || tag[0] != RowEncodingVersion {
+		return ErrMismatch
+	}
+	b, err := encodeRow(cols)
+	if err != nil {
+		return err
+	}
+	h.Reset()
+	h.Write(b)
+	if !Equal(h.Sum(nil), tag[1:]) {
+		audit("VerifyRow", "", int64(len(b)))
+		return ErrMismatch
+	}
+	return nil
+}
+
+func encodeRow(cols []Column) ([]byte, error) {
+	sorted := make([]Column, len(cols))
+	copy(sorted, cols)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	var v [12]byte
+	b := []byte{RowEncodingVersion}
+	binary.BigEndian.PutUint32(v[:], uint32(len(sorted)))
+	b = append(b, v[:4]...)
+	for i, c := range sorted {
+		if i > 0 && c.Name == sorted[i-1].Name {
+			return nil, ErrInvalidColumn
+		}
+		binary.BigEndian.PutUint32(v[:], uint32(len(c.Name)))
+		b = append(append(b, v[:4]...), c.Name...)
+		val, err := driver.DefaultParameterConverter.ConvertValue(c.Value)
+		if err != nil {
+			return nil, ErrInvalidColumn
+		}
+		switch x := val.(type) {
+		case nil:
+			b = append(b, 0)
+		case int64:
+			binary.BigEndian.PutUint64(v[:], uint64(x))
+			b = append(append(b, 1), v[:8]...)
+		case float64:
+			binary.BigEndian.PutUint64(v[:], math.Float64bits(x))
+			b = append(append(b, 2), v[:8]...)
+		case bool:
+			if x {
+				b = append(b, 3, 1)
+			} else {
+				b = append(b, 3, 0)
+			}
+		case []byte:
+			binary.BigEndian.PutUint32(v[:], uint32(len(x)))
+			b = append(append(append(b, 4), v[:4]...), x...)
+		case string:
+			binary.BigEndian.PutUint32(v[:], uint32(len(x)))
+			b = append(append(append(b, 5), v[:4]...), x...)
+		case time.Time:
+			binary.BigEndian.PutUint64(v[:], uint64(x.Unix()))
+			binary.BigEndian.PutUint32(v[8:], uint32(x.Nanosecond()))
+			b = append(append(b, 6), v[:12]...)
+		default:
+			return nil, ErrInvalidColumn
+		}
+	}
+	return b, nil
+}
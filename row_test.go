@@ -0,0 +1,66 @@
+//go:build !cmac_small
+// +build !cmac_small
+
+package cmac
+
+import (
+	"crypto/aes"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRow(t *testing.T) {
+	h, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	cols := []Column{
+		{"id", 42},
+		{"name", "alice"},
+		{"balance", 12.5},
+		{"active", true},
+		{"blob", []byte{1, 2}},
+		{"created", ts},
+		{"deleted", nil},
+	}
+	tag, err := SumRow(h, cols)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if tag[0] != RowEncodingVersion || len(tag) != 17 {
+		t.Fatalf("invalid tag %x", tag)
+	}
+
+	b, _ := encodeRow([]Column{{"b", int64(-1)}, {"a", "x"}})
+	if exp := "01000000020000000161050000000178000000016201ffffffffffffffff"; hex.EncodeToString(b) != exp {
+		t.Errorf("got encoding %x, expected %s", b, exp)
+	}
+
+	reordered := []Column{cols[6], cols[5], cols[4], cols[3], cols[2], cols[1], cols[0]}
+	if err := VerifyRow(h, reordered, tag); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	tests := [][]Column{
+		append(cols[:6:6], Column{"deleted", false}),
+		append(cols[:6:6], Column{"deleted", ""}),
+		append(cols[:6:6], Column{"removed", nil}),
+		cols[:6],
+	}
+	for i, test := range tests {
+		if err := VerifyRow(h, test, tag); err != ErrMismatch {
+			t.Errorf("%d: got error %v, expected %v", i, err, ErrMismatch)
+		}
+	}
+	if err := VerifyRow(h, cols, append([]byte{2}, tag[1:]...)); err != ErrMismatch {
+		t.Errorf("got error %v, expected %v", err, ErrMismatch)
+	}
+	if _, err := SumRow(h, []Column{{"a", 1}, {"a", 2}}); err != ErrInvalidColumn {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidColumn)
+	}
+	if _, err := SumRow(h, []Column{{"a", struct{}{}}}); err != ErrInvalidColumn {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidColumn)
+	}
+	if !errors.Is(ErrInvalidColumn, ErrInvalidArgument) {
+		t.Errorf("got error %v, expected category %v", ErrInvalidColumn, ErrInvalidArgument)
+	}
+}
@@ -0,0 +1,73 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"crypto/rand"
+	"io"
+)
+
+/* Seal and Open derive the AES-SIV-CMAC key from the AES key K of 16, 24
+or 32 bytes with DeriveKey, and seal the plaintext P with a random nonce N:
+
+   SIVKey = DeriveKey(K, "cmac-go seal", version, 2*len(K))
+   blob   = version || N || SIV(SIVKey, AD, version, N, P)
+
+where version is the byte 1. The random nonce makes equal plaintexts yield
+different blobs. If a nonce is ever repeated, SIV only reveals whether the
+plaintexts are equal.
+*/
+
+const (
+	sealVersion = 1
+	sealLabel   = "cmac-go seal"
+)
+
+// SealOverhead is the byte size added by Seal to the plaintext.
+const SealOverhead = 1 + sivNonceSize + aes.BlockSize
+
+// Seal encrypts and authenticates the plaintext and the additional data ad
+// with the AES key of 16, 24 or 32 bytes, and returns the sealed blob. The
+// blob doesn't contain ad, which must be given to Open. It is
+// SealOverhead bytes longer than the plaintext.
+func Seal(key, plaintext, ad []byte) ([]byte, error) {
+	s, err := newSealSIV(key, sealVersion)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, 1+sivNonceSize, SealOverhead+len(plaintext))
+	b[0] = sealVersion
+	if _, err := io.ReadFull(rand.Reader, b[1:]); err != nil {
+		return nil, wrapError(ErrIO, err)
+	}
+	return s.SealVec(b, plaintext, ad, b[:1], b[1:]), nil
+}
+
+// Open decrypts and authenticates the blob returned by Seal with the same
+// key and additional data, and returns the plaintext. It returns ErrOpen
+// when the blob is malformed, was modified, or was sealed with another key
+// or additional data.
+func Open(key, blob, ad []byte) ([]byte, error) {
+	if len(blob) < SealOverhead || blob[0] != sealVersion {
+		return nil, ErrOpen
+	}
+	s, err := newSealSIV(key, blob[0])
+	if err != nil {
+		return nil, err
+	}
+	n := 1 + sivNonceSize
+	return s.OpenVec(nil, blob[n:], ad, blob[:1], blob[1:n])
+}
+
+// newSealSIV returns the SIV of the blob version derived from the AES key.
+func newSealSIV(key []byte, version byte) (*SIV, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, newError(ErrInvalidKey, "cmac: invalid seal key size")
+	}
+	k, err := DeriveKey(aes.NewCipher, key, []byte(sealLabel), []byte{version}, 2*len(key))
+	if err != nil {
+		return nil, err
+	}
+	return NewSIV(k)
+}
@@ -0,0 +1,54 @@
+package cmac
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSeal(t *testing.T) {
+	for _, size := range []int{16, 24, 32} {
+		key := bytes.Repeat([]byte{byte(size)}, size)
+		for _, msg := range []string{"", "a", "the quick brown fox jumps over the lazy dog"} {
+			b1, err := Seal(key, []byte(msg), []byte("ad"))
+			if err != nil {
+				t.Fatalf("%d: unexpected error: %v", size, err)
+			}
+			b2, _ := Seal(key, []byte(msg), []byte("ad"))
+			if len(b1) != len(msg)+SealOverhead || bytes.Equal(b1, b2) {
+				t.Errorf("%d %q: got blobs of %d bytes or equal blobs", size, msg, len(b1))
+			}
+			p, err := Open(key, b1, []byte("ad"))
+			if err != nil || string(p) != msg {
+				t.Errorf("%d: got %q, %v, expected %q", size, p, err, msg)
+			}
+		}
+	}
+
+	key := bytes.Repeat([]byte{1}, 16)
+	b, _ := Seal(key, []byte("message"), nil)
+	tests := []struct {
+		key, blob, ad []byte
+	}{
+		{bytes.Repeat([]byte{2}, 16), b, nil},
+		{bytes.Repeat([]byte{1}, 32), b, nil},
+		{key, b, []byte("ad")},
+		{key, b[:SealOverhead-1], nil},
+		{key, append([]byte{2}, b[1:]...), nil},
+		{key, append(b[:len(b)-1:len(b)-1], b[len(b)-1]^1), nil},
+		{key, append(b[:5:5], append([]byte{b[5] ^ 1}, b[6:]...)...), nil},
+	}
+	for i, test := range tests {
+		if _, err := Open(test.key, test.blob, test.ad); err != ErrOpen {
+			t.Errorf("%d: got error %v, expected %v", i, err, ErrOpen)
+		}
+	}
+	for _, k := range [][]byte{nil, make([]byte, 15), make([]byte, 64)} {
+		if _, err := Seal(k, nil, nil); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("%d: got error %v, expected %v", len(k), err, ErrInvalidKey)
+		}
+		if _, err := Open(k, b, nil); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("%d: got error %v, expected %v", len(k), err, ErrInvalidKey)
+		}
+	}
+}
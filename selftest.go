@@ -0,0 +1,53 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"encoding/hex"
+	"errors"
+)
+
+// selfTestVectors are the known answers of RFC 4493 and NIST SP 800-38B.
+var selfTestVectors = []struct {
+	key    string
+	msgLen int
+	mac    string
+}{
+	{"2b7e151628aed2a6abf7158809cf4f3c", 0, "bb1d6929e95937287fa37d129b756746"},
+	{"2b7e151628aed2a6abf7158809cf4f3c", 16, "070a16b46b4d4144f79bdd9dd04a287c"},
+	{"2b7e151628aed2a6abf7158809cf4f3c", 40, "dfa66747de9ae63030ca32611497c827"},
+	{"2b7e151628aed2a6abf7158809cf4f3c", 64, "51f0bebf7e3b9d92fc49741779363cfe"},
+	{"8e73b0f7da0e6452c810f32b809079e562f8ead2522c6b7b", 0, "d17ddf46adaacde531cac483de7a9367"},
+	{"8e73b0f7da0e6452c810f32b809079e562f8ead2522c6b7b", 16, "9e99a7bf31e710900662f65e617c5184"},
+	{"603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4", 0, "028962f61b7bf89efc6b551f4667d983"},
+	{"603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4", 16, "28a7023f452e8f82bd4bf28d8c37c35c"},
+}
+
+// selfTestMessage is the message of the known answer tests, truncated to
+// their message length.
+const selfTestMessage = "6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e51" +
+	"30c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710"
+
+// selfTest runs the known answer tests with the whole message written at
+// once and byte by byte, and returns an error when a MAC is wrong.
+func selfTest() error {
+	msg, _ := hex.DecodeString(selfTestMessage)
+	for _, v := range selfTestVectors {
+		key, _ := hex.DecodeString(v.key)
+		h, err := New(aes.NewCipher, key)
+		if err != nil {
+			return err
+		}
+		h.Write(msg[:v.msgLen])
+		if hex.EncodeToString(h.Sum(nil)) != v.mac {
+			return errors.New("cmac: self test failed: invalid MAC")
+		}
+		h.Reset()
+		for i := 0; i < v.msgLen; i++ {
+			h.Write(msg[i : i+1])
+		}
+		if hex.EncodeToString(h.Sum(nil)) != v.mac {
+			return errors.New("cmac: self test failed: invalid MAC with split writes")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build cmacselftest
+// +build cmacselftest
+
+package cmac
+
+// With the cmacselftest build tag, the package initialization runs the
+// known answer tests and panics when one fails.
+func init() {
+	if err := selfTest(); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,15 @@
+package cmac
+
+import "testing"
+
+func TestSelfTest(t *testing.T) {
+	if err := selfTest(); err != nil {
+		t.Error("unexpected error: ", err)
+	}
+	v := selfTestVectors[1]
+	defer func() { selfTestVectors[1] = v }()
+	selfTestVectors[1].mac = "070a16b46b4d4144f79bdd9dd04a287d"
+	if err := selfTest(); err == nil {
+		t.Error("unexpected nil error for invalid known answer")
+	}
+}
@@ -0,0 +1,155 @@
+package cmac
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash"
+)
+
+/* A serial frame has the following layout:
+
+   +------+-----+-----+---------+-----+
+   | sync | len | seq | payload | tag |
+   +------+-----+-----+---------+-----+
+
+sync is the two bytes 0xA5 0x5A, len is the payload byte length, seq is the
+big endian uint32 sequence number of the frame, and tag is the CMAC of
+len || seq || payload truncated to the tag size agreed by both ends. The
+sequence numbers of a link are strictly increasing, and a decoder drops the
+frames whose sequence number is not larger than the last one received.
+After a byte loss or corruption, the decoder resynchronizes on the next
+sync bytes followed by a valid frame.
+*/
+
+const (
+	serialHeaderSize = 7
+
+	// MaxSerialPayload is the maximum payload size of a serial frame.
+	MaxSerialPayload = 255
+)
+
+var serialSync = []byte{0xa5, 0x5a}
+
+// SerialEncoder encodes authenticated frames for serial links. It must not
+// be used concurrently.
+type SerialEncoder struct {
+	h       hash.Hash
+	tagSize int
+	seq     uint64
+}
+
+// NewSerialEncoder returns an encoder of frames with tags computed with h
+// and truncated to tagSize bytes.
+func NewSerialEncoder(h hash.Hash, tagSize int) (*SerialEncoder, error) {
+	if tagSize < minTagSize || tagSize > h.Size() {
+		return nil, newError(ErrInvalidArgument, "cmac: invalid tag size")
+	}
+	return &SerialEncoder{h: h, tagSize: tagSize}, nil
+}
+
+// Encode appends the frame of the payload to dst. It returns an error when
+// the payload is longer than MaxSerialPayload, or when the 2^32 sequence
+// numbers are exhausted, in which case the key must be changed.
+func (e *SerialEncoder) Encode(dst, payload []byte) ([]byte, error) {
+	if len(payload) > MaxSerialPayload {
+		return dst, newError(ErrInvalidArgument, "cmac: serial payload too long")
+	}
+	if e.seq > 0xffffffff {
+		return dst, newError(ErrInvalidArgument, "cmac: serial sequence numbers exhausted")
+	}
+	dst = append(dst, serialSync...)
+	n := len(dst)
+	dst = append(dst, byte(len(payload)), 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(dst[n+1:], uint32(e.seq))
+	dst = append(dst, payload...)
+	e.seq++
+	e.h.Reset()
+	e.h.Write(dst[n:])
+	return append(dst, e.h.Sum(nil)[:e.tagSize]...), nil
+}
+
+// SerialDecoder decodes the authenticated frames received on a serial link.
+// It must not be used concurrently.
+type SerialDecoder struct {
+	h       hash.Hash
+	tagSize int
+	buf     []byte
+	next    uint64 // smallest acceptable sequence number
+}
+
+// NewSerialDecoder returns a decoder of frames with tags computed with h and
+// truncated to tagSize bytes.
+func NewSerialDecoder(h hash.Hash, tagSize int) (*SerialDecoder, error) {
+	if tagSize < minTagSize || tagSize > h.Size() {
+		return nil, newError(ErrInvalidArgument, "cmac: invalid tag size")
+	}
+	return &SerialDecoder{h: h, tagSize: tagSize}, nil
+}
+
+// Feed processes the received bytes p and returns the payloads of the
+// authenticated frames completed by them. Bytes that don't belong to a
+// valid frame are dropped. A false sync in the data doesn't delay the
+// following frames.
+func (d *SerialDecoder) Feed(p []byte) [][]byte {
+	var out [][]byte
+	d.buf = append(d.buf, p...)
+	for {
+		pending, found := -1, false
+		for i := 0; i < len(d.buf); i++ {
+			j := bytes.Index(d.buf[i:], serialSync)
+			if j < 0 {
+				break
+			}
+			i += j
+			if len(d.buf)-i < serialHeaderSize {
+				if pending < 0 {
+					pending = i
+				}
+				break
+			}
+			n := i + serialHeaderSize + int(d.buf[i+2]) + d.tagSize
+			if n > len(d.buf) {
+				if pending < 0 {
+					pending = i
+				}
+				continue
+			}
+			if payload, ok := d.open(d.buf[i:n]); ok {
+				out = append(out, payload)
+				d.buf = d.buf[n:]
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		switch {
+		case pending >= 0:
+			d.buf = d.buf[pending:]
+		case len(d.buf) > 0 && d.buf[len(d.buf)-1] == serialSync[0]:
+			d.buf = append(d.buf[:0], serialSync[0])
+		default:
+			d.buf = d.buf[:0]
+		}
+		return out
+	}
+}
+
+// open returns a copy of the payload of the frame if it is valid.
+func (d *SerialDecoder) open(frame []byte) ([]byte, bool) {
+	n := len(frame) - d.tagSize
+	d.h.Reset()
+	d.h.Write(frame[2:n])
+	if !Equal(d.h.Sum(nil)[:d.tagSize], frame[n:]) {
+		audit("SerialDecoder.Feed", "", int64(n-2))
+		return nil, false
+	}
+	seq := uint64(binary.BigEndian.Uint32(frame[3:]))
+	if seq < d.next {
+		audit("SerialDecoder.Feed", "", int64(n-2))
+		return nil, false
+	}
+	d.next = seq + 1
+	return append([]byte(nil), frame[serialHeaderSize:n]...), true
+}
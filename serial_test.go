@@ -0,0 +1,80 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestSerial(t *testing.T) {
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	h1, _ := New(aes.NewCipher, key)
+	h2, _ := New(aes.NewCipher, key)
+	enc, err := NewSerialEncoder(h1, 8)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	dec, _ := NewSerialDecoder(h2, 8)
+
+	f0, _ := enc.Encode(nil, []byte("hello"))
+	if exp := "a55a" + "05" + "00000000" + hex.EncodeToString([]byte("hello")); hex.EncodeToString(f0[:12]) != exp {
+		t.Errorf("got header %x, expected %s", f0[:12], exp)
+	}
+	h1.Reset()
+	h1.Write(f0[2:12])
+	if !bytes.Equal(f0[12:], h1.Sum(nil)[:8]) {
+		t.Errorf("invalid tag")
+	}
+	f1, _ := enc.Encode(nil, []byte{0xa5, 0x5a, 0xa5})
+	f2, _ := enc.Encode(nil, nil)
+	f3, _ := enc.Encode(nil, []byte("last"))
+
+	// noise, a frame fed byte by byte, a truncated frame, a corrupted frame,
+	// a replayed frame, and a valid frame
+	var stream []byte
+	stream = append(stream, 0x00, 0xa5, 0x5a, 0xff)
+	stream = append(stream, f0...)
+	stream = append(stream, f1[:len(f1)-3]...)
+	bad := append([]byte(nil), f2...)
+	bad[len(bad)-1] ^= 1
+	stream = append(stream, bad...)
+	stream = append(stream, f0...)
+	stream = append(stream, f3...)
+
+	var got []string
+	for _, b := range stream {
+		for _, p := range dec.Feed([]byte{b}) {
+			got = append(got, string(p))
+		}
+	}
+	if len(got) != 2 || got[0] != "hello" || got[1] != "last" {
+		t.Errorf("got payloads %q, expected [hello last]", got)
+	}
+	if len(dec.buf) != 0 {
+		t.Errorf("got %d buffered bytes, expected 0", len(dec.buf))
+	}
+
+	dec, _ = NewSerialDecoder(h2, 8)
+	got = got[:0]
+	for _, p := range dec.Feed(append(append(append([]byte{}, f1...), f2...), f3...)) {
+		got = append(got, string(p))
+	}
+	if len(got) != 3 || got[0] != "\xa5\x5a\xa5" || got[1] != "" || got[2] != "last" {
+		t.Errorf("got payloads %q", got)
+	}
+
+	if _, err := enc.Encode(nil, make([]byte, 256)); err == nil {
+		t.Errorf("unexpected nil error for too long payload")
+	}
+	enc.seq = 1 << 32
+	if _, err := enc.Encode(nil, nil); err == nil {
+		t.Errorf("unexpected nil error for exhausted sequence numbers")
+	}
+	if _, err := NewSerialEncoder(h1, 4); err == nil {
+		t.Errorf("unexpected nil error for too short tag")
+	}
+	if _, err := NewSerialDecoder(h1, 17); err == nil {
+		t.Errorf("unexpected nil error for too long tag")
+	}
+}
@@ -0,0 +1,41 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"hash"
+	"reflect"
+	"sync"
+)
+
+var sharedBlocks struct {
+	once  sync.Once
+	types []reflect.Type
+}
+
+// NewSharing returns a new CMAC hash using the block cipher b, which may be
+// shared by any number of CMACs used concurrently, so that the key schedule
+// is computed once. Only the blocks of crypto/aes and crypto/des, which
+// are safe for concurrent use, are accepted, since the cipher.Block
+// interface doesn't tell whether a block has a state. It returns an
+// ErrInvalidArgument error for other blocks.
+func NewSharing(b cipher.Block) (hash.Hash, error) {
+	sharedBlocks.once.Do(func() {
+		a, _ := aes.NewCipher(make([]byte, 16))
+		d, _ := des.NewCipher(make([]byte, 8))
+		t, _ := des.NewTripleDESCipher(make([]byte, 24))
+		sharedBlocks.types = []reflect.Type{reflect.TypeOf(a), reflect.TypeOf(d), reflect.TypeOf(t)}
+	})
+	typ := reflect.TypeOf(b)
+	for _, t := range sharedBlocks.types {
+		if typ == t {
+			c, err := newCMAC(b)
+			if err != nil {
+				return nil, err
+			}
+			return c, nil
+		}
+	}
+	return nil, newError(ErrInvalidArgument, "cmac: cipher block not known to be safe for sharing")
+}
@@ -0,0 +1,49 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"testing"
+)
+
+type wrappedBlock struct{ cipher.Block }
+
+func TestNewSharing(t *testing.T) {
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	msg, _ := hex.DecodeString("6bc1bee22e409f96e93d7e117393172a")
+	mac := "070a16b46b4d4144f79bdd9dd04a287c"
+	b, _ := aes.NewCipher(key)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		h, err := NewSharing(b)
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				h.Reset()
+				h.Write(msg)
+				if hex.EncodeToString(h.Sum(nil)) != mac {
+					t.Errorf("mac mismatch")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	d, _ := des.NewTripleDESCipher(make([]byte, 24))
+	if _, err := NewSharing(d); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := NewSharing(wrappedBlock{b}); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidArgument)
+	}
+}
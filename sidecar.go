@@ -0,0 +1,127 @@
+package cmac
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+)
+
+/* A sidecar is a detached signature stored as a JSON object next to the
+signed file. Byte strings are encoded in standard base64 with padding.
+
+   {
+     "algorithm": "AES-128-CMAC",
+     "key_id": "optional key identifier",
+     "chunk_size": 1048576,
+     "chunks": ["<chunk tag>", ...],
+     "tag": "<tag>"
+   }
+
+When chunk_size is absent or zero, tag is the CMAC of the file content and
+chunks is absent.
+
+When chunk_size is positive, the file content is split in chunks of
+chunk_size bytes, the last chunk being possibly shorter. An empty file has no
+chunk. With big endian 64 bit unsigned integers, the tag of chunk i, starting
+at 0, is
+
+   chunk_tag_i = CMAC(K, uint64(i) || chunk_i)
+
+and the tag of the file is
+
+   tag = CMAC(K, uint64(file length) || chunk_tag_0 || ... || chunk_tag_n-1)
+*/
+
+// ErrMismatch is returned when a MAC doesn't match the data.
+var ErrMismatch = errors.New("cmac: MAC mismatch")
+
+// Sidecar is a detached signature of a file. Its JSON encoding is the sidecar
+// format.
+type Sidecar struct {
+	Algorithm Algorithm `json:"algorithm"`
+	KeyID     string    `json:"key_id,omitempty"`
+	ChunkSize int64     `json:"chunk_size,omitempty"`
+	Chunks    [][]byte  `json:"chunks,omitempty"`
+	Tag       []byte    `json:"tag"`
+}
+
+// NewSidecar returns the sidecar of the data read from r with the algorithm
+// alg and the key. The data is chunked when chunkSize is positive.
+func NewSidecar(alg Algorithm, key []byte, keyID string, r io.Reader, chunkSize int64) (*Sidecar, error) {
+	h, err := alg.New(key)
+	if err != nil {
+		return nil, err
+	}
+	s := &Sidecar{Algorithm: alg, KeyID: keyID}
+	if chunkSize <= 0 {
+		if _, err := io.Copy(h, r); err != nil {
+			return nil, wrapError(ErrIO, err)
+		}
+		s.Tag = h.Sum(nil)
+		return s, nil
+	}
+	s.ChunkSize = chunkSize
+	s.Chunks, s.Tag, err = sumChunks(h, r, chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Verify returns nil if the data read from r matches the sidecar signature
+// with the given key, and ErrMismatch otherwise.
+func (s *Sidecar) Verify(key []byte, r io.Reader) error {
+	ref, err := NewSidecar(s.Algorithm, key, s.KeyID, r, s.ChunkSize)
+	if err != nil {
+		return err
+	}
+	if !Equal(ref.Tag, s.Tag) || len(ref.Chunks) != len(s.Chunks) {
+		audit("Sidecar.Verify", s.KeyID, -1)
+		return ErrMismatch
+	}
+	for i := range ref.Chunks {
+		if !Equal(ref.Chunks[i], s.Chunks[i]) {
+			audit("Sidecar.Verify", s.KeyID, -1)
+			return ErrMismatch
+		}
+	}
+	return nil
+}
+
+// sumChunks returns the chunk tags and the tag of the data read from r as
+// defined by the chunked sidecar format.
+func sumChunks(h hash.Hash, r io.Reader, chunkSize int64) ([][]byte, []byte, error) {
+	var chunks [][]byte
+	var total uint64
+	var hdr [8]byte
+	for i := uint64(0); ; i++ {
+		h.Reset()
+		binary.BigEndian.PutUint64(hdr[:], i)
+		h.Write(hdr[:])
+		n, err := io.CopyN(h, r, chunkSize)
+		if n > 0 {
+			chunks = append(chunks, h.Sum(nil))
+			total += uint64(n)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, wrapError(ErrIO, err)
+		}
+	}
+	return chunks, sumChunkTags(h, total, chunks), nil
+}
+
+// sumChunkTags returns the tag of the data of length total with the given chunk tags.
+func sumChunkTags(h hash.Hash, total uint64, chunks [][]byte) []byte {
+	var hdr [8]byte
+	h.Reset()
+	binary.BigEndian.PutUint64(hdr[:], total)
+	h.Write(hdr[:])
+	for _, c := range chunks {
+		h.Write(c)
+	}
+	return h.Sum(nil)
+}
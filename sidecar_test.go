@@ -0,0 +1,70 @@
+package cmac
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+)
+
+func TestSidecar(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	data := bytes.Repeat([]byte("some file content "), 100)
+	for _, chunkSize := range []int64{0, 1, 100, 1800, 4096} {
+		s, err := NewSidecar(AES128, key, "k1", bytes.NewReader(data), chunkSize)
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %s", chunkSize, err)
+		}
+		js, err := json.Marshal(s)
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %s", chunkSize, err)
+		}
+		var s2 Sidecar
+		if err := json.Unmarshal(js, &s2); err != nil {
+			t.Fatalf("%d: unexpected error: %s", chunkSize, err)
+		}
+		if err := s2.Verify(key, bytes.NewReader(data)); err != nil {
+			t.Errorf("%d: unexpected error: %s", chunkSize, err)
+		}
+		data[len(data)-1] ^= 1
+		if err := s2.Verify(key, bytes.NewReader(data)); err != ErrMismatch {
+			t.Errorf("%d: got error %v, expected %v", chunkSize, err, ErrMismatch)
+		}
+		data[len(data)-1] ^= 1
+		if err := s2.Verify(key, bytes.NewReader(data[:len(data)-1])); err != ErrMismatch {
+			t.Errorf("%d: got error %v, expected %v", chunkSize, err, ErrMismatch)
+		}
+	}
+
+	// chunk tags as defined by the format
+	s, _ := NewSidecar(AES128, key, "", bytes.NewReader(data), 1000)
+	if len(s.Chunks) != 2 {
+		t.Fatalf("got %d chunks, expected 2", len(s.Chunks))
+	}
+	h, _ := AES128.New(key)
+	h.Write([]byte{0, 0, 0, 0, 0, 0, 0, 1})
+	h.Write(data[1000:])
+	if !bytes.Equal(h.Sum(nil), s.Chunks[1]) {
+		t.Errorf("chunk tag mismatch")
+	}
+	h.Reset()
+	var hdr [8]byte
+	binary.BigEndian.PutUint64(hdr[:], uint64(len(data)))
+	h.Write(hdr[:])
+	h.Write(s.Chunks[0])
+	h.Write(s.Chunks[1])
+	if !bytes.Equal(h.Sum(nil), s.Tag) {
+		t.Errorf("tag mismatch")
+	}
+
+	s, _ = NewSidecar(AES128, key, "", bytes.NewReader(nil), 1000)
+	if len(s.Chunks) != 0 {
+		t.Errorf("got %d chunks for empty data, expected 0", len(s.Chunks))
+	}
+	if _, err := NewSidecar(AES256, key, "", bytes.NewReader(data), 0); err == nil {
+		t.Errorf("unexpected nil error for invalid key size")
+	}
+	if err := s.Verify(key[:3], bytes.NewReader(data)); err == nil {
+		t.Errorf("unexpected nil error for invalid key size")
+	}
+}
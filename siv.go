@@ -0,0 +1,216 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+)
+
+/* SIV implements the AEAD_AES_SIV_CMAC_256, 384 and 512 algorithms of
+RFC 5297. The key is split by SplitSIVKey into the S2V key K1 and the CTR
+key K2. The nonce is the last associated data component before the
+plaintext, as specified in section 6 of RFC 5297:
+
+   V = S2V(K1, A, N, P)
+   C = CTR(K2, Q, P) where Q is V with bits 31 and 63 cleared
+
+The ciphertext is V || C. The AEADs are safe for concurrent use.
+*/
+
+const (
+	sivNonceSize = 16
+
+	// MaxSIVComponents is the maximum number of associated data components
+	// accepted by SealVec and OpenVec.
+	MaxSIVComponents = 126
+)
+
+var (
+	// ErrInvalidSIVKey is returned when a SIV key is not 32, 48 or 64 bytes long.
+	ErrInvalidSIVKey = newError(ErrInvalidKey, "cmac: invalid SIV key size")
+
+	// ErrOpen is returned when the authentication of a SIV ciphertext fails.
+	ErrOpen = newError(ErrInvalidArgument, "cmac: message authentication failed")
+)
+
+// SplitSIVKey splits the SIV key as specified by RFC 5297 section 2.6. The
+// first half is the S2V key K1 and the second half is the CTR key K2. The
+// key must be 32, 48 or 64 bytes long for AES-SIV-CMAC-256, 384 and 512.
+// The returned keys reference key.
+func SplitSIVKey(key []byte) (macKey, ctrKey []byte, err error) {
+	switch len(key) {
+	case 32, 48, 64:
+	default:
+		return nil, nil, ErrInvalidSIVKey
+	}
+	n := len(key) / 2
+	return key[:n:n], key[n:], nil
+}
+
+// SIV is the AES-SIV-CMAC authenticated encryption of RFC 5297. Besides
+// the cipher.AEAD methods, it accepts a vector of associated data components
+// with SealVec and OpenVec.
+type SIV struct {
+	mac cipher.Block
+	ctr cipher.Block
+}
+
+// NewSIV256 returns the AEAD_AES_SIV_CMAC_256 AEAD with the 32 byte key. Its
+// nonce size is 16 bytes.
+func NewSIV256(key []byte) (cipher.AEAD, error) { return newSIV(key, 32) }
+
+// NewSIV384 returns the AEAD_AES_SIV_CMAC_384 AEAD with the 48 byte key. Its
+// nonce size is 16 bytes.
+func NewSIV384(key []byte) (cipher.AEAD, error) { return newSIV(key, 48) }
+
+// NewSIV512 returns the AEAD_AES_SIV_CMAC_512 AEAD with the 64 byte key. Its
+// nonce size is 16 bytes.
+func NewSIV512(key []byte) (cipher.AEAD, error) { return newSIV(key, 64) }
+
+// NewSIV returns the AES-SIV-CMAC with the 32, 48 or 64 byte key.
+func NewSIV(key []byte) (*SIV, error) { return newSIV(key, len(key)) }
+
+func newSIV(key []byte, size int) (*SIV, error) {
+	if len(key) != size {
+		return nil, ErrInvalidSIVKey
+	}
+	k1, k2, err := SplitSIVKey(key)
+	if err != nil {
+		return nil, err
+	}
+	s := new(SIV)
+	if s.mac, err = aes.NewCipher(k1); err != nil {
+		return nil, wrapError(ErrInvalidKey, err)
+	}
+	if s.ctr, err = aes.NewCipher(k2); err != nil {
+		return nil, wrapError(ErrInvalidKey, err)
+	}
+	return s, nil
+}
+
+// NonceSize returns the nonce size of Seal and Open, which is 16 bytes.
+func (s *SIV) NonceSize() int { return sivNonceSize }
+
+// Overhead returns the size of V, which is 16 bytes.
+func (s *SIV) Overhead() int { return aes.BlockSize }
+
+// Seal encrypts and authenticates the plaintext with the nonce and
+// additional data, and appends the result to dst.
+func (s *SIV) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != sivNonceSize {
+		panic("cmac: incorrect nonce length given to SIV")
+	}
+	return s.seal(dst, [][]byte{additionalData, nonce}, plaintext)
+}
+
+// Open decrypts and authenticates the ciphertext with the nonce and
+// additional data, and appends the plaintext to dst.
+func (s *SIV) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != sivNonceSize {
+		panic("cmac: incorrect nonce length given to SIV")
+	}
+	return s.open(dst, [][]byte{additionalData, nonce}, ciphertext)
+}
+
+// SealVec encrypts and authenticates the plaintext with the vector of
+// associated data components, and appends V || C to dst. A nonce, if any, is
+// the last component. It panics if there are more than MaxSIVComponents
+// components.
+func (s *SIV) SealVec(dst, plaintext []byte, ad ...[]byte) []byte {
+	if len(ad) > MaxSIVComponents {
+		panic("cmac: too many SIV associated data components")
+	}
+	return s.seal(dst, ad, plaintext)
+}
+
+// OpenVec decrypts and authenticates the ciphertext V || C with the vector
+// of associated data components, and appends the plaintext to dst.
+func (s *SIV) OpenVec(dst, ciphertext []byte, ad ...[]byte) ([]byte, error) {
+	if len(ad) > MaxSIVComponents {
+		return nil, newError(ErrInvalidArgument, "cmac: too many SIV associated data components")
+	}
+	return s.open(dst, ad, ciphertext)
+}
+
+// seal appends V || C to dst, where the associated data components ad
+// include the nonce.
+func (s *SIV) seal(dst []byte, ad [][]byte, plaintext []byte) []byte {
+	v := s.s2v(ad, plaintext)
+	ret, out := sliceForAppend(dst, len(v)+len(plaintext))
+	copy(out[len(v):], plaintext)
+	s.xorCTR(out[len(v):], v)
+	copy(out, v)
+	return ret
+}
+
+// open appends the plaintext of V || C to dst.
+func (s *SIV) open(dst []byte, ad [][]byte, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < aes.BlockSize {
+		return nil, ErrOpen
+	}
+	v := append([]byte(nil), ciphertext[:aes.BlockSize]...)
+	ret, out := sliceForAppend(dst, len(ciphertext)-len(v))
+	copy(out, ciphertext[len(v):])
+	s.xorCTR(out, v)
+	if !Equal(s.s2v(ad, out), v) {
+		zero(out)
+		audit("SIV.Open", "", int64(len(out)))
+		return nil, ErrOpen
+	}
+	return ret, nil
+}
+
+// xorCTR xors b in place with the AES-CTR key stream of K2 with the initial
+// counter derived from v.
+func (s *SIV) xorCTR(b, v []byte) {
+	var q [aes.BlockSize]byte
+	copy(q[:], v)
+	q[8] &= 0x7f
+	q[12] &= 0x7f
+	cipher.NewCTR(s.ctr, q[:]).XORKeyStream(b, b)
+}
+
+// s2v returns the S2V of the associated data components followed by the
+// plaintext.
+func (s *SIV) s2v(ad [][]byte, plaintext []byte) []byte {
+	mac, _ := New(func([]byte) (cipher.Block, error) { return s.mac, nil }, nil)
+	var zero [aes.BlockSize]byte
+	mac.Write(zero[:])
+	d := mac.Sum(nil)
+	for _, a := range ad {
+		dbl(d)
+		mac.Reset()
+		mac.Write(a)
+		xor(d, mac.Sum(zero[:0]))
+	}
+	mac.Reset()
+	if n := len(plaintext); n >= aes.BlockSize {
+		mac.Write(plaintext[:n-aes.BlockSize])
+		xor(d, plaintext[n-aes.BlockSize:])
+	} else {
+		dbl(d)
+		xor(d, plaintext)
+		d[n] ^= 0x80
+	}
+	mac.Write(d)
+	return mac.Sum(d[:0])
+}
+
+// dbl multiplies the 128 bit block d by x in GF(2^128).
+func dbl(d []byte) {
+	msb := d[0]
+	shiftLeftOneBit(d, d)
+	d[len(d)-1] ^= 0x87 & byte(int8(msb)>>7)
+}
+
+// sliceForAppend extends in by n bytes and returns the extended slice and
+// its last n bytes.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
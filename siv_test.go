@@ -0,0 +1,209 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestSplitSIVKey(t *testing.T) {
+	key := make([]byte, 64)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	for _, n := range []int{32, 48, 64} {
+		k1, k2, err := SplitSIVKey(key[:n])
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", n, err)
+		}
+		if !bytes.Equal(k1, key[:n/2]) || !bytes.Equal(k2, key[n/2:n]) {
+			t.Errorf("%d: got %x and %x", n, k1, k2)
+		}
+	}
+	for _, n := range []int{0, 16, 31, 33, 128} {
+		if _, _, err := SplitSIVKey(make([]byte, n)); err != ErrInvalidSIVKey {
+			t.Errorf("%d: got error %v, expected %v", n, err, ErrInvalidSIVKey)
+		}
+	}
+}
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(strings.Replace(s, " ", "", -1))
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestSIVVectors(t *testing.T) {
+	tests := []struct {
+		key, plaintext, output string
+		ad                     []string
+	}{
+		{ // RFC 5297 A.1 deterministic authenticated encryption
+			key: "fffefdfc fbfaf9f8 f7f6f5f4 f3f2f1f0 f0f1f2f3 f4f5f6f7 f8f9fafb fcfdfeff",
+			ad: []string{
+				"10111213 14151617 18191a1b 1c1d1e1f 20212223 24252627",
+			},
+			plaintext: "11223344 55667788 99aabbcc ddee",
+			output:    "85632d07 c6e8f37f 950acd32 0a2ecc93 40c02b96 90c4dc04 daef7f6a fe5c",
+		},
+		{ // RFC 5297 A.2 nonce-based authenticated encryption
+			key: "7f7e7d7c 7b7a7978 77767574 73727170 40414243 44454647 48494a4b 4c4d4e4f",
+			ad: []string{
+				"00112233 44556677 8899aabb ccddeeff deaddada deaddada ffeeddcc bbaa9988 77665544 33221100",
+				"10203040 50607080 90a0",
+				"09f91102 9d74e35b d84156c5 635688c0",
+			},
+			plaintext: "74686973 20697320 736f6d65 20706c61 696e7465 78742074 6f20656e 63727970 74207573 696e6720 5349562d 414553",
+			output: "7bdb6e3b 432667eb 06f4d14b ff2fbd0f cb900f2f ddbe4043 26601965 c889bf17 " +
+				"dba77ceb 094fa663 b7a3f748 ba8af829 ea64ad54 4a272e9c 485b62a3 fd5c0d",
+		},
+	}
+	for i, tc := range tests {
+		s, err := NewSIV(unhex(tc.key))
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+		var ad [][]byte
+		for _, a := range tc.ad {
+			ad = append(ad, unhex(a))
+		}
+		exp := unhex(tc.output)
+		out := s.SealVec(nil, unhex(tc.plaintext), ad...)
+		if !bytes.Equal(out, exp) {
+			t.Errorf("%d: got %x, expected %x", i, out, exp)
+		}
+		p, err := s.OpenVec(nil, out, ad...)
+		if err != nil || !bytes.Equal(p, unhex(tc.plaintext)) {
+			t.Errorf("%d: got %x %v, expected %s", i, p, err, tc.plaintext)
+		}
+		if _, err := s.OpenVec(nil, out, ad[1:]...); err != ErrOpen {
+			t.Errorf("%d: got error %v, expected %v", i, err, ErrOpen)
+		}
+		if len(ad) > 1 {
+			joined := bytes.Join(ad[:2], nil)
+			if _, err := s.OpenVec(nil, out, append([][]byte{joined}, ad[2:]...)...); err != ErrOpen {
+				t.Errorf("%d: got error %v, expected %v for joined components", i, err, ErrOpen)
+			}
+		}
+		out[len(out)-1] ^= 1
+		if _, err := s.OpenVec(nil, out, ad...); err != ErrOpen {
+			t.Errorf("%d: got error %v, expected %v", i, err, ErrOpen)
+		}
+	}
+
+	s, _ := NewSIV(make([]byte, 48))
+	ad := make([][]byte, MaxSIVComponents)
+	out := s.SealVec(nil, nil, ad...)
+	if _, err := s.OpenVec(nil, out, ad...); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := s.OpenVec(nil, out, append(ad, nil)...); err == nil {
+		t.Errorf("unexpected nil error for too many components")
+	}
+	if _, err := NewSIV(make([]byte, 16)); err != ErrInvalidSIVKey {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidSIVKey)
+	}
+}
+
+func TestSIVAEAD(t *testing.T) {
+	key := make([]byte, 64)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	nonce, ad := key[:16], []byte("header")
+	for i, f := range []func([]byte) (cipher.AEAD, error){NewSIV256, NewSIV384, NewSIV512} {
+		n := 32 + 16*i
+		a, err := f(key[:n])
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", n, err)
+		}
+		if a.NonceSize() != 16 || a.Overhead() != 16 {
+			t.Errorf("%d: invalid nonce size or overhead", n)
+		}
+		if _, err := f(key[:n-16]); err != ErrInvalidSIVKey {
+			t.Errorf("%d: got error %v, expected %v", n, err, ErrInvalidSIVKey)
+		}
+
+		for _, size := range []int{0, 1, 15, 16, 17, 100} {
+			plaintext := bytes.Repeat([]byte{'p'}, size)
+			s, _ := newSIV(key[:n], n)
+			exp := s.seal(nil, [][]byte{ad, nonce}, plaintext)
+			c := a.Seal([]byte("prefix"), nonce, plaintext, ad)
+			if !bytes.Equal(c[6:], exp) || string(c[:6]) != "prefix" {
+				t.Errorf("%d %d: got %x, expected prefix%x", n, size, c, exp)
+			}
+
+			// in place encryption and decryption
+			buf := make([]byte, size, size+16)
+			copy(buf, plaintext)
+			c = a.Seal(buf[:0], nonce, buf, ad)
+			if !bytes.Equal(c, exp) {
+				t.Errorf("%d %d: in place got %x, expected %x", n, size, c, exp)
+			}
+			p, err := a.Open(c[:0], nonce, c, ad)
+			if err != nil || !bytes.Equal(p, plaintext) {
+				t.Errorf("%d %d: got %x %v, expected %x", n, size, p, err, plaintext)
+			}
+			if _, err := a.Open(nil, key[16:32], exp, ad); err != ErrOpen {
+				t.Errorf("%d %d: got error %v, expected %v", n, size, err, ErrOpen)
+			}
+		}
+		if _, err := a.Open(nil, nonce, make([]byte, 15), ad); err != ErrOpen {
+			t.Errorf("%d: got error %v, expected %v", n, err, ErrOpen)
+		}
+	}
+	// Known answers of the OpenSSL AES-192-SIV and AES-256-SIV ciphers,
+	// which also yield the RFC 5297 vectors, with the data of RFC 5297 A.1
+	// and A.2 and the nonce of A.2.
+	vectors := []struct {
+		f                              func([]byte) (cipher.AEAD, error)
+		key, ad, plaintext, ciphertext string
+	}{
+		{
+			f:          NewSIV384,
+			key:        "00010203 04050607 08090a0b 0c0d0e0f 10111213 14151617 18191a1b 1c1d1e1f 20212223 24252627 28292a2b 2c2d2e2f",
+			ad:         "00112233 44556677 8899aabb ccddeeff deaddada deaddada ffeeddcc bbaa9988 77665544 33221100",
+			plaintext:  "74686973 20697320 736f6d65 20706c61 696e7465 78742074 6f20656e 63727970 74207573 696e6720 5349562d 414553",
+			ciphertext: "87cd2bed ec8ef361 0767029e 8d3b612b b3e9cb23 110f2178 9740b47e 60dba7b6 dc94c771 f3a9c567 d662eaa0 be62d07b b6ae09ae cfb3ce51 df8e9762 fe531b",
+		},
+		{
+			f:          NewSIV384,
+			key:        "00010203 04050607 08090a0b 0c0d0e0f 10111213 14151617 18191a1b 1c1d1e1f 20212223 24252627 28292a2b 2c2d2e2f",
+			ad:         "10111213 14151617 18191a1b 1c1d1e1f 20212223 24252627",
+			plaintext:  "11223344 55667788 99aabbcc ddee",
+			ciphertext: "257db9b7 c3e345fc c31c901a 28ec404f 45b0cb4e f6c6f388 4203258b 1721",
+		},
+		{
+			f:          NewSIV512,
+			key:        "00010203 04050607 08090a0b 0c0d0e0f 10111213 14151617 18191a1b 1c1d1e1f 20212223 24252627 28292a2b 2c2d2e2f 30313233 34353637 38393a3b 3c3d3e3f",
+			ad:         "00112233 44556677 8899aabb ccddeeff deaddada deaddada ffeeddcc bbaa9988 77665544 33221100",
+			plaintext:  "74686973 20697320 736f6d65 20706c61 696e7465 78742074 6f20656e 63727970 74207573 696e6720 5349562d 414553",
+			ciphertext: "a9dd2971 f34fda46 4dce592a 3705a34e a38433f9 e3f38505 76e0ebe4 0d67c9bb fa4ce09a 5a787031 fe546715 d17ac45f fd08ab5c fa6d81b4 a15e7229 5d6953",
+		},
+		{
+			f:          NewSIV512,
+			key:        "00010203 04050607 08090a0b 0c0d0e0f 10111213 14151617 18191a1b 1c1d1e1f 20212223 24252627 28292a2b 2c2d2e2f 30313233 34353637 38393a3b 3c3d3e3f",
+			ad:         "10111213 14151617 18191a1b 1c1d1e1f 20212223 24252627",
+			plaintext:  "11223344 55667788 99aabbcc ddee",
+			ciphertext: "47be0f68 1f30b551 85c5fda3 1c92adf2 340706eb a87e8439 9b04e31b 4d69",
+		},
+	}
+	nonce = unhex("09f91102 9d74e35b d84156c5 635688c0")
+	for i, tc := range vectors {
+		a, err := tc.f(unhex(tc.key))
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+		exp := unhex(tc.ciphertext)
+		if c := a.Seal(nil, nonce, unhex(tc.plaintext), unhex(tc.ad)); !bytes.Equal(c, exp) {
+			t.Errorf("%d: got %x, expected %x", i, c, exp)
+		}
+		if p, err := a.Open(nil, nonce, exp, unhex(tc.ad)); err != nil || !bytes.Equal(p, unhex(tc.plaintext)) {
+			t.Errorf("%d: got %x %v, expected %s", i, p, err, tc.plaintext)
+		}
+	}
+}
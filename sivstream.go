@@ -0,0 +1,205 @@
+package cmac
+
+import (
+	"bufio"
+	"crypto/aes"
+	"encoding/binary"
+	"io"
+)
+
+/* A chunked SIV stream splits the plaintext in chunks of chunkSize bytes.
+All chunks but the last one are full, and the last one may be empty. The
+key of each chunk is derived with a Ratchet whose root is the AES key K,
+yielding AES-SIV-CMAC keys twice as long as K. Chunk i is sealed as
+
+   V_i || C_i = SIV(K_i, AD, uint64(i) || final, V_i-1, P_i)
+
+where AD is the stream associated data, uint64(i) is big endian, final is
+the byte 1 for the last chunk and 0 otherwise, and V_-1 is empty. The
+stream is the concatenation of the sealed chunks. Chaining V and flagging
+the last chunk detect reordered, dropped and truncated chunks.
+*/
+
+// NewChunkedSIVWriter returns a writer encrypting the data written to it in
+// chunks of chunkSize bytes into w with the AES key of 16, 24 or 32 bytes
+// and the associated data ad. Close must be called to write the last chunk.
+// Chunks are encrypted with distinct keys, and at most chunkSize bytes are
+// buffered. The WithProgress option reports the plaintext bytes of the
+// chunks written to w.
+func NewChunkedSIVWriter(w io.Writer, key, ad []byte, chunkSize int, opts ...FileOption) (io.WriteCloser, error) {
+	s, err := newSIVStream(key, ad, chunkSize, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &sivWriter{sivStream: s, w: w, buf: make([]byte, 0, chunkSize)}, nil
+}
+
+// NewChunkedSIVReader returns a reader decrypting the stream read from r
+// written by a chunked SIV writer with the same key, associated data and
+// chunk size. The reader returns ErrOpen when the stream was modified or
+// truncated. The data of a chunk is only returned after its authentication.
+// The WithProgress option reports the plaintext bytes of the chunks opened.
+func NewChunkedSIVReader(r io.Reader, key, ad []byte, chunkSize int, opts ...FileOption) (io.Reader, error) {
+	s, err := newSIVStream(key, ad, chunkSize, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &sivReader{
+		sivStream: s,
+		r:         bufio.NewReader(r),
+		buf:       make([]byte, chunkSize+aes.BlockSize),
+	}, nil
+}
+
+type sivStream struct {
+	ratchet   *Ratchet
+	ad        []byte
+	chunkSize int
+	prev      []byte
+	done      bool
+	progress  *progress
+}
+
+func newSIVStream(key, ad []byte, chunkSize int, opts []FileOption) (*sivStream, error) {
+	if chunkSize <= 0 {
+		return nil, newError(ErrInvalidArgument, "cmac: invalid chunk size")
+	}
+	r, err := newRatchet(aes.NewCipher, key, 2*len(key))
+	if err != nil {
+		return nil, err
+	}
+	return &sivStream{
+		ratchet:   r,
+		ad:        append([]byte(nil), ad...),
+		chunkSize: chunkSize,
+		progress:  newFileOptions(opts).progress,
+	}, nil
+}
+
+// chunk returns the SIV of the next chunk and its associated data.
+func (s *sivStream) chunk(final bool) (*SIV, [][]byte, error) {
+	i, k, err := s.ratchet.Next()
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := NewSIV(k)
+	if err != nil {
+		return nil, nil, err
+	}
+	hdr := make([]byte, 9)
+	binary.BigEndian.PutUint64(hdr, i)
+	if final {
+		hdr[8] = 1
+	}
+	return c, [][]byte{s.ad, hdr, s.prev}, nil
+}
+
+type sivWriter struct {
+	*sivStream
+	w   io.Writer
+	buf []byte
+	out []byte
+}
+
+func (w *sivWriter) Write(p []byte) (n int, err error) {
+	if w.done {
+		return 0, newError(ErrInvalidArgument, "cmac: write to closed chunked SIV writer")
+	}
+	for len(p) > 0 {
+		if len(w.buf) == w.chunkSize {
+			if err = w.flush(false); err != nil {
+				return
+			}
+		}
+		m := copy(w.buf[len(w.buf):w.chunkSize], p)
+		w.buf = w.buf[:len(w.buf)+m]
+		p = p[m:]
+		n += m
+	}
+	return
+}
+
+// Close writes the last chunk. It doesn't close the underlying writer.
+func (w *sivWriter) Close() error {
+	if w.done {
+		return nil
+	}
+	w.done = true
+	return w.flush(true)
+}
+
+// flush writes the buffered chunk.
+func (w *sivWriter) flush(final bool) error {
+	c, ad, err := w.chunk(final)
+	if err != nil {
+		return err
+	}
+	w.out = c.SealVec(w.out[:0], w.buf, ad...)
+	w.prev = append(w.prev[:0], w.out[:aes.BlockSize]...)
+	n := len(w.buf)
+	w.buf = w.buf[:0]
+	if _, err = w.w.Write(w.out); err != nil {
+		return err
+	}
+	w.progress.add(n)
+	if final {
+		w.progress.done()
+	}
+	return nil
+}
+
+type sivReader struct {
+	*sivStream
+	r    *bufio.Reader
+	buf  []byte
+	data []byte
+	err  error
+}
+
+func (r *sivReader) Read(p []byte) (n int, err error) {
+	for len(r.data) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		r.err = r.next()
+	}
+	n = copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// next reads and opens the next chunk.
+func (r *sivReader) next() error {
+	n, err := io.ReadFull(r.r, r.buf)
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	final := n < len(r.buf)
+	if !final {
+		if _, err := r.r.Peek(1); err == io.EOF {
+			final = true
+		} else if err != nil {
+			return err
+		}
+	}
+	c, ad, err := r.chunk(final)
+	if err != nil {
+		return err
+	}
+	if r.data, err = c.OpenVec(r.buf[aes.BlockSize:aes.BlockSize], r.buf[:n], ad...); err != nil {
+		return ErrOpen
+	}
+	r.prev = append(r.prev[:0], r.buf[:aes.BlockSize]...)
+	r.done = final
+	r.progress.add(len(r.data))
+	if final {
+		r.progress.done()
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package cmac
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestChunkedSIV(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 16)
+	ad := []byte("backup 42")
+	const chunkSize = 32
+	for _, size := range []int{0, 1, 31, 32, 33, 64, 100} {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i)
+		}
+		var buf bytes.Buffer
+		w, err := NewChunkedSIVWriter(&buf, key, ad, chunkSize)
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		w.Write(data[:size/3])
+		w.Write(data[size/3:])
+		if err := w.Close(); err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		chunks := size/chunkSize + 1
+		if size > 0 && size%chunkSize == 0 {
+			chunks--
+		}
+		if buf.Len() != size+16*chunks {
+			t.Errorf("%d: got stream length %d, expected %d", size, buf.Len(), size+16*chunks)
+		}
+		stream := buf.Bytes()
+
+		r, _ := NewChunkedSIVReader(bytes.NewReader(stream), key, ad, chunkSize)
+		got, err := io.ReadAll(r)
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("%d: got %x %v, expected %x", size, got, err, data)
+		}
+
+		bad := [][]byte{
+			stream[:len(stream)-1],
+			append(append([]byte(nil), stream...), 0),
+		}
+		if chunks > 1 {
+			// drop the last chunk, or swap the first two chunks
+			bad = append(bad, stream[:chunkSize+16])
+			end := 2 * (chunkSize + 16)
+			if end > len(stream) {
+				end = len(stream)
+			}
+			swapped := append([]byte(nil), stream[chunkSize+16:end]...)
+			swapped = append(swapped, stream[:chunkSize+16]...)
+			bad = append(bad, append(swapped, stream[end:]...))
+		}
+		for i, b := range bad {
+			r, _ := NewChunkedSIVReader(bytes.NewReader(b), key, ad, chunkSize)
+			if _, err := io.ReadAll(r); err != ErrOpen {
+				t.Errorf("%d %d: got error %v, expected %v", size, i, err, ErrOpen)
+			}
+		}
+		r, _ = NewChunkedSIVReader(bytes.NewReader(stream), key, []byte("other"), chunkSize)
+		if _, err := io.ReadAll(r); err != ErrOpen {
+			t.Errorf("%d: got error %v, expected %v", size, err, ErrOpen)
+		}
+	}
+
+	if _, err := NewChunkedSIVWriter(io.Discard, key[:5], nil, 10); err == nil {
+		t.Errorf("unexpected nil error for invalid key")
+	}
+	if _, err := NewChunkedSIVReader(nil, key, nil, 0); err == nil {
+		t.Errorf("unexpected nil error for invalid chunk size")
+	}
+	w, _ := NewChunkedSIVWriter(io.Discard, key, nil, 10)
+	w.Close()
+	if _, err := w.Write([]byte{1}); err == nil {
+		t.Errorf("unexpected nil error for write after close")
+	}
+}
+
+func TestChunkedSIVProgress(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 16)
+	var wrote, read []int64
+	var buf bytes.Buffer
+	w, err := NewChunkedSIVWriter(&buf, key, nil, 32, WithProgress(64, func(n int64) { wrote = append(wrote, n) }))
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	w.Write(make([]byte, 200))
+	if err := w.Close(); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	r, err := NewChunkedSIVReader(&buf, key, nil, 32, WithProgress(64, func(n int64) { read = append(read, n) }))
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if _, err := io.ReadAll(r); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	expected := []int64{64, 128, 192, 200}
+	for _, got := range [][]int64{wrote, read} {
+		if len(got) != len(expected) {
+			t.Errorf("got progress %v, expected %v", got, expected)
+			continue
+		}
+		for i := range got {
+			if got[i] != expected[i] {
+				t.Errorf("got progress %v, expected %v", got, expected)
+				break
+			}
+		}
+	}
+}
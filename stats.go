@@ -0,0 +1,75 @@
+package cmac
+
+import (
+	"hash"
+	"sync"
+)
+
+// KeyStats are the operation counters of a key ID.
+type KeyStats struct {
+	MACs     uint64 `json:"macs"`     // number of MACs computed
+	Bytes    uint64 `json:"bytes"`    // number of bytes processed
+	Failures uint64 `json:"failures"` // number of rejected tags
+}
+
+// Stats counts the operations of a Registry per key ID. Except with the
+// cmac_small build tag, it implements expvar.Var so that it may be published
+// with expvar.Publish. A Stats is safe for concurrent use.
+type Stats struct {
+	mu   sync.Mutex
+	keys map[string]*KeyStats
+}
+
+// NewStats returns empty statistics.
+func NewStats() *Stats {
+	return &Stats{keys: make(map[string]*KeyStats)}
+}
+
+// Add adds macs, bytes and failures to the counters of the key ID.
+func (s *Stats) Add(keyID string, macs, bytes, failures uint64) {
+	s.mu.Lock()
+	k := s.keys[keyID]
+	if k == nil {
+		k = new(KeyStats)
+		s.keys[keyID] = k
+	}
+	k.MACs += macs
+	k.Bytes += bytes
+	k.Failures += failures
+	s.mu.Unlock()
+}
+
+// Snapshot returns a copy of the counters per key ID.
+func (s *Stats) Snapshot() map[string]KeyStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := make(map[string]KeyStats, len(s.keys))
+	for id, k := range s.keys {
+		m[id] = *k
+	}
+	return m
+}
+
+// Reset clears all counters.
+func (s *Stats) Reset() {
+	s.mu.Lock()
+	s.keys = make(map[string]*KeyStats)
+	s.mu.Unlock()
+}
+
+// statsHash is a hash counting its MACs and bytes in stats.
+type statsHash struct {
+	hash.Hash
+	stats *Stats
+	keyID string
+}
+
+func (h *statsHash) Write(p []byte) (int, error) {
+	h.stats.Add(h.keyID, 0, uint64(len(p)), 0)
+	return h.Hash.Write(p)
+}
+
+func (h *statsHash) Sum(b []byte) []byte {
+	h.stats.Add(h.keyID, 1, 0, 0)
+	return h.Hash.Sum(b)
+}
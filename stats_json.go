@@ -0,0 +1,12 @@
+//go:build !cmac_small
+// +build !cmac_small
+
+package cmac
+
+import "encoding/json"
+
+// String returns the JSON encoding of the snapshot. It implements expvar.Var.
+func (s *Stats) String() string {
+	b, _ := json.Marshal(s.Snapshot())
+	return string(b)
+}
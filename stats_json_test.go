@@ -0,0 +1,24 @@
+//go:build !cmac_small
+// +build !cmac_small
+
+package cmac
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+)
+
+var _ expvar.Var = (*Stats)(nil)
+
+func TestStatsString(t *testing.T) {
+	s := NewStats()
+	s.Add("k1", 2, 10, 1)
+	var m map[string]KeyStats
+	if err := json.Unmarshal([]byte(s.String()), &m); err != nil {
+		t.Fatalf("got %s, %v", s.String(), err)
+	}
+	if exp := (KeyStats{MACs: 2, Bytes: 10, Failures: 1}); len(m) != 1 || m["k1"] != exp {
+		t.Errorf("got %v, expected %v", m, exp)
+	}
+}
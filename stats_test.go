@@ -0,0 +1,47 @@
+package cmac
+
+import "testing"
+
+func TestStats(t *testing.T) {
+	r := NewRegistry(nil)
+	r.Add("k1", AES128, []byte("0123456789abcdef"))
+	r.Add("k2", AES128, []byte("fedcba9876543210"))
+	s := NewStats()
+	r.SetStats(s)
+
+	env, _ := r.Sign("k1", []byte("hello"))
+	r.Verify([]byte("hello"), env)
+	r.Verify([]byte("hello!"), env)
+	h, _ := r.Hash("k2")
+	h.Write([]byte("abc"))
+	tag := h.Sum(nil)
+	VerifyBatch(r, []BatchItem{
+		{KeyID: "k2", Msg: []byte("abc"), Tag: tag},
+		{KeyID: "k2", Msg: []byte("abd"), Tag: tag},
+		{KeyID: "k3", Msg: []byte("abc"), Tag: tag},
+	})
+
+	exp := map[string]KeyStats{
+		"k1": {MACs: 3, Bytes: 16, Failures: 1},
+		"k2": {MACs: 3, Bytes: 9, Failures: 1},
+	}
+	got := s.Snapshot()
+	if len(got) != len(exp) {
+		t.Fatalf("got %v, expected %v", got, exp)
+	}
+	for id, v := range exp {
+		if got[id] != v {
+			t.Errorf("%s: got %+v, expected %+v", id, got[id], v)
+		}
+	}
+
+	s.Reset()
+	if len(s.Snapshot()) != 0 {
+		t.Errorf("got %v after reset", s.Snapshot())
+	}
+	r.SetStats(nil)
+	r.Sign("k1", []byte("hello"))
+	if len(s.Snapshot()) != 0 {
+		t.Errorf("got %v with stats disabled", s.Snapshot())
+	}
+}
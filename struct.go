@@ -0,0 +1,177 @@
+//go:build !cmac_small
+// +build !cmac_small
+
+package cmac
+
+import (
+	"encoding"
+	"encoding/binary"
+	"hash"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+/* SumStruct computes the CMAC of the following deterministic encoding of a
+value, where integers are big endian and lengths are uint32:
+
+   nil pointer or interface   'n'
+   non-nil pointer            'p' || encoding of the pointed value
+   bool                       'b' || 0 or 1
+   signed integer             'i' || int64
+   unsigned integer           'u' || uint64
+   float                      'f' || IEEE 754 binary64 representation
+   string                     's' || length || bytes
+   encoding.BinaryMarshaler   'x' || length || MarshalBinary bytes
+   []byte or [N]byte          'y' || length || bytes
+   slice or array             'l' || length || elements
+   map                        'm' || length || key and value encodings, sorted by key encoding
+   struct                     't' || number of fields || for each field sorted by name: length || name || value
+
+Only exported struct fields are encoded. The field name may be changed with
+a `cmac:"name"` field tag and a field is ignored with `cmac:"-"`. Renaming a
+Go field with a tag preserving the encoded name, or reordering fields, keeps
+the encoding unchanged.
+*/
+
+// maxStructDepth is the maximum nesting depth of encoded values.
+const maxStructDepth = 64
+
+// ErrUnsupportedType is returned when a value can't be encoded by SumStruct.
+var ErrUnsupportedType = newError(ErrInvalidArgument, "cmac: unsupported type")
+
+var binaryMarshalerType = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+
+// SumStruct returns the CMAC computed with h, which is reset, of the
+// deterministic encoding of v. The encoding is described in struct.go. Channels,
+// functions, complex numbers and nesting deeper than 64 levels are not supported.
+func SumStruct(h hash.Hash, v interface{}) ([]byte, error) {
+	b, err := appendValue(nil, reflect.ValueOf(v), 0)
+	if err != nil {
+		return nil, err
+	}
+	h.Reset()
+	h.Write(b)
+	return h.Sum(nil), nil
+}
+
+func appendLen(b []byte, n int) []byte {
+	var l [4]byte
+	binary.BigEndian.PutUint32(l[:], uint32(n))
+	return append(b, l[:]...)
+}
+
+func appendValue(b []byte, v reflect.Value, depth int) ([]byte, error) {
+	if depth > maxStructDepth {
+		return nil, ErrUnsupportedType
+	}
+	if !v.IsValid() {
+		return append(b, 'n'), nil
+	}
+	if v.Type().Implements(binaryMarshalerType) && (v.Kind() != reflect.Ptr || !v.IsNil()) {
+		p, err := v.Interface().(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		return append(appendLen(append(b, 'x'), len(p)), p...), nil
+	}
+	var n [8]byte
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return append(b, 'n'), nil
+		}
+		if v.Kind() == reflect.Ptr {
+			b = append(b, 'p')
+		}
+		return appendValue(b, v.Elem(), depth+1)
+	case reflect.Bool:
+		if v.Bool() {
+			return append(b, 'b', 1), nil
+		}
+		return append(b, 'b', 0), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		binary.BigEndian.PutUint64(n[:], uint64(v.Int()))
+		return append(append(b, 'i'), n[:]...), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		binary.BigEndian.PutUint64(n[:], v.Uint())
+		return append(append(b, 'u'), n[:]...), nil
+	case reflect.Float32, reflect.Float64:
+		binary.BigEndian.PutUint64(n[:], math.Float64bits(v.Float()))
+		return append(append(b, 'f'), n[:]...), nil
+	case reflect.String:
+		return append(appendLen(append(b, 's'), v.Len()), v.String()...), nil
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b = appendLen(append(b, 'y'), v.Len())
+			for i := 0; i < v.Len(); i++ {
+				b = append(b, byte(v.Index(i).Uint()))
+			}
+			return b, nil
+		}
+		b = appendLen(append(b, 'l'), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			var err error
+			if b, err = appendValue(b, v.Index(i), depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case reflect.Map:
+		type entry struct{ k, v []byte }
+		entries := make([]entry, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			k, err := appendValue(nil, iter.Key(), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			e, err := appendValue(nil, iter.Value(), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry{k, e})
+		}
+		sort.Slice(entries, func(i, j int) bool { return string(entries[i].k) < string(entries[j].k) })
+		b = appendLen(append(b, 'm'), len(entries))
+		for _, e := range entries {
+			b = append(append(b, e.k...), e.v...)
+		}
+		return b, nil
+	case reflect.Struct:
+		type field struct {
+			name string
+			idx  int
+		}
+		var fields []field
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			name := f.Name
+			if tag := f.Tag.Get("cmac"); tag == "-" {
+				continue
+			} else if tag != "" {
+				name = strings.Split(tag, ",")[0]
+			}
+			fields = append(fields, field{name, i})
+		}
+		sort.Slice(fields, func(i, j int) bool { return fields[i].name < fields[j].name })
+		b = appendLen(append(b, 't'), len(fields))
+		for i, f := range fields {
+			if i > 0 && f.name == fields[i-1].name {
+				return nil, ErrUnsupportedType
+			}
+			b = append(appendLen(b, len(f.name)), f.name...)
+			var err error
+			if b, err = appendValue(b, v.Field(f.idx), depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+	return nil, ErrUnsupportedType
+}
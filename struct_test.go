@@ -0,0 +1,105 @@
+//go:build !cmac_small
+// +build !cmac_small
+
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Name     string
+	Port     uint16
+	Ratio    float32
+	Enabled  bool
+	Tags     []string
+	Limits   map[string]int
+	Key      []byte
+	Parent   *testConfig
+	Created  time.Time
+	Comment  string `cmac:"-"`
+	Renamed  int    `cmac:"old_name"`
+	internal int
+}
+
+// testConfigV2 is testConfig with reordered and renamed fields.
+type testConfigV2 struct {
+	Renamed2 int `cmac:"old_name"`
+	Created  time.Time
+	Parent   *testConfig
+	Key      []byte
+	Limits   map[string]int
+	Tags     []string
+	Enabled  bool
+	Ratio    float32
+	Port     uint16
+	Name     string
+}
+
+func TestSumStruct(t *testing.T) {
+	h, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	c1 := testConfig{Name: "srv", Port: 80, Ratio: 0.5, Enabled: true, Tags: []string{"a", "b"},
+		Limits: map[string]int{"x": 1, "y": 2, "z": 3}, Key: []byte{1}, Created: ts, Comment: "ignored", Renamed: 7, internal: 1}
+	c2 := testConfigV2{Name: "srv", Port: 80, Ratio: 0.5, Enabled: true, Tags: []string{"a", "b"},
+		Limits: map[string]int{"z": 3, "y": 2, "x": 1}, Key: []byte{1}, Created: ts, Renamed2: 7}
+	tag1, err := SumStruct(h, c1)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	tag2, err := SumStruct(h, c2)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if !bytes.Equal(tag1, tag2) {
+		t.Errorf("tag mismatch for equivalent structs")
+	}
+	if tag, _ := SumStruct(h, &c2); bytes.Equal(tag, tag2) {
+		t.Errorf("unexpected tag match of pointer and value")
+	}
+
+	c1.Limits["x"] = 4
+	if tag, _ := SumStruct(h, c1); bytes.Equal(tag, tag1) {
+		t.Errorf("unexpected tag match after change")
+	}
+	c1.Limits["x"] = 1
+	c1.Parent = &testConfig{}
+	if tag, _ := SumStruct(h, c1); bytes.Equal(tag, tag1) {
+		t.Errorf("unexpected tag match after change")
+	}
+
+	b, err := appendValue(nil, reflect.ValueOf(struct {
+		A int8
+		B []interface{}
+	}{-1, []interface{}{nil, "x", uint(2)}}), 0)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	exp := "7400000002000000014169ffffffffffffffff00000001426c000000036e730000000178750000000000000002"
+	if hex.EncodeToString(b) != exp {
+		t.Errorf("got encoding %x, expected %s", b, exp)
+	}
+
+	for i, v := range []interface{}{make(chan int), func() {}, complex(1, 2), struct {
+		A int
+		B int `cmac:"A"`
+	}{}} {
+		if _, err := SumStruct(h, v); err != ErrUnsupportedType {
+			t.Errorf("%d: got error %v, expected %v", i, err, ErrUnsupportedType)
+		}
+	}
+	loop := &testConfig{}
+	loop.Parent = loop
+	if _, err := SumStruct(h, loop); err != ErrUnsupportedType {
+		t.Errorf("got error %v, expected %v", err, ErrUnsupportedType)
+	}
+	if !errors.Is(ErrUnsupportedType, ErrInvalidArgument) {
+		t.Errorf("got error %v, expected category %v", ErrUnsupportedType, ErrInvalidArgument)
+	}
+}
@@ -0,0 +1,91 @@
+package cmac
+
+import (
+	"crypto/cipher"
+	"crypto/des"
+	"math/bits"
+)
+
+// TDEA keying options of NIST SP 800-67.
+const (
+	TDEAKeyingOption1 = 1 // three independent keys K1, K2 and K3
+	TDEAKeyingOption2 = 2 // K1 and K2 independent, K3 = K1
+)
+
+// ErrInvalidTDEAKey is returned for a TDEA key of invalid length or with
+// equal consecutive DES keys, which would degrade it to single DES.
+var ErrInvalidTDEAKey = newError(ErrInvalidKey, "cmac: invalid TDEA key")
+
+// TDEAKeyingOption returns the keying option of the 16 byte (2-key) or 24
+// byte (3-key) TDEA key. It returns ErrInvalidTDEAKey when the key has an
+// invalid length, or when K1 equals K2 or K2 equals K3, ignoring parity bits.
+func TDEAKeyingOption(key []byte) (int, error) {
+	k, err := ExpandTDEAKey(key)
+	if err != nil {
+		return 0, err
+	}
+	k1, k2, k3 := k[:8], k[8:16], k[16:]
+	if equalDESKeys(k1, k2) || equalDESKeys(k2, k3) {
+		return 0, ErrInvalidTDEAKey
+	}
+	if equalDESKeys(k1, k3) {
+		return TDEAKeyingOption2, nil
+	}
+	return TDEAKeyingOption1, nil
+}
+
+// ExpandTDEAKey returns the 24 byte TDEA key K1 || K2 || K1 of the 16 byte
+// 2-key TDEA key K1 || K2, or a copy of a 24 byte key.
+func ExpandTDEAKey(key []byte) ([]byte, error) {
+	switch len(key) {
+	case 16:
+		return append(append([]byte(nil), key...), key[:8]...), nil
+	case 24:
+		return append([]byte(nil), key...), nil
+	}
+	return nil, ErrInvalidTDEAKey
+}
+
+// NewTDEACipher returns the TDEA cipher of the 16 or 24 byte key after
+// validating its keying option. It is a NewCipherFunc. Parity bits are
+// ignored.
+func NewTDEACipher(key []byte) (cipher.Block, error) {
+	if _, err := TDEAKeyingOption(key); err != nil {
+		return nil, err
+	}
+	k, _ := ExpandTDEAKey(key)
+	return des.NewTripleDESCipher(k)
+}
+
+// CheckDESParity returns true if all bytes of the DES or TDEA key have odd
+// parity.
+func CheckDESParity(key []byte) bool {
+	for _, b := range key {
+		if bits.OnesCount8(b)&1 == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// FixDESParity sets the least significant bit of each byte of the DES or
+// TDEA key so that the byte has odd parity.
+func FixDESParity(key []byte) {
+	for i, b := range key {
+		b &^= 1
+		if bits.OnesCount8(b)&1 == 0 {
+			b |= 1
+		}
+		key[i] = b
+	}
+}
+
+// equalDESKeys returns true if the 8 byte DES keys a and b are equal,
+// ignoring parity bits.
+func equalDESKeys(a, b []byte) bool {
+	var d byte
+	for i := range a {
+		d |= (a[i] ^ b[i]) &^ 1
+	}
+	return d == 0
+}
@@ -0,0 +1,96 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/des"
+	"encoding/hex"
+	"testing"
+)
+
+func TestTDEAKey(t *testing.T) {
+	tests := []struct {
+		key    string
+		option int
+		err    error
+	}{
+		{"0123456789abcdef23456789abcdef01456789abcdef0123", TDEAKeyingOption1, nil},
+		{"0123456789abcdef23456789abcdef010123456789abcdef", TDEAKeyingOption2, nil},
+		{"0123456789abcdef23456789abcdef01", TDEAKeyingOption2, nil},
+		{"0123456789abcdef0123456789abcdef", 0, ErrInvalidTDEAKey},
+		{"0123456789abcdef0022446688aaccee", 0, ErrInvalidTDEAKey}, // equal but for parity
+		{"0123456789abcdef23456789abcdef0123456789abcdef01", 0, ErrInvalidTDEAKey},
+		{"0123456789abcdef", 0, ErrInvalidTDEAKey},
+	}
+	for i, tc := range tests {
+		key, _ := hex.DecodeString(tc.key)
+		option, err := TDEAKeyingOption(key)
+		if option != tc.option || err != tc.err {
+			t.Errorf("%d: got %d %v, expected %d %v", i, option, err, tc.option, tc.err)
+		}
+	}
+
+	key, _ := hex.DecodeString("0123456789abcdef23456789abcdef01")
+	k, _ := ExpandTDEAKey(key)
+	if exp := "0123456789abcdef23456789abcdef010123456789abcdef"; hex.EncodeToString(k) != exp {
+		t.Errorf("got %x, expected %s", k, exp)
+	}
+	c, err := NewTDEACipher(key)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	ref, _ := des.NewTripleDESCipher(k)
+	b1, b2 := make([]byte, 8), make([]byte, 8)
+	c.Encrypt(b1, b1)
+	ref.Encrypt(b2, b2)
+	if !bytes.Equal(b1, b2) {
+		t.Errorf("2-key cipher mismatch")
+	}
+	if _, err := NewTDEACipher(key[:8]); err != ErrInvalidTDEAKey {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidTDEAKey)
+	}
+}
+
+func TestDESParity(t *testing.T) {
+	key, _ := hex.DecodeString("0123456789abcdef")
+	if !CheckDESParity(key) {
+		t.Errorf("unexpected invalid parity for %x", key)
+	}
+	bad, _ := hex.DecodeString("0022446688aaccee")
+	if CheckDESParity(bad) {
+		t.Errorf("unexpected valid parity for %x", bad)
+	}
+	FixDESParity(bad)
+	if exp := "0123456789abcdef"; hex.EncodeToString(bad) != exp {
+		t.Errorf("got %x, expected %s", bad, exp)
+	}
+	if !CheckDESParity(bad) {
+		t.Errorf("unexpected invalid parity after fix")
+	}
+}
+
+// using the TDEA examples of NIST SP 800-38B
+func TestTDEACMAC(t *testing.T) {
+	msg, _ := hex.DecodeString("6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e51")
+	for _, tc := range []struct {
+		key  string
+		tags [4]string
+	}{
+		{"8aa83bf8cbda10620bc1bf19fbb6cd58bc313d4a371ca8b5",
+			[4]string{"b7a688e122ffaf95", "8e8f293136283797", "743ddbe0ce2dc2ed", "33e6b1092400eae5"}},
+		{"4cf15134a2850dd58a3d10ba80570d384cf15134a2850dd5",
+			[4]string{"bd2ebf9a3ba00361", "4ff2ab813c53ce83", "62dd1b471902bd4e", "31b1e431dabc4eb8"}},
+	} {
+		key, _ := hex.DecodeString(tc.key)
+		h, err := New(NewTDEACipher, key)
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		for i, n := range []int{0, 8, 20, 32} {
+			h.Reset()
+			h.Write(msg[:n])
+			if tag := hex.EncodeToString(h.Sum(nil)); tag != tc.tags[i] {
+				t.Errorf("%s %d: got %s, expected %s", tc.key[:8], n, tag, tc.tags[i])
+			}
+		}
+	}
+}
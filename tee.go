@@ -0,0 +1,87 @@
+package cmac
+
+import (
+	"hash"
+	"io"
+)
+
+// teeQueueSize is the number of buffers queued to the background computation.
+const teeQueueSize = 16
+
+// SumFuture is the result of a background CMAC computation.
+type SumFuture struct {
+	done chan struct{}
+	tag  []byte
+	err  error
+}
+
+// Done returns a channel closed when the computation is done.
+func (f *SumFuture) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait waits until the computation is done and returns the CMAC, or the
+// error that stopped the computation.
+func (f *SumFuture) Wait() ([]byte, error) {
+	<-f.done
+	return f.tag, f.err
+}
+
+type teeReader struct {
+	r     io.Reader
+	queue chan []byte
+	ended bool
+	err   error // reason of the end of the stream, read by the goroutine
+}
+
+// TeeSum returns a reader of r and the future CMAC, computed with h in a
+// background goroutine, of the data read up to io.EOF. The future resolves
+// with the read error when reading r fails, or with io.ErrClosedPipe when
+// the reader is closed before io.EOF. The reader must be read up to an error
+// or closed to release the goroutine. The reader may not be used
+// concurrently, and h may not be used until the future is done.
+func TeeSum(r io.Reader, h hash.Hash) (io.ReadCloser, *SumFuture) {
+	t := &teeReader{r: r, queue: make(chan []byte, teeQueueSize)}
+	f := &SumFuture{done: make(chan struct{})}
+	h.Reset()
+	go func() {
+		defer close(f.done)
+		for b := range t.queue {
+			h.Write(b)
+		}
+		if t.err != io.EOF {
+			f.err = t.err
+			return
+		}
+		f.tag = h.Sum(nil)
+	}()
+	return t, f
+}
+
+func (t *teeReader) Read(p []byte) (int, error) {
+	if t.ended {
+		return 0, t.err
+	}
+	n, err := t.r.Read(p)
+	if n > 0 {
+		t.queue <- append([]byte(nil), p[:n]...)
+	}
+	if err != nil {
+		t.end(err)
+	}
+	return n, err
+}
+
+// Close stops the computation when it is not done.
+func (t *teeReader) Close() error {
+	t.end(io.ErrClosedPipe)
+	return nil
+}
+
+func (t *teeReader) end(err error) {
+	if !t.ended {
+		t.ended = true
+		t.err = err
+		close(t.queue)
+	}
+}
@@ -0,0 +1,47 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestTeeSum(t *testing.T) {
+	h, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+	data := bytes.Repeat([]byte("0123456789"), 100000)
+	h.Write(data)
+	exp := h.Sum(nil)
+
+	r, f := TeeSum(bytes.NewReader(data), h)
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, r); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	tag, err := f.Wait()
+	if err != nil || !bytes.Equal(tag, exp) || !bytes.Equal(buf.Bytes(), data) {
+		t.Errorf("got %x, %v, expected %x", tag, err, exp)
+	}
+	<-f.Done()
+	if n, err := r.Read(make([]byte, 10)); n != 0 || err != io.EOF {
+		t.Errorf("got %d, %v after EOF", n, err)
+	}
+
+	r, f = TeeSum(bytes.NewReader(data), h)
+	r.Read(make([]byte, 100))
+	r.Close()
+	if tag, err := f.Wait(); err != io.ErrClosedPipe || tag != nil {
+		t.Errorf("got %x, %v, expected %v", tag, err, io.ErrClosedPipe)
+	}
+	r.Close()
+
+	readErr := errors.New("read error")
+	r, f = TeeSum(io.MultiReader(bytes.NewReader(data), &errReader{readErr}), h)
+	if _, err := io.Copy(io.Discard, r); err != readErr {
+		t.Errorf("got error %v, expected %v", err, readErr)
+	}
+	if tag, err := f.Wait(); err != readErr || tag != nil {
+		t.Errorf("got %x, %v, expected %v", tag, err, readErr)
+	}
+}
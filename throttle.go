@@ -0,0 +1,124 @@
+package cmac
+
+import (
+	"sync"
+	"time"
+)
+
+// ErrLockedOut is returned by Throttle.Verify when a source has too many
+// recent verification failures.
+var ErrLockedOut = newError(ErrInvalidArgument, "cmac: too many verification failures")
+
+type throttleState struct {
+	failures int
+	pending  int       // attempts being verified
+	last     time.Time // time of the last failure
+}
+
+// minThrottleSweep is the minimum number of sources above which expired
+// sources are removed.
+const minThrottleSweep = 64
+
+// Throttle limits the rate of verification failures per source, e.g. a
+// remote address or a key ID, to slow down online brute force of short
+// truncated tags. It wraps any verification function, like Registry.Verify
+// or VerifyToken. A Throttle is safe for concurrent use.
+type Throttle struct {
+	maxFailures int
+	lockout     time.Duration
+	delay       time.Duration
+	mu          sync.Mutex
+	sources     map[string]*throttleState
+	sweepAt     int // number of sources triggering the next sweep
+}
+
+// NewThrottle returns a throttle that locks out a source for the lockout
+// duration after its maxFailures-th failure, and that delays the return of
+// each verification by delay, so that successes and failures take the same
+// time. The failure count of a source is forgotten after a success, or
+// lockout after its last failure. There is no lockout when maxFailures is
+// zero.
+func NewThrottle(maxFailures int, lockout, delay time.Duration) *Throttle {
+	return &Throttle{
+		maxFailures: maxFailures,
+		lockout:     lockout,
+		delay:       delay,
+		sources:     make(map[string]*throttleState),
+		sweepAt:     minThrottleSweep,
+	}
+}
+
+// Verify calls verify for the source at time now and returns its error. It
+// returns ErrLockedOut without calling verify when the source is locked
+// out. Any error returned by verify counts as a failure. The attempts being
+// verified count as failures for the lockout, so that concurrent attempts
+// of a source can't exceed the maximum number of failures.
+func (t *Throttle) Verify(source string, now time.Time, verify func() error) error {
+	defer time.Sleep(t.delay)
+	t.mu.Lock()
+	s := t.state(source, now)
+	if t.maxFailures > 0 && s.failures+s.pending >= t.maxFailures {
+		t.release(source, s)
+		t.mu.Unlock()
+		return ErrLockedOut
+	}
+	s.pending++
+	t.mu.Unlock()
+
+	err := verify()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s.pending--
+	if err == nil {
+		s.failures = 0
+	} else {
+		s.failures++
+		s.last = now
+	}
+	t.release(source, s)
+	return err
+}
+
+// state returns the state of the source at time now, creating it when
+// needed. Expired states are reset, and expired sources are removed when
+// their number doubled since the last sweep. t.mu must be held.
+func (t *Throttle) state(source string, now time.Time) *throttleState {
+	if len(t.sources) >= t.sweepAt {
+		for k, v := range t.sources {
+			if v.pending == 0 && now.Sub(v.last) >= t.lockout {
+				delete(t.sources, k)
+			}
+		}
+		t.sweepAt = 2 * len(t.sources)
+		if t.sweepAt < minThrottleSweep {
+			t.sweepAt = minThrottleSweep
+		}
+	}
+	s := t.sources[source]
+	if s == nil {
+		s = new(throttleState)
+		t.sources[source] = s
+	} else if now.Sub(s.last) >= t.lockout {
+		s.failures = 0
+	}
+	return s
+}
+
+// release removes the state of the source when it has no failure nor
+// pending attempt. t.mu must be held.
+func (t *Throttle) release(source string, s *throttleState) {
+	if s.failures == 0 && s.pending == 0 {
+		delete(t.sources, source)
+	}
+}
+
+// Failures returns the number of recent failures of the source at time now.
+func (t *Throttle) Failures(source string, now time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if s := t.sources[source]; s != nil && now.Sub(s.last) < t.lockout {
+		return s.failures
+	}
+	return 0
+}
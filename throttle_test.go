@@ -0,0 +1,126 @@
+package cmac
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	r := NewRegistry(nil)
+	r.Add("k1", AES128, []byte("0123456789abcdef"))
+	env, _ := r.Sign("k1", []byte("hello"))
+	good := func() error { return r.Verify([]byte("hello"), env) }
+	bad := func() error { return r.Verify([]byte("hello!"), env) }
+
+	th := NewThrottle(3, time.Minute, time.Millisecond)
+	now := time.Unix(1600000000, 0)
+	for i := 0; i < 3; i++ {
+		if err := th.Verify("a", now, bad); err != ErrMismatch {
+			t.Errorf("%d: got error %v, expected %v", i, err, ErrMismatch)
+		}
+	}
+	if n := th.Failures("a", now); n != 3 {
+		t.Errorf("got %d failures, expected 3", n)
+	}
+	if err := th.Verify("a", now, good); err != ErrLockedOut {
+		t.Errorf("got error %v, expected %v", err, ErrLockedOut)
+	}
+	if err := th.Verify("b", now, good); err != nil {
+		t.Errorf("unexpected error for other source: %v", err)
+	}
+	now = now.Add(time.Minute)
+	if err := th.Verify("a", now, good); err != nil {
+		t.Errorf("unexpected error after lockout: %v", err)
+	}
+
+	// a success clears the failures
+	th.Verify("a", now, bad)
+	th.Verify("a", now, bad)
+	th.Verify("a", now, good)
+	th.Verify("a", now, bad)
+	if n := th.Failures("a", now); n != 1 {
+		t.Errorf("got %d failures, expected 1", n)
+	}
+
+	// no lockout with zero max failures
+	th = NewThrottle(0, time.Minute, 0)
+	for i := 0; i < 10; i++ {
+		th.Verify("a", now, bad)
+	}
+	if err := th.Verify("a", now, good); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestThrottleConcurrent(t *testing.T) {
+	th := NewThrottle(3, time.Minute, 0)
+	now := time.Unix(1600000000, 0)
+	start := make(chan struct{})
+	errBad := errors.New("bad tag")
+	results := make(chan error)
+	for i := 0; i < 10; i++ {
+		go func() {
+			results <- th.Verify("a", now, func() error {
+				<-start
+				return errBad
+			})
+		}()
+	}
+	// only 3 attempts may be in flight, the others are locked out at once
+	counts := map[error]int{}
+	for i := 0; i < 10; i++ {
+		if i == 7 {
+			close(start)
+		}
+		select {
+		case err := <-results:
+			counts[err]++
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %v after %d results, expected 7 lockouts", counts, i)
+		}
+	}
+	if counts[ErrLockedOut] != 7 || counts[errBad] != 3 {
+		t.Errorf("got %v, expected 7 lockouts and 3 failures", counts)
+	}
+	if n := th.Failures("a", now); n != 3 {
+		t.Errorf("got %d failures, expected 3", n)
+	}
+}
+
+func TestThrottleDelay(t *testing.T) {
+	th := NewThrottle(1, time.Minute, 20*time.Millisecond)
+	now := time.Unix(1600000000, 0)
+	for i, verify := range []func() error{
+		func() error { return nil },
+		func() error { return ErrMismatch },
+		func() error { return nil }, // locked out
+	} {
+		start := time.Now()
+		th.Verify("a", now, verify)
+		if d := time.Since(start); d < 20*time.Millisecond {
+			t.Errorf("%d: returned after %v, expected at least 20ms", i, d)
+		}
+	}
+}
+
+func TestThrottleSweep(t *testing.T) {
+	th := NewThrottle(3, time.Minute, 0)
+	now := time.Unix(1600000000, 0)
+	bad := func() error { return ErrMismatch }
+	for i := 0; i < 1000; i++ {
+		th.Verify(strconv.Itoa(i), now, bad)
+	}
+	if n := len(th.sources); n != 1000 {
+		t.Errorf("got %d sources, expected 1000", n)
+	}
+	// the expired sources are removed when the number of sources doubled
+	now = now.Add(time.Minute)
+	for i := 0; i < 1000; i++ {
+		th.Verify("new"+strconv.Itoa(i), now, bad)
+	}
+	if n := len(th.sources); n != 1000 {
+		t.Errorf("got %d sources, expected the 1000 new ones", n)
+	}
+}
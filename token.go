@@ -0,0 +1,97 @@
+package cmac
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"time"
+)
+
+/* A token is the base64url encoding, without padding, of the following bytes:
+
+   +---------+-----+--------+---------+-------+-----+
+   | version | len | key ID | expires | value | tag |
+   +---------+-----+--------+---------+-------+-----+
+
+version is the byte 1, len is the byte length of the key ID, expires is the
+expiry time in seconds since the Unix epoch as a big endian int64, and tag
+is the CMAC of all the preceding bytes. The tag length is the Size of the
+hash associated to the key ID.
+*/
+
+const tokenVersion = 1
+
+var (
+	// ErrInvalidToken is returned when a token is malformed or its tag is invalid.
+	ErrInvalidToken = errors.New("cmac: invalid token")
+
+	// ErrExpiredToken is returned when a valid token has expired.
+	ErrExpiredToken = errors.New("cmac: expired token")
+)
+
+// KeyFunc returns the CMAC hash associated to the key ID. The returned hash
+// is reset before use. It must not be used concurrently by the caller.
+type KeyFunc func(keyID string) (hash.Hash, error)
+
+// Token is an authenticated value with an expiry time. KeyID identifies the
+// key used to compute its tag.
+type Token struct {
+	KeyID   string
+	Expires time.Time
+	Value   []byte
+}
+
+// SignToken returns the token t authenticated with h as a base64url string.
+// The key ID may not be longer than 255 bytes. h is reset.
+func SignToken(h hash.Hash, t Token) (string, error) {
+	if len(t.KeyID) > 255 {
+		return "", newError(ErrInvalidArgument, "cmac: key ID too long")
+	}
+	b := make([]byte, 0, 10+len(t.KeyID)+len(t.Value)+h.Size())
+	b = append(b, tokenVersion, byte(len(t.KeyID)))
+	b = append(b, t.KeyID...)
+	var exp [8]byte
+	binary.BigEndian.PutUint64(exp[:], uint64(t.Expires.Unix()))
+	b = append(b, exp[:]...)
+	b = append(b, t.Value...)
+	h.Reset()
+	h.Write(b)
+	b = h.Sum(b)
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// VerifyToken decodes and verifies the token s with the hash returned by keys
+// for its key ID. It returns ErrExpiredToken when now is after the token
+// expiry time plus skew, where skew tolerates clock differences between the
+// signer and the verifier.
+func VerifyToken(keys KeyFunc, s string, now time.Time, skew time.Duration) (Token, error) {
+	var t Token
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) < 2 || b[0] != tokenVersion || len(b) < 10+int(b[1]) {
+		return t, ErrInvalidToken
+	}
+	keyID := string(b[2 : 2+int(b[1])])
+	h, err := keys(keyID)
+	if err != nil {
+		return t, err
+	}
+	n := len(b) - h.Size()
+	if n < 10+int(b[1]) {
+		return t, ErrInvalidToken
+	}
+	h.Reset()
+	h.Write(b[:n])
+	if !Equal(h.Sum(nil), b[n:]) {
+		audit("VerifyToken", keyID, int64(n))
+		return t, ErrInvalidToken
+	}
+	o := 2 + int(b[1])
+	t.KeyID = keyID
+	t.Expires = time.Unix(int64(binary.BigEndian.Uint64(b[o:])), 0)
+	t.Value = b[o+8 : n]
+	if now.After(t.Expires.Add(skew)) {
+		return t, ErrExpiredToken
+	}
+	return t, nil
+}
@@ -0,0 +1,64 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"testing"
+	"time"
+)
+
+func TestToken(t *testing.T) {
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	keys := func(keyID string) (hash.Hash, error) {
+		if keyID != "k1" {
+			return nil, errors.New("unknown key")
+		}
+		return New(aes.NewCipher, key)
+	}
+	h, _ := keys("k1")
+	now := time.Unix(1600000000, 0)
+	in := Token{KeyID: "k1", Expires: now.Add(time.Minute), Value: []byte("session data")}
+	s, err := SignToken(h, in)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	out, err := VerifyToken(keys, s, now, 0)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if out.KeyID != in.KeyID || !out.Expires.Equal(in.Expires) || !bytes.Equal(out.Value, in.Value) {
+		t.Errorf("token mismatch, got %+v, expected %+v", out, in)
+	}
+
+	if _, err := VerifyToken(keys, s, now.Add(2*time.Minute), 0); err != ErrExpiredToken {
+		t.Errorf("got error %v, expected %v", err, ErrExpiredToken)
+	}
+	if _, err := VerifyToken(keys, s, now.Add(2*time.Minute), 2*time.Minute); err != nil {
+		t.Errorf("unexpected error with clock skew: %v", err)
+	}
+
+	tampered := []byte(s)
+	tampered[10] ^= 1
+	if _, err := VerifyToken(keys, string(tampered), now, 0); err != ErrInvalidToken {
+		t.Errorf("got error %v, expected %v", err, ErrInvalidToken)
+	}
+	for _, bad := range []string{"", "!!", "AQ", s[:20]} {
+		if _, err := VerifyToken(keys, bad, now, 0); err != ErrInvalidToken {
+			t.Errorf("%q: got error %v, expected %v", bad, err, ErrInvalidToken)
+		}
+	}
+
+	in.KeyID = "k2"
+	s, _ = SignToken(h, in)
+	if _, err := VerifyToken(keys, s, now, 0); err == nil {
+		t.Errorf("unexpected nil error for unknown key ID")
+	}
+	in.KeyID = string(make([]byte, 256))
+	if _, err := SignToken(h, in); err == nil {
+		t.Errorf("unexpected nil error for too long key ID")
+	}
+}
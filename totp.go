@@ -0,0 +1,93 @@
+package cmac
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"hash"
+	"strconv"
+	"time"
+)
+
+/* A TOTP code is computed like the HOTP and TOTP codes of RFC 4226 and
+RFC 6238, with CMAC instead of HMAC-SHA1:
+
+   T      = floor(unix time / step)
+   tag    = CMAC(K, uint64(T))
+   offset = tag[len(tag)-1] mod (len(tag)-3)
+   code   = (uint32(tag[offset:offset+4]) & 0x7fffffff) mod 10^digits
+
+where integers are big endian and the code is left padded with zeros to
+digits decimal digits.
+*/
+
+// TOTP generates and verifies time based one-time codes with CMAC. A TOTP
+// is not safe for concurrent use.
+type TOTP struct {
+	// Hash is the CMAC hash with the shared key.
+	Hash hash.Hash
+
+	// Digits is the number of decimal digits of the codes, between 6 and 9.
+	// Other values are replaced with 6.
+	Digits int
+
+	// Step is the validity period of a code in whole seconds. Periods
+	// shorter than a second are replaced with 30 seconds.
+	Step time.Duration
+
+	// Skew is the number of steps before and after the current one whose
+	// codes are also accepted by Verify, to tolerate clock differences.
+	Skew int
+}
+
+// Code returns the code at time t.
+func (o *TOTP) Code(t time.Time) string {
+	return o.code(o.counter(t))
+}
+
+// Verify returns nil if code is the code at time t or of one of the Skew
+// steps before or after it, and ErrMismatch otherwise. The comparison is
+// constant time.
+func (o *TOTP) Verify(code string, t time.Time) error {
+	c := o.counter(t)
+	ok := 0
+	for i := -o.Skew; i <= o.Skew; i++ {
+		ok |= subtle.ConstantTimeCompare([]byte(o.code(c+uint64(int64(i)))), []byte(code))
+	}
+	if ok != 1 {
+		audit("TOTP.Verify", "", -1)
+		return ErrMismatch
+	}
+	return nil
+}
+
+func (o *TOTP) counter(t time.Time) uint64 {
+	step := o.Step
+	if step < time.Second {
+		step = 30 * time.Second
+	}
+	return uint64(t.Unix() / int64(step/time.Second))
+}
+
+// code returns the code of the counter.
+func (o *TOTP) code(counter uint64) string {
+	digits := o.Digits
+	if digits < 6 || digits > 9 {
+		digits = 6
+	}
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], counter)
+	o.Hash.Reset()
+	o.Hash.Write(b[:])
+	tag := o.Hash.Sum(nil)
+	offset := int(tag[len(tag)-1]) % (len(tag) - 3)
+	v := binary.BigEndian.Uint32(tag[offset:]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	s := strconv.FormatUint(uint64(v%mod), 10)
+	for len(s) < digits {
+		s = "0" + s
+	}
+	return s
+}
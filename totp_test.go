@@ -0,0 +1,58 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTOTP(t *testing.T) {
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	h, _ := New(aes.NewCipher, key)
+	now := time.Unix(1599999960, 0) // multiple of 60
+
+	// reference computation of the code for T = 1599999960/30
+	ref, _ := New(aes.NewCipher, key)
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], 1599999960/30)
+	ref.Write(b[:])
+	tag := ref.Sum(nil)
+	offset := int(tag[15]) % 13
+	exp := fmt.Sprintf("%08d", (binary.BigEndian.Uint32(tag[offset:])&0x7fffffff)%100000000)
+
+	o := &TOTP{Hash: h, Digits: 8}
+	if code := o.Code(now); code != exp {
+		t.Errorf("got %s, expected %s", code, exp)
+	}
+	if err := o.Verify(exp, now.Add(29*time.Second)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := o.Verify(exp, now.Add(30*time.Second)); err != ErrMismatch {
+		t.Errorf("got error %v, expected %v", err, ErrMismatch)
+	}
+	o.Skew = 1
+	if err := o.Verify(exp, now.Add(30*time.Second)); err != nil {
+		t.Errorf("unexpected error with skew: %v", err)
+	}
+	if err := o.Verify(exp, now.Add(-30*time.Second)); err != nil {
+		t.Errorf("unexpected error with skew: %v", err)
+	}
+	if err := o.Verify(exp, now.Add(60*time.Second)); err != ErrMismatch {
+		t.Errorf("got error %v, expected %v", err, ErrMismatch)
+	}
+	if err := o.Verify(exp[1:], now); err != ErrMismatch {
+		t.Errorf("got error %v, expected %v", err, ErrMismatch)
+	}
+
+	o = &TOTP{Hash: h}
+	if code := o.Code(now); len(code) != 6 || code != exp[2:] {
+		t.Errorf("got %s, expected %s", code, exp[2:])
+	}
+	o.Step = time.Minute
+	if o.Code(now) != o.Code(now.Add(59*time.Second)) || o.Code(now) == o.Code(now.Add(time.Minute)) {
+		t.Errorf("invalid step handling")
+	}
+}
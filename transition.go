@@ -0,0 +1,77 @@
+package cmac
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Transition signs with a target algorithm and, until a cutover time, also
+// accepts the tags of a legacy algorithm, e.g. HMAC-SHA-256, so that the
+// signers and verifiers of a fleet may switch algorithms at different times.
+// The algorithm of a tag is identified by its length, which must differ
+// between the two. A Transition is safe for concurrent use if its KeyFuncs
+// are.
+type Transition struct {
+	legacyCount    uint64 // first for 64 bit alignment
+	target, legacy KeyFunc
+	cutover        time.Time
+}
+
+// NewTransition returns a transition signing with the hashes returned by
+// target, and accepting until cutover the tags of the hashes returned by
+// legacy, e.g.
+//
+//	func(keyID string) (hash.Hash, error) {
+//		return hmac.New(sha256.New, hmacKeys[keyID]), nil
+//	}
+func NewTransition(target, legacy KeyFunc, cutover time.Time) *Transition {
+	return &Transition{target: target, legacy: legacy, cutover: cutover}
+}
+
+// Sign returns the tag of msg computed with the target hash of the key ID.
+func (t *Transition) Sign(keyID string, msg []byte) ([]byte, error) {
+	h, err := t.target(keyID)
+	if err != nil {
+		return nil, err
+	}
+	h.Reset()
+	h.Write(msg)
+	return h.Sum(nil), nil
+}
+
+// Verify returns nil if tag is the tag of msg computed with the target hash
+// of the key ID or, before the cutover time, with its legacy hash. It
+// returns ErrMismatch otherwise, and for legacy tags from the cutover time
+// on. Truncated tags are rejected.
+func (t *Transition) Verify(keyID string, msg, tag []byte, now time.Time) error {
+	h, err := t.target(keyID)
+	if err != nil {
+		return err
+	}
+	legacy := len(tag) != h.Size()
+	if legacy {
+		if !now.Before(t.cutover) {
+			audit("Transition.Verify", keyID, int64(len(msg)))
+			return ErrMismatch
+		}
+		if h, err = t.legacy(keyID); err != nil {
+			return err
+		}
+	}
+	h.Reset()
+	h.Write(msg)
+	if !Equal(h.Sum(nil), tag) {
+		audit("Transition.Verify", keyID, int64(len(msg)))
+		return ErrMismatch
+	}
+	if legacy {
+		atomic.AddUint64(&t.legacyCount, 1)
+	}
+	return nil
+}
+
+// Legacy returns the number of legacy tags accepted by Verify. It tells
+// when all the signers have switched to the target algorithm.
+func (t *Transition) Legacy() uint64 {
+	return atomic.LoadUint64(&t.legacyCount)
+}
@@ -0,0 +1,64 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"testing"
+	"time"
+)
+
+func TestTransition(t *testing.T) {
+	errUnknown := errors.New("unknown key")
+	target := func(keyID string) (hash.Hash, error) {
+		if keyID != "k1" {
+			return nil, errUnknown
+		}
+		return New(aes.NewCipher, []byte("0123456789abcdef"))
+	}
+	legacy := func(keyID string) (hash.Hash, error) {
+		return hmac.New(sha256.New, []byte("legacy key")), nil
+	}
+	cutover := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	before, after := cutover.Add(-time.Second), cutover
+	tr := NewTransition(target, legacy, cutover)
+	msg := []byte("message")
+
+	tag, err := tr.Sign("k1", msg)
+	if err != nil || len(tag) != 16 {
+		t.Fatalf("got %x, %v", tag, err)
+	}
+	lh, _ := legacy("k1")
+	lh.Write(msg)
+	legacyTag := lh.Sum(nil)
+
+	tests := []struct {
+		tag []byte
+		now time.Time
+		err error
+	}{
+		{tag, before, nil},
+		{tag, after, nil},
+		{legacyTag, before, nil},
+		{legacyTag, after, ErrMismatch},
+		{tag[:8], before, ErrMismatch},
+		{legacyTag[:16], before, ErrMismatch},
+		{append(legacyTag[:31:31], legacyTag[31]^1), before, ErrMismatch},
+	}
+	for i, test := range tests {
+		if err := tr.Verify("k1", msg, test.tag, test.now); err != test.err {
+			t.Errorf("%d: got error %v, expected %v", i, err, test.err)
+		}
+	}
+	if tr.Legacy() != 1 {
+		t.Errorf("got %d legacy tags, expected 1", tr.Legacy())
+	}
+	if _, err := tr.Sign("k2", msg); err != errUnknown {
+		t.Errorf("got error %v, expected %v", err, errUnknown)
+	}
+	if err := tr.Verify("k2", msg, tag, before); err != errUnknown {
+		t.Errorf("got error %v, expected %v", err, errUnknown)
+	}
+}
@@ -0,0 +1,78 @@
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestTupleMAC(t *testing.T) {
+	h, _ := New(aes.NewCipher, []byte("0123456789abcdef"))
+
+	// the encoding is the one published in tuple.go
+	enc, _ := hex.DecodeString("010000000161000000000000000278790100000000000000000000000000")
+	h.Write(enc)
+	exp := h.Sum(nil)
+	tag := SumTuple(h, Field{"a", []byte("xy")}, Field{"", nil})
+	if !bytes.Equal(tag, exp) {
+		t.Errorf("got %x, expected %x", tag, exp)
+	}
+	if err := VerifyTuple(h, tag, Field{"a", []byte("xy")}, Field{"", nil}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	tuples := [][]Field{
+		nil,
+		{{"", nil}},
+		{{"a", []byte("xy")}},
+		{{"a", []byte("x")}, {"y", nil}},
+		{{"ax", []byte("y")}},
+		{{"a", []byte("xy")}, {"", nil}, {"", nil}},
+	}
+	for i, tuple := range tuples {
+		if VerifyTuple(h, tag, tuple...) != ErrMismatch {
+			t.Errorf("%d: unexpected tag match", i)
+		}
+	}
+
+	more := SumTuple(h, Field{"a", []byte("xy")}, Field{"", nil}, Field{"b", []byte("z")})
+	tm := NewTupleMAC(h)
+	tm.Add("a", []byte("xy"))
+	tm.Add("", nil)
+	if !bytes.Equal(tm.Sum(nil), tag) {
+		t.Errorf("tag mismatch")
+	}
+	if !bytes.Equal(tm.Sum(nil), tag) {
+		t.Errorf("tag mismatch on second Sum")
+	}
+	tm.Add("b", []byte("z"))
+	if !bytes.Equal(tm.Sum(nil), more) {
+		t.Errorf("tag mismatch after adding a field after Sum")
+	}
+	tm.Reset()
+	if !bytes.Equal(tm.Sum(nil), SumTuple(h)) {
+		t.Errorf("empty tuple tag mismatch")
+	}
+
+	// hashes whose state can't be saved keep the tag of the first Sum
+	nested, _ := NewNested(aes.NewCipher, []byte("0123456789abcdef"), []byte("fedcba9876543210"))
+	tm = NewTupleMAC(nested)
+	tm.Add("a", []byte("xy"))
+	if first := tm.Sum(nil); !bytes.Equal(tm.Sum(nil), first) {
+		t.Errorf("tag mismatch on second Sum with a nested CMAC")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("expected panic for a field added after Sum")
+			}
+		}()
+		tm.Add("b", []byte("z"))
+	}()
+	tm.Reset()
+	tm.Add("a", []byte("xy"))
+	if !bytes.Equal(tm.Sum(nil), SumTuple(nested, Field{"a", []byte("xy")})) {
+		t.Errorf("tag mismatch after Reset with a nested CMAC")
+	}
+}
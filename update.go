@@ -0,0 +1,114 @@
+package cmac
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Range is the byte range [Off, Off+Len) of a file.
+type Range struct {
+	Off, Len int64
+}
+
+// Update updates the sidecar for the modified file content read from r,
+// whose length is size. oldSize is the length of the content before the
+// modification. dirty are the ranges of the modified bytes. Only the chunks
+// overlapping a dirty range, the previous and new last chunks, and the
+// appended chunks are read. An unchunked sidecar is computed from the whole
+// content, and oldSize is ignored. dirty must cover all the modifications
+// for the result to be valid.
+//
+// The chunk tags that are kept are only trusted after the sidecar tag has
+// been verified with the key and oldSize. Update returns ErrMismatch when it
+// is invalid, so that a modified sidecar isn't signed again.
+func (s *Sidecar) Update(key []byte, r io.ReaderAt, oldSize, size int64, dirty []Range) error {
+	if size < 0 || oldSize < 0 {
+		return newError(ErrInvalidArgument, "cmac: invalid size")
+	}
+	if s.ChunkSize <= 0 {
+		ref, err := NewSidecar(s.Algorithm, key, s.KeyID, io.NewSectionReader(r, 0, size), 0)
+		if err != nil {
+			return err
+		}
+		s.Tag = ref.Tag
+		return nil
+	}
+	h, err := s.Algorithm.New(key)
+	if err != nil {
+		return err
+	}
+	if !Equal(sumChunkTags(h, uint64(oldSize), s.Chunks), s.Tag) {
+		audit("Sidecar.Update", s.KeyID, oldSize)
+		return ErrMismatch
+	}
+	n := int((size + s.ChunkSize - 1) / s.ChunkSize)
+	stale := make([]bool, n)
+	for i := len(s.Chunks) - 1; i < n; i++ {
+		if i >= 0 {
+			stale[i] = true
+		}
+	}
+	if n > 0 {
+		stale[n-1] = true
+	}
+	for _, d := range dirty {
+		if d.Len <= 0 {
+			continue
+		}
+		if d.Off < 0 {
+			return newError(ErrInvalidArgument, "cmac: invalid range")
+		}
+		for i := d.Off / s.ChunkSize; i < int64(n) && i*s.ChunkSize < d.Off+d.Len; i++ {
+			stale[i] = true
+		}
+	}
+	chunks := make([][]byte, n)
+	copy(chunks, s.Chunks)
+	var hdr [8]byte
+	for i := range chunks {
+		if !stale[i] {
+			continue
+		}
+		off := int64(i) * s.ChunkSize
+		l := s.ChunkSize
+		if off+l > size {
+			l = size - off
+		}
+		h.Reset()
+		binary.BigEndian.PutUint64(hdr[:], uint64(i))
+		h.Write(hdr[:])
+		if _, err := io.Copy(h, io.NewSectionReader(r, off, l)); err != nil {
+			return wrapError(ErrIO, err)
+		}
+		chunks[i] = h.Sum(nil)
+	}
+	s.Chunks = chunks
+	s.Tag = sumChunkTags(h, uint64(size), chunks)
+	return nil
+}
+
+// Update updates the entry of the file with the given path for its modified
+// content read from r, whose length is size, and the manifest tag, as
+// Sidecar.Update does. It returns ErrNotInManifest when the file is not in
+// the manifest, and ErrMismatch when the manifest tag is invalid.
+func (m *Manifest) Update(path string, key []byte, r io.ReaderAt, size int64, dirty []Range) error {
+	e := m.Lookup(path)
+	if e == nil {
+		return ErrNotInManifest
+	}
+	h, err := m.Algorithm.New(key)
+	if err != nil {
+		return err
+	}
+	if !Equal(m.sum(h), m.Tag) {
+		audit("Manifest.Update", m.KeyID, -1)
+		return ErrMismatch
+	}
+	s := Sidecar{Algorithm: m.Algorithm, KeyID: m.KeyID, ChunkSize: m.ChunkSize, Chunks: e.Chunks, Tag: e.Tag}
+	if err := s.Update(key, r, e.Size, size, dirty); err != nil {
+		return err
+	}
+	e.Size, e.Chunks, e.Tag = size, s.Chunks, s.Tag
+	m.Tag = m.sum(h)
+	return nil
+}
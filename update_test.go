@@ -0,0 +1,135 @@
+package cmac
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/fstest"
+)
+
+// recordingReaderAt records the offsets read.
+type recordingReaderAt struct {
+	r    io.ReaderAt
+	offs []int64
+}
+
+func (r *recordingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	r.offs = append(r.offs, off)
+	return r.r.ReadAt(p, off)
+}
+
+func TestSidecarUpdate(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	data := make([]byte, 100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	modify := func(f func([]byte) []byte) []byte {
+		return f(append([]byte(nil), data...))
+	}
+	tests := []struct {
+		name  string
+		data  []byte
+		dirty []Range
+		reads int
+	}{
+		{"unchanged", data, nil, 1},
+		{"in place", modify(func(b []byte) []byte { b[25] = 0; return b }), []Range{{25, 1}}, 2},
+		{"across chunks", modify(func(b []byte) []byte { b[19], b[20] = 0, 0; return b }), []Range{{19, 2}}, 3},
+		{"append", append(append([]byte(nil), data...), 1, 2, 3), nil, 2},
+		{"append chunk", append(append([]byte(nil), data...), make([]byte, 30)...), nil, 4},
+		{"truncate", data[:55], nil, 1},
+		{"truncate to chunk", data[:40], nil, 1},
+		{"empty", nil, nil, 0},
+	}
+	for _, tc := range tests {
+		s, _ := NewSidecar(AES128, key, "", bytes.NewReader(data), 10)
+		r := &recordingReaderAt{r: bytes.NewReader(tc.data)}
+		if err := s.Update(key, r, int64(len(data)), int64(len(tc.data)), tc.dirty); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		exp, _ := NewSidecar(AES128, key, "", bytes.NewReader(tc.data), 10)
+		if !bytes.Equal(s.Tag, exp.Tag) || len(s.Chunks) != len(exp.Chunks) {
+			t.Errorf("%s: tag mismatch", tc.name)
+		}
+		if len(r.offs) != tc.reads {
+			t.Errorf("%s: got %d chunk reads, expected %d", tc.name, len(r.offs), tc.reads)
+		}
+		if err := s.Verify(key, bytes.NewReader(tc.data)); err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+
+	s, _ := NewSidecar(AES128, key, "", bytes.NewReader(data), 0)
+	changed := modify(func(b []byte) []byte { b[0] = 1; return b })
+	if err := s.Update(key, bytes.NewReader(changed), 100, 100, nil); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if err := s.Verify(key, bytes.NewReader(changed)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := s.Update(key, bytes.NewReader(changed), 100, 100, []Range{{-1, 2}}); err != nil {
+		t.Errorf("unexpected error for unchunked sidecar: %v", err)
+	}
+	s, _ = NewSidecar(AES128, key, "", bytes.NewReader(data), 10)
+	if err := s.Update(key, bytes.NewReader(changed), 100, 100, []Range{{-1, 2}}); err == nil {
+		t.Errorf("unexpected nil error for invalid range")
+	}
+}
+
+func TestSidecarUpdateTampered(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	data := bytes.Repeat([]byte("d"), 100)
+	tests := []func(s *Sidecar) int64{
+		func(s *Sidecar) int64 { s.Chunks[3][0] ^= 1; return 100 },
+		func(s *Sidecar) int64 { s.Chunks[1], s.Chunks[2] = s.Chunks[2], s.Chunks[1]; return 100 },
+		func(s *Sidecar) int64 { s.Tag[0] ^= 1; return 100 },
+		func(s *Sidecar) int64 { return 99 },
+	}
+	for i, tamper := range tests {
+		s, _ := NewSidecar(AES128, key, "", bytes.NewReader(data), 10)
+		oldSize := tamper(s)
+		tag := append([]byte(nil), s.Tag...)
+		if err := s.Update(key, bytes.NewReader(data), oldSize, 100, []Range{{0, 1}}); err != ErrMismatch {
+			t.Errorf("%d: got error %v, expected %v", i, err, ErrMismatch)
+		}
+		if !bytes.Equal(s.Tag, tag) {
+			t.Errorf("%d: tampered sidecar was signed", i)
+		}
+	}
+}
+
+func TestManifestUpdate(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	fsys := fstest.MapFS{
+		"a.txt": {Data: bytes.Repeat([]byte("a"), 50)},
+		"b.txt": {Data: []byte("b")},
+	}
+	m, err := BuildManifest(fsys, AES128, key, &ManifestOptions{ChunkSize: 16})
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	data := append(bytes.Repeat([]byte("a"), 50), "ppp"...)
+	data[3] = 'x'
+	fsys["a.txt"].Data = data
+	if err := m.Update("a.txt", key, bytes.NewReader(data), int64(len(data)), []Range{{3, 1}}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if err := m.Verify(fsys, key, nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := m.Update("c.txt", key, bytes.NewReader(nil), 0, nil); err != ErrNotInManifest {
+		t.Errorf("got error %v, expected %v", err, ErrNotInManifest)
+	}
+
+	// a tampered manifest isn't signed again
+	m.Files[0].Chunks, m.Files[1].Chunks = m.Files[1].Chunks, m.Files[0].Chunks
+	m.Files[0].Tag, m.Files[1].Tag = m.Files[1].Tag, m.Files[0].Tag
+	tag := append([]byte(nil), m.Tag...)
+	if err := m.Update("b.txt", key, bytes.NewReader([]byte("b")), 1, nil); err != ErrMismatch {
+		t.Errorf("got error %v, expected %v", err, ErrMismatch)
+	}
+	if !bytes.Equal(m.Tag, tag) {
+		t.Errorf("tampered manifest was signed")
+	}
+}
@@ -0,0 +1,100 @@
+package cmac
+
+import (
+	"encoding/base64"
+	"hash"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+/* A signed URL has the key ID, expiry time and signature query parameters.
+The signature is the base64url encoded truncated TupleMAC, without padding,
+of the fields
+
+   "path"   escaped URL path
+   "query"  query
+
+where query is the URL query, including the key ID and expiry time
+parameters but not the signature, with its parameters sorted by name as by
+url.Values.Encode. The expiry time is in seconds since the Unix epoch. The
+scheme and host are not signed, so that the URL remains valid behind
+proxies; a key should thus be used by a single service.
+*/
+
+// Query parameter names of signed URLs.
+const (
+	URLKeyIDParam     = "cmac-key"
+	URLExpiresParam   = "cmac-exp"
+	URLSignatureParam = "cmac-sig"
+)
+
+var (
+	// ErrInvalidSignedURL is returned when the signature parameters of a URL
+	// are missing or malformed, or its signature is invalid.
+	ErrInvalidSignedURL = newError(ErrInvalidArgument, "cmac: invalid signed URL")
+
+	// ErrExpiredURL is returned when a signed URL has expired.
+	ErrExpiredURL = newError(ErrInvalidArgument, "cmac: expired signed URL")
+)
+
+// SignURL returns the URL u with the key ID, expiry time and signature
+// parameters added. The signature is computed with h, the hash of the key
+// with ID keyID, and truncated to tagSize bytes.
+func SignURL(u *url.URL, h hash.Hash, keyID string, expires time.Time, tagSize int) (*url.URL, error) {
+	if tagSize < minTagSize || tagSize > h.Size() {
+		return nil, newError(ErrInvalidArgument, "cmac: invalid tag size")
+	}
+	s := *u
+	q := s.Query()
+	q.Del(URLSignatureParam)
+	q.Set(URLKeyIDParam, keyID)
+	q.Set(URLExpiresParam, strconv.FormatInt(expires.Unix(), 10))
+	s.RawQuery = q.Encode()
+	tag := sumURL(h, s.EscapedPath(), s.RawQuery)
+	q.Set(URLSignatureParam, base64.RawURLEncoding.EncodeToString(tag[:tagSize]))
+	s.RawQuery = q.Encode()
+	return &s, nil
+}
+
+// VerifyURL verifies the signature of u with the hash returned by keys for
+// the URL key ID. It returns ErrExpiredURL when now is after the expiry time
+// plus skew, where skew tolerates clock differences between the signer and
+// the verifier. The signature may not be shorter than 8 bytes.
+func VerifyURL(u *url.URL, keys KeyFunc, now time.Time, skew time.Duration) error {
+	q, err := url.ParseQuery(u.RawQuery)
+	if err != nil || len(q[URLKeyIDParam]) != 1 || len(q[URLExpiresParam]) != 1 || len(q[URLSignatureParam]) != 1 {
+		return ErrInvalidSignedURL
+	}
+	keyID := q.Get(URLKeyIDParam)
+	exp, err := strconv.ParseInt(q.Get(URLExpiresParam), 10, 64)
+	if err != nil {
+		return ErrInvalidSignedURL
+	}
+	tag, err := base64.RawURLEncoding.DecodeString(q.Get(URLSignatureParam))
+	if err != nil || len(tag) < minTagSize {
+		return ErrInvalidSignedURL
+	}
+	h, err := keys(keyID)
+	if err != nil {
+		return err
+	}
+	if len(tag) > h.Size() {
+		return ErrInvalidSignedURL
+	}
+	q.Del(URLSignatureParam)
+	if !Equal(sumURL(h, u.EscapedPath(), q.Encode())[:len(tag)], tag) {
+		audit("VerifyURL", keyID, -1)
+		return ErrInvalidSignedURL
+	}
+	if now.After(time.Unix(exp, 0).Add(skew)) {
+		return ErrExpiredURL
+	}
+	return nil
+}
+
+// sumURL returns the TupleMAC computed with h of the escaped path and the
+// encoded query of a URL.
+func sumURL(h hash.Hash, path, query string) []byte {
+	return SumTuple(h, Field{"path", []byte(path)}, Field{"query", []byte(query)})
+}
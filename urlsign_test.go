@@ -0,0 +1,73 @@
+package cmac
+
+import (
+	"crypto/aes"
+	"hash"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSignURL(t *testing.T) {
+	keys := func(keyID string) (hash.Hash, error) {
+		if keyID != "k1" {
+			return nil, ErrUnknownKey
+		}
+		return New(aes.NewCipher, []byte("0123456789abcdef"))
+	}
+	h, _ := keys("k1")
+	now := time.Unix(1600000000, 0)
+	u, _ := url.Parse("https://files.example.com/fw/image%20v2.bin?b=2&a=1&a=0")
+	s, err := SignURL(u, h, "k1", now.Add(time.Minute), 10)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if u.RawQuery != "b=2&a=1&a=0" {
+		t.Errorf("input URL modified: %s", u)
+	}
+	if q := s.Query(); q.Get(URLKeyIDParam) != "k1" || q.Get(URLExpiresParam) != "1600000060" || len(q.Get(URLSignatureParam)) != 14 {
+		t.Errorf("got signed URL %s", s)
+	}
+
+	// the server sees the path and query only
+	r, _ := url.ParseRequestURI(s.RequestURI())
+	if err := VerifyURL(r, keys, now, 0); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := VerifyURL(r, keys, now.Add(2*time.Minute), 0); err != ErrExpiredURL {
+		t.Errorf("got error %v, expected %v", err, ErrExpiredURL)
+	}
+	if err := VerifyURL(r, keys, now.Add(2*time.Minute), time.Minute); err != nil {
+		t.Errorf("unexpected error with skew: %v", err)
+	}
+
+	tamper := func(f func(q url.Values)) *url.URL {
+		v := *r
+		q := v.Query()
+		f(q)
+		v.RawQuery = q.Encode()
+		return &v
+	}
+	tests := []struct {
+		u   *url.URL
+		err error
+	}{
+		{tamper(func(q url.Values) { q.Set("b", "3") }), ErrInvalidSignedURL},
+		{tamper(func(q url.Values) { q.Add("c", "1") }), ErrInvalidSignedURL},
+		{tamper(func(q url.Values) { q.Set(URLExpiresParam, "1700000000") }), ErrInvalidSignedURL},
+		{tamper(func(q url.Values) { q.Add(URLSignatureParam, "AAAAAAAAAAAA") }), ErrInvalidSignedURL},
+		{tamper(func(q url.Values) { q.Del(URLSignatureParam) }), ErrInvalidSignedURL},
+		{tamper(func(q url.Values) { q.Set(URLSignatureParam, q.Get(URLSignatureParam)[:8]) }), ErrInvalidSignedURL},
+		{tamper(func(q url.Values) { q.Set(URLKeyIDParam, "k2") }), ErrUnknownKey},
+		{&url.URL{Path: "/fw/other.bin", RawQuery: r.RawQuery}, ErrInvalidSignedURL},
+	}
+	for i, test := range tests {
+		if err := VerifyURL(test.u, keys, now, 0); err != test.err {
+			t.Errorf("%d: got error %v, expected %v", i, err, test.err)
+		}
+	}
+
+	if _, err := SignURL(u, h, "k1", now, 7); err == nil {
+		t.Errorf("unexpected nil error for invalid tag size")
+	}
+}
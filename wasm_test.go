@@ -0,0 +1,37 @@
+package cmac
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// maxWasmSize is the maximum size of the js/wasm binary of
+// testdata/wasmsize built with the cmac_small tag.
+const maxWasmSize = 3 << 20
+
+func TestWasmSize(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping js/wasm build in short mode")
+	}
+	gocmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	for _, tags := range []string{"", "cmac_small"} {
+		out := filepath.Join(t.TempDir(), "main.wasm")
+		cmd := exec.Command(gocmd, "build", "-tags", tags, "-o", out, "./testdata/wasmsize")
+		cmd.Env = append(os.Environ(), "GOOS=js", "GOARCH=wasm")
+		if b, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("tags %q: build failed: %v\n%s", tags, err, b)
+		}
+		fi, err := os.Stat(out)
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		if tags != "" && fi.Size() > maxWasmSize {
+			t.Errorf("tags %q: got binary size %d, expected at most %d", tags, fi.Size(), maxWasmSize)
+		}
+	}
+}